@@ -0,0 +1,170 @@
+// Package api serves a versioned JSON API of a user's own reflections,
+// authenticated by personal access tokens.
+package api
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/jharlap/good-day-app/metrics"
+	"github.com/jharlap/good-day-app/store"
+	"github.com/jharlap/good-day-app/webhook"
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog/log"
+)
+
+// Prefix is the path the API is served under.
+const Prefix = "/api/v1/"
+
+// maxBodyBytes is far more than a reflection needs.
+const maxBodyBytes = 64 << 10
+
+// openAPI describes the API, for clients and their generators.
+//
+//go:embed openapi.json
+var openAPI []byte
+
+type API struct {
+	db          *sqlx.DB
+	reflections *store.Store
+	metrics     *metrics.Metrics
+	notify      Notifier
+	refresh     Refresher
+}
+
+// Notifier is told about every change made to reflections through the API,
+// so webhooks hear about them too.
+type Notifier func(ctx context.Context, e webhook.Event)
+
+// Refresher is told whose reflections were changed through the API, so their
+// Slack home tab doesn't show stale history and streaks.
+type Refresher func(ctx context.Context, tid, uid string)
+
+func New(db *sqlx.DB, m *metrics.Metrics, notify Notifier, refresh Refresher) *API {
+	return &API{db: db, reflections: store.New(db, m), metrics: m, notify: notify, refresh: refresh}
+}
+
+// handler serves a request authenticated by token t. id is the rest of the
+// path after the collection, if any.
+type handler func(w http.ResponseWriter, r *http.Request, t Token, id string)
+
+// route is how a method of a path is served, and the scope it needs.
+type route struct {
+	scope string
+	h     handler
+}
+
+func (a *API) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.URL.Path, Prefix) {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	path := strings.TrimPrefix(r.URL.Path, Prefix)
+
+	if path == "openapi.json" {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := w.Write(openAPI); err != nil {
+			log.Error().Err(err).Msg("error writing openapi document")
+		}
+		return
+	}
+
+	t, err := a.authenticate(r)
+	if errors.Is(err, errUnauthorized) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="good-day"`)
+		writeError(w, http.StatusUnauthorized, err.Error())
+		return
+	} else if err != nil {
+		log.Error().Err(err).Msg("error authenticating api request")
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	collection, id := path, ""
+	if i := strings.Index(path, "/"); i >= 0 {
+		collection, id = path[:i], path[i+1:]
+	}
+
+	switch {
+	case collection == "questions" && id == "":
+		a.dispatch(w, r, t, id, map[string]route{
+			http.MethodGet: {ScopeRead, a.listQuestions},
+		})
+	case collection == "reflections" && id == "":
+		a.dispatch(w, r, t, id, map[string]route{
+			http.MethodGet:  {ScopeRead, a.listReflections},
+			http.MethodPost: {ScopeWrite, a.createReflection},
+		})
+	case collection == "reflections":
+		a.dispatch(w, r, t, id, map[string]route{
+			http.MethodGet:    {ScopeRead, a.getReflection},
+			http.MethodPut:    {ScopeWrite, a.updateReflection},
+			http.MethodDelete: {ScopeWrite, a.deleteReflection},
+		})
+	case collection == "stats" && id == "":
+		a.dispatch(w, r, t, id, map[string]route{
+			http.MethodGet: {ScopeRead, a.getStats},
+		})
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// dispatch serves the request with the route for its method, if the token
+// has the route's scope.
+func (a *API) dispatch(w http.ResponseWriter, r *http.Request, t Token, id string, routes map[string]route) {
+	rt, ok := routes[r.Method]
+	if !ok {
+		var allow []string
+		for m := range routes {
+			allow = append(allow, m)
+		}
+		sort.Strings(allow)
+		w.Header().Set("Allow", strings.Join(allow, ", "))
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if !t.HasScope(rt.scope) {
+		writeError(w, http.StatusForbidden, "token lacks the "+rt.scope+" scope")
+		return
+	}
+	rt.h(w, r, t, id)
+}
+
+// apiError is the body of every error response.
+type apiError struct {
+	Error string `json:"error"`
+	// Fields has what's wrong with each invalid field of a request body.
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, apiError{Error: msg})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Error().Err(err).Msg("error writing api response")
+	}
+}
+
+// decodeBody decodes a JSON request body into v, refusing fields v doesn't
+// have so that typos aren't silently ignored.
+func decodeBody(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
@@ -0,0 +1,326 @@
+package api
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/jharlap/good-day-app/export"
+	"github.com/jharlap/good-day-app/metrics"
+	"github.com/jharlap/good-day-app/reflection"
+	"github.com/jharlap/good-day-app/webhook"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+)
+
+const testSecret = "gdt_test-secret"
+
+func newTestAPI(t *testing.T) (*API, sqlmock.Sqlmock) {
+	mdb, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { mdb.Close() })
+	mock.MatchExpectationsInOrder(false)
+	return New(sqlx.NewDb(mdb, "mysql"), metrics.New(), func(context.Context, webhook.Event) {}, func(context.Context, string, string) {}), mock
+}
+
+// recordEvents records the events a notifies webhooks of.
+func recordEvents(a *API) *[]webhook.Event {
+	var ee []webhook.Event
+	a.notify = func(_ context.Context, e webhook.Event) { ee = append(ee, e) }
+	return &ee
+}
+
+// recordRefreshes records whose home tabs a refreshes, as team/user.
+func recordRefreshes(a *API) *[]string {
+	var rr []string
+	a.refresh = func(_ context.Context, tid, uid string) { rr = append(rr, tid+"/"+uid) }
+	return &rr
+}
+
+// expectToken lets the test secret authenticate one request with scopes.
+func expectToken(mock sqlmock.Sqlmock, scopes ...string) {
+	mock.ExpectQuery("FROM api_tokens WHERE token_hash = \\? AND revoked_at IS NULL").WithArgs(hashToken(testSecret)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "team_id", "user_id", "name", "scopes", "last_used_at", "created_at"}).
+			AddRow(1, "T1", "U1", "notebook", strings.Join(scopes, ","), nil, time.Now()))
+	mock.ExpectExec("UPDATE api_tokens SET last_used_at").WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+func request(a *API, method, path, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	r.Header.Set("Authorization", "Bearer "+testSecret)
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	return w
+}
+
+// answers answers every question with its first option.
+func answers() map[string]string {
+	m := make(map[string]string)
+	for _, q := range reflection.Questions {
+		m[q.Field] = q.Options.Options[0].Code
+	}
+	return m
+}
+
+func TestAuthentication(t *testing.T) {
+	a, mock := newTestAPI(t)
+
+	tcs := map[string]string{
+		"no token":         "",
+		"not bearer":       "Basic " + testSecret,
+		"not an app token": "Bearer xoxb-123",
+	}
+	for name, auth := range tcs {
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, Prefix+"reflections", nil)
+			r.Header.Set("Authorization", auth)
+			w := httptest.NewRecorder()
+			a.ServeHTTP(w, r)
+			require.Equal(t, http.StatusUnauthorized, w.Code)
+			require.Contains(t, w.Header().Get("WWW-Authenticate"), "Bearer")
+		})
+	}
+
+	mock.ExpectQuery("FROM api_tokens WHERE token_hash").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	w := request(a, http.MethodGet, Prefix+"reflections", "")
+	require.Equal(t, http.StatusUnauthorized, w.Code, "unknown and revoked tokens should be refused")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestScopes(t *testing.T) {
+	a, mock := newTestAPI(t)
+
+	expectToken(mock, ScopeRead)
+	w := request(a, http.MethodDelete, Prefix+"reflections/1625259600", "")
+	require.Equal(t, http.StatusForbidden, w.Code, "read tokens shouldn't change reflections")
+
+	expectToken(mock, ScopeWrite)
+	w = request(a, http.MethodGet, Prefix+"reflections", "")
+	require.Equal(t, http.StatusForbidden, w.Code)
+
+	expectToken(mock, ScopeRead)
+	w = request(a, http.MethodPatch, Prefix+"reflections", "")
+	require.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	require.Equal(t, "GET, POST", w.Header().Get("Allow"))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateReflection(t *testing.T) {
+	a, mock := newTestAPI(t)
+	events := recordEvents(a)
+	refreshes := recordRefreshes(a)
+	date := time.Date(2021, 7, 2, 21, 0, 0, 0, time.UTC)
+	body, err := json.Marshal(map[string]interface{}{"date": date, "answers": answers()})
+	require.NoError(t, err)
+
+	expectToken(mock, ScopeRead, ScopeWrite)
+	mock.ExpectExec("INSERT INTO reflections").WithArgs(append([]driver.Value{"T1", "U1", date}, anyArgs(len(reflection.Questions))...)...).WillReturnResult(sqlmock.NewResult(1, 1))
+	w := request(a, http.MethodPost, Prefix+"reflections", string(body))
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	require.Equal(t, Prefix+"reflections/1625259600", w.Header().Get("Location"))
+	var got export.Reflection
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+	require.Equal(t, "1625259600", got.ID)
+	require.Equal(t, "0-terrible", got.Answers[0].Code)
+	require.Len(t, *events, 1)
+	require.Equal(t, webhook.EventReflectionCreated, (*events)[0].Type)
+	require.Equal(t, "1625259600", (*events)[0].Reflection.ID)
+	require.Equal(t, []string{"T1/U1"}, *refreshes)
+
+	expectToken(mock, ScopeRead, ScopeWrite)
+	// 1062 is a duplicate key
+	mock.ExpectExec("INSERT INTO reflections").WillReturnError(&mysql.MySQLError{Number: 1062})
+	w = request(a, http.MethodPost, Prefix+"reflections", string(body))
+	require.Equal(t, http.StatusConflict, w.Code)
+	require.Len(t, *events, 1, "conflicts aren't notified")
+	require.Len(t, *refreshes, 1)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestInvalidReflections(t *testing.T) {
+	missing := answers()
+	delete(missing, "meeting_number")
+	wrong := answers()
+	wrong["work_day_quality"] = "9-perfect"
+	extra := answers()
+	extra["mood"] = "1-fine"
+
+	tcs := map[string]struct {
+		body  interface{}
+		field string
+	}{
+		"missing answer": {body: map[string]interface{}{"answers": missing}, field: "meeting_number"},
+		"unknown code":   {body: map[string]interface{}{"answers": wrong}, field: "work_day_quality"},
+		"unknown field":  {body: map[string]interface{}{"answers": extra}, field: "mood"},
+		"future date":    {body: map[string]interface{}{"answers": answers(), "date": time.Now().Add(time.Hour)}},
+		"unknown key":    {body: map[string]interface{}{"answers": answers(), "mood": "fine"}},
+	}
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			a, mock := newTestAPI(t)
+			body, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			expectToken(mock, ScopeWrite)
+			w := request(a, http.MethodPost, Prefix+"reflections", string(body))
+			require.Equal(t, http.StatusBadRequest, w.Code)
+			if len(tc.field) > 0 {
+				var res apiError
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+				require.Contains(t, res.Fields, tc.field)
+			}
+			require.NoError(t, mock.ExpectationsWereMet(), "nothing should be saved")
+		})
+	}
+}
+
+func TestListReflectionsPages(t *testing.T) {
+	a, mock := newTestAPI(t)
+	first := time.Date(2021, 7, 1, 21, 0, 0, 0, time.UTC)
+
+	expectToken(mock, ScopeRead)
+	mock.ExpectQuery("FROM reflections WHERE team_id = \\? AND user_id = \\? AND date >= \\? AND date < \\? ORDER BY date LIMIT \\?").
+		WithArgs("T1", "U1", time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC), maxDate, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"team_id", "user_id", "date", "work_day_quality"}).
+			AddRow("T1", "U1", first, "3-good").
+			AddRow("T1", "U1", first.AddDate(0, 0, 1), "2-ok"))
+	w := request(a, http.MethodGet, Prefix+"reflections?from=2021-07-01&limit=1&lang=fr", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var res reflectionList
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	require.Len(t, res.Reflections, 1)
+	require.Equal(t, "Bonne", res.Reflections[0].Answers[0].Label, "labels should be in the requested language")
+	require.Equal(t, "2021-07-02T21:00:00Z", res.Next)
+
+	expectToken(mock, ScopeRead)
+	w = request(a, http.MethodGet, Prefix+"reflections?from=yesterday", "")
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateReflection(t *testing.T) {
+	a, mock := newTestAPI(t)
+	events := recordEvents(a)
+	refreshes := recordRefreshes(a)
+	date := time.Unix(1625259600, 0).UTC()
+	body, err := json.Marshal(map[string]interface{}{"answers": answers()})
+	require.NoError(t, err)
+
+	expectToken(mock, ScopeWrite)
+	mock.ExpectQuery("FROM reflections WHERE team_id = \\? AND user_id = \\? AND date = \\?").WithArgs("T1", "U1", date).
+		WillReturnRows(sqlmock.NewRows([]string{"team_id", "user_id", "date"}).AddRow("T1", "U1", date))
+	mock.ExpectExec("UPDATE reflections SET .* WHERE team_id=\\? AND user_id=\\? AND date=\\?").WithArgs(append(anyArgs(len(reflection.Questions)), "T1", "U1", date)...).WillReturnResult(sqlmock.NewResult(0, 1))
+	w := request(a, http.MethodPut, Prefix+"reflections/1625259600", string(body))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, mock.ExpectationsWereMet())
+	require.Len(t, *events, 1)
+	require.Equal(t, webhook.EventReflectionUpdated, (*events)[0].Type)
+	require.Equal(t, []string{"T1/U1"}, *refreshes)
+}
+
+func TestUpdateAndDeleteMissingReflections(t *testing.T) {
+	a, mock := newTestAPI(t)
+	refreshes := recordRefreshes(a)
+	body, err := json.Marshal(map[string]interface{}{"answers": answers()})
+	require.NoError(t, err)
+
+	expectToken(mock, ScopeWrite)
+	mock.ExpectQuery("FROM reflections WHERE team_id = \\? AND user_id = \\? AND date = \\?").WillReturnRows(sqlmock.NewRows([]string{"team_id"}))
+	w := request(a, http.MethodPut, Prefix+"reflections/1625259600", string(body))
+	require.Equal(t, http.StatusNotFound, w.Code)
+
+	expectToken(mock, ScopeWrite)
+	mock.ExpectBegin()
+	date := time.Unix(1625259600, 0).UTC()
+	mock.ExpectExec("DELETE FROM reflections").WithArgs("T1", "U1", date, date.Add(time.Second)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+	w = request(a, http.MethodDelete, Prefix+"reflections/1625259600", "")
+	require.Equal(t, http.StatusNotFound, w.Code)
+
+	expectToken(mock, ScopeWrite)
+	w = request(a, http.MethodDelete, Prefix+"reflections/yesterday", "")
+	require.Equal(t, http.StatusNotFound, w.Code)
+	require.NoError(t, mock.ExpectationsWereMet())
+	require.Empty(t, *refreshes, "nothing changed")
+}
+
+func TestDeleteReflectionIsAudited(t *testing.T) {
+	a, mock := newTestAPI(t)
+	events := recordEvents(a)
+	refreshes := recordRefreshes(a)
+	date := time.Unix(1625259600, 0).UTC()
+
+	expectToken(mock, ScopeWrite)
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM reflections").WithArgs("T1", "U1", date, date.Add(time.Second)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO deletion_audit").WithArgs("T1", "U1", "reflection", date, date.Add(time.Second), 1).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	w := request(a, http.MethodDelete, Prefix+"reflections/1625259600", "")
+	require.Equal(t, http.StatusNoContent, w.Code)
+	require.NoError(t, mock.ExpectationsWereMet())
+	require.Equal(t, []webhook.Event{webhook.ReflectionsDeleted("T1", "U1", date, date.Add(time.Second), 1)}, *events)
+	require.Equal(t, []string{"T1/U1"}, *refreshes)
+}
+
+func TestSummarize(t *testing.T) {
+	rr := []reflection.Reflection{
+		{Date: time.Date(2021, 7, 1, 21, 0, 0, 0, time.UTC), WorkDayQuality: "3-good"},
+		{Date: time.Date(2021, 7, 2, 21, 0, 0, 0, time.UTC), WorkDayQuality: "0-terrible", MeetingNumber: "4-many"},
+	}
+	s := summarize(rr)
+	require.Equal(t, 2, s.Count)
+	require.Equal(t, rr[1].Date, *s.Last)
+	require.Equal(t, "work_day_quality", s.Questions[0].Field)
+	require.Equal(t, 2, s.Questions[0].Answered)
+	require.Equal(t, 1.5, *s.Questions[0].Average)
+	for _, q := range s.Questions {
+		require.NotEqual(t, "work_day_feeling", q.Field, "unordered questions can't be averaged")
+		if q.Field == "interrupted_amount" {
+			require.Nil(t, q.Average, "unanswered questions have no average")
+		}
+	}
+
+	empty := summarize(nil)
+	require.Zero(t, empty.Count)
+	require.Nil(t, empty.First)
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	a, _ := newTestAPI(t)
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, httptest.NewRequest(http.MethodGet, Prefix+"openapi.json", nil))
+	require.Equal(t, http.StatusOK, w.Code, "the document shouldn't need a token")
+
+	var doc struct {
+		OpenAPI string                            `json:"openapi"`
+		Paths   map[string]map[string]interface{} `json:"paths"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+	require.Equal(t, "3.0.3", doc.OpenAPI)
+	for path, methods := range map[string][]string{
+		"/questions":        {"get"},
+		"/reflections":      {"get", "post"},
+		"/reflections/{id}": {"get", "put", "delete"},
+		"/stats":            {"get"},
+	} {
+		for _, m := range methods {
+			require.Contains(t, doc.Paths[path], m, "%s %s should be documented", m, path)
+		}
+	}
+}
+
+func anyArgs(n int) []driver.Value {
+	aa := make([]driver.Value, n)
+	for i := range aa {
+		aa[i] = sqlmock.AnyArg()
+	}
+	return aa
+}
@@ -0,0 +1,300 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/jharlap/good-day-app/export"
+	"github.com/jharlap/good-day-app/i18n"
+	"github.com/jharlap/good-day-app/metrics"
+	"github.com/jharlap/good-day-app/reflection"
+	"github.com/jharlap/good-day-app/store"
+	"github.com/jharlap/good-day-app/webhook"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// DefaultLimit is how many reflections are listed at once unless a
+	// limit is given.
+	DefaultLimit = 100
+	// MaxLimit bounds the number of reflections listed at once.
+	MaxLimit = 1000
+
+	dateFormat = "2006-01-02"
+)
+
+// maxDate is after every reflection, for listing without an end.
+var maxDate = time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+
+// reflectionInput is the body of a request to create or update a
+// reflection, with an answer code for every question by its field.
+type reflectionInput struct {
+	// Date is when the reflection was made, now if it's not given. It can't
+	// be changed by an update.
+	Date    *time.Time        `json:"date"`
+	Answers map[string]string `json:"answers"`
+}
+
+type reflectionList struct {
+	Reflections []export.Reflection `json:"reflections"`
+	// Next is where the next page starts, if there are more reflections.
+	Next string `json:"next,omitempty"`
+}
+
+func (a *API) listQuestions(w http.ResponseWriter, r *http.Request, t Token, id string) {
+	lang := i18n.FromLocale(r.URL.Query().Get("lang"))
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"questions": export.QuestionsMeta(reflection.LocalizedQuestions(lang)),
+	})
+}
+
+func (a *API) listReflections(w http.ResponseWriter, r *http.Request, t Token, id string) {
+	q := r.URL.Query()
+	from, to, err := parseRange(q)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	limit := DefaultLimit
+	if v := q.Get("limit"); len(v) > 0 {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > MaxLimit {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("limit must be 1 to %d", MaxLimit))
+			return
+		}
+	}
+
+	var rr []reflection.Reflection
+	start := time.Now()
+	// one more than the limit, to know if there's another page
+	err = a.db.SelectContext(r.Context(), &rr, "SELECT "+store.Columns+" FROM reflections WHERE team_id = ? AND user_id = ? AND date >= ? AND date < ? ORDER BY date LIMIT ?", t.TeamID, t.UserID, from, to, limit+1)
+	a.metrics.ObserveQuery("api_list_reflections", start, err)
+	if err != nil {
+		log.Error().Err(err).Str("tid", t.TeamID).Str("uid", t.UserID).Msg("error listing reflections")
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	lang := i18n.FromLocale(q.Get("lang"))
+	res := reflectionList{Reflections: []export.Reflection{}}
+	for i, rf := range rr {
+		if i == limit {
+			res.Next = rf.Date.UTC().Format(time.RFC3339)
+			break
+		}
+		res.Reflections = append(res.Reflections, export.NewReflection(rf, reflection.LocalizedQuestions(lang)))
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (a *API) getReflection(w http.ResponseWriter, r *http.Request, t Token, id string) {
+	rf, ok := a.reflectionByID(w, r, t, id)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, export.NewReflection(rf, reflection.LocalizedQuestions(i18n.FromLocale(r.URL.Query().Get("lang")))))
+}
+
+func (a *API) createReflection(w http.ResponseWriter, r *http.Request, t Token, id string) {
+	var in reflectionInput
+	if err := decodeBody(w, r, &in); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid body: "+err.Error())
+		return
+	}
+	rf, fields := answersReflection(in.Answers)
+	if len(fields) > 0 {
+		writeJSON(w, http.StatusBadRequest, apiError{Error: "invalid answers", Fields: fields})
+		return
+	}
+
+	rf.TeamID = t.TeamID
+	rf.UserID = t.UserID
+	rf.Date = time.Now()
+	if in.Date != nil {
+		rf.Date = *in.Date
+	}
+	// the date column has no fractional seconds, so truncate rather than let
+	// mysql round, to keep the id identical to the stored date
+	rf.Date = rf.Date.UTC().Truncate(time.Second)
+	if rf.Date.After(time.Now()) {
+		writeError(w, http.StatusBadRequest, "date can't be in the future")
+		return
+	}
+
+	err := a.reflections.Save(r.Context(), rf)
+	if errors.Is(err, store.ErrExists) {
+		writeError(w, http.StatusConflict, "there's already a reflection at that date")
+		return
+	} else if err != nil {
+		log.Error().Err(err).Str("tid", t.TeamID).Str("uid", t.UserID).Msg("error creating reflection")
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	a.metrics.ReflectionsSaved(metrics.SourceAPI, 1)
+	a.notify(r.Context(), webhook.ReflectionSaved(rf, false))
+	a.refresh(r.Context(), t.TeamID, t.UserID)
+
+	res := export.NewReflection(rf, reflection.LocalizedQuestions(i18n.FromLocale(r.URL.Query().Get("lang"))))
+	w.Header().Set("Location", Prefix+"reflections/"+res.ID)
+	writeJSON(w, http.StatusCreated, res)
+}
+
+func (a *API) updateReflection(w http.ResponseWriter, r *http.Request, t Token, id string) {
+	var in reflectionInput
+	if err := decodeBody(w, r, &in); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid body: "+err.Error())
+		return
+	}
+	if in.Date != nil {
+		writeError(w, http.StatusBadRequest, "date can't be changed")
+		return
+	}
+	rf, fields := answersReflection(in.Answers)
+	if len(fields) > 0 {
+		writeJSON(w, http.StatusBadRequest, apiError{Error: "invalid answers", Fields: fields})
+		return
+	}
+
+	// mysql doesn't count unchanged rows as affected, so check the
+	// reflection exists first
+	existing, ok := a.reflectionByID(w, r, t, id)
+	if !ok {
+		return
+	}
+	rf.TeamID = existing.TeamID
+	rf.UserID = existing.UserID
+	rf.Date = existing.Date
+
+	if err := a.reflections.Update(r.Context(), rf); err != nil {
+		log.Error().Err(err).Str("tid", t.TeamID).Str("uid", t.UserID).Msg("error updating reflection")
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	a.metrics.ReflectionsSaved(metrics.SourceEdited, 1)
+	a.notify(r.Context(), webhook.ReflectionSaved(rf, true))
+	a.refresh(r.Context(), t.TeamID, t.UserID)
+
+	writeJSON(w, http.StatusOK, export.NewReflection(rf, reflection.LocalizedQuestions(i18n.FromLocale(r.URL.Query().Get("lang")))))
+}
+
+// deleteReflection deletes a reflection and records it in the deletion
+// audit log, like deleting one from the home tab.
+func (a *API) deleteReflection(w http.ResponseWriter, r *http.Request, t Token, id string) {
+	date, err := parseID(id)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	n, err := a.reflections.DeleteReflection(r.Context(), t.TeamID, t.UserID, date)
+	if err != nil {
+		log.Error().Err(err).Str("tid", t.TeamID).Str("uid", t.UserID).Msg("error deleting reflection")
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	} else if n == 0 {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	log.Info().Str("tid", t.TeamID).Str("uid", t.UserID).Int64("token", t.ID).Msg("deleted reflection through api")
+	a.metrics.ReflectionsDeleted(int(n))
+	start, end := store.ReflectionRange(date)
+	a.notify(r.Context(), webhook.ReflectionsDeleted(t.TeamID, t.UserID, start, end, n))
+	a.refresh(r.Context(), t.TeamID, t.UserID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// reflectionByID gets the token's user's reflection with id, or writes a not
+// found or error response and returns false.
+func (a *API) reflectionByID(w http.ResponseWriter, r *http.Request, t Token, id string) (reflection.Reflection, bool) {
+	date, err := parseID(id)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "not found")
+		return reflection.Reflection{}, false
+	}
+
+	rf, err := a.reflections.Get(r.Context(), t.TeamID, t.UserID, date)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, "not found")
+		return rf, false
+	} else if err != nil {
+		log.Error().Err(err).Str("tid", t.TeamID).Str("uid", t.UserID).Msg("error getting reflection")
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return rf, false
+	}
+	return rf, true
+}
+
+// answersReflection returns a reflection with answers by question field.
+// Like the Slack modal, every question must be answered with one of its
+// option codes; what's wrong with each answer that isn't is returned by
+// field.
+func answersReflection(answers map[string]string) (reflection.Reflection, map[string]string) {
+	var r reflection.Reflection
+	fields := make(map[string]string)
+	known := make(map[string]bool)
+	for _, q := range reflection.Questions {
+		known[q.Field] = true
+		code, ok := answers[q.Field]
+		if !ok || len(code) == 0 {
+			fields[q.Field] = "missing"
+			continue
+		}
+		if len(q.Options.ValueFor(code)) == 0 {
+			fields[q.Field] = fmt.Sprintf("%q isn't one of the question's option codes", code)
+			continue
+		}
+		r.SetValueForQuestion(q.Field, reflection.NumberPrefixedEnum(code))
+	}
+	for f := range answers {
+		if !known[f] {
+			fields[f] = "not a question"
+		}
+	}
+	return r, fields
+}
+
+// parseID returns the date of the reflection with id, its unix time.
+func parseID(id string) (time.Time, error) {
+	sec, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("error parsing reflection id: %w", err)
+	}
+	return time.Unix(sec, 0).UTC(), nil
+}
+
+// parseRange returns the from and to query parameters, each either an
+// RFC 3339 time or a date, which is midnight UTC. Without them, the range
+// is unbounded.
+func parseRange(q url.Values) (from, to time.Time, err error) {
+	from, to = time.Unix(0, 0).UTC(), maxDate
+	if v := q.Get("from"); len(v) > 0 {
+		if from, err = parseTime(v); err != nil {
+			return from, to, fmt.Errorf("invalid from: %w", err)
+		}
+	}
+	if v := q.Get("to"); len(v) > 0 {
+		if to, err = parseTime(v); err != nil {
+			return from, to, fmt.Errorf("invalid to: %w", err)
+		}
+	}
+	if to.Before(from) {
+		return from, to, errors.New("to is before from")
+	}
+	return from, to, nil
+}
+
+func parseTime(v string) (time.Time, error) {
+	if t, err := time.Parse(dateFormat, v); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return t, fmt.Errorf("%q is neither a date nor an RFC 3339 time", v)
+	}
+	return t.UTC(), nil
+}
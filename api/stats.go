@@ -0,0 +1,69 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/jharlap/good-day-app/insights"
+	"github.com/jharlap/good-day-app/reflection"
+	"github.com/jharlap/good-day-app/store"
+	"github.com/rs/zerolog/log"
+)
+
+// stats summarizes the user's reflections in a range.
+type stats struct {
+	Count int        `json:"count"`
+	First *time.Time `json:"first,omitempty"`
+	Last  *time.Time `json:"last,omitempty"`
+	// Questions has the average score of each ordered question.
+	Questions []questionStats `json:"questions"`
+}
+
+type questionStats struct {
+	Field    string   `json:"field"`
+	Answered int      `json:"answered"`
+	Average  *float64 `json:"average,omitempty"`
+}
+
+func (a *API) getStats(w http.ResponseWriter, r *http.Request, t Token, id string) {
+	from, to, err := parseRange(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var rr []reflection.Reflection
+	start := time.Now()
+	err = a.db.SelectContext(r.Context(), &rr, "SELECT "+store.Columns+" FROM reflections WHERE team_id = ? AND user_id = ? AND date >= ? AND date < ? ORDER BY date", t.TeamID, t.UserID, from, to)
+	a.metrics.ObserveQuery("api_stats_reflections", start, err)
+	if err != nil {
+		log.Error().Err(err).Str("tid", t.TeamID).Str("uid", t.UserID).Msg("error getting reflections for stats")
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, summarize(rr))
+}
+
+// summarize returns the stats of reflections, oldest first.
+func summarize(rr []reflection.Reflection) stats {
+	s := stats{Count: len(rr), Questions: []questionStats{}}
+	if len(rr) > 0 {
+		first, last := rr[0].Date.UTC(), rr[len(rr)-1].Date.UTC()
+		s.First, s.Last = &first, &last
+	}
+
+	averages, counts := insights.Averages(rr)
+	for _, q := range reflection.Questions {
+		if !q.Options.Ordered {
+			continue
+		}
+		qs := questionStats{Field: q.Field, Answered: counts[q.Field]}
+		if qs.Answered > 0 {
+			avg := averages[q.Field]
+			qs.Average = &avg
+		}
+		s.Questions = append(s.Questions, qs)
+	}
+	return s
+}
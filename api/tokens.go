@@ -0,0 +1,162 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Scopes of access tokens.
+const (
+	ScopeRead  = "reflections:read"
+	ScopeWrite = "reflections:write"
+)
+
+const (
+	// MaxActiveTokens is how many tokens a user can have at once.
+	MaxActiveTokens = 10
+	// MaxTokenNameLength is the longest a token's name can be.
+	MaxTokenNameLength = 100
+
+	// tokenPrefix marks the app's tokens, so they can be recognised, e.g. by
+	// secret scanners, if they leak.
+	tokenPrefix = "gdt_"
+)
+
+var (
+	// ErrTooManyTokens is returned when creating a token for a user who
+	// already has MaxActiveTokens.
+	ErrTooManyTokens = errors.New("too many active tokens")
+
+	errUnauthorized = errors.New("missing or invalid access token")
+)
+
+// Token is a personal access token. Its secret is only known when it's
+// created, since only a hash of it is stored.
+type Token struct {
+	ID         int64        `db:"id"`
+	TeamID     string       `db:"team_id"`
+	UserID     string       `db:"user_id"`
+	Name       string       `db:"name"`
+	Scopes     string       `db:"scopes"`
+	LastUsedAt sql.NullTime `db:"last_used_at"`
+	CreatedAt  time.Time    `db:"created_at"`
+}
+
+const tokenColumns = "id, team_id, user_id, name, scopes, last_used_at, created_at"
+
+// HasScope is true if the token was granted scope.
+func (t Token) HasScope(scope string) bool {
+	for _, s := range strings.Split(t.Scopes, ",") {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateToken issues a token with scopes for the user, returning its secret.
+func (a *API) CreateToken(ctx context.Context, teamID, userID, name string, scopes []string) (_ string, err error) {
+	defer func(t time.Time) { a.metrics.ObserveQuery("create_api_token", t, err) }(time.Now())
+
+	name = strings.TrimSpace(name)
+	if len(name) == 0 || len([]rune(name)) > MaxTokenNameLength {
+		return "", fmt.Errorf("token name must be 1 to %d characters", MaxTokenNameLength)
+	}
+	if len(scopes) == 0 {
+		return "", errors.New("token must have a scope")
+	}
+	for _, s := range scopes {
+		if s != ScopeRead && s != ScopeWrite {
+			return "", fmt.Errorf("unknown scope %q", s)
+		}
+	}
+
+	var n int
+	err = a.db.GetContext(ctx, &n, "SELECT COUNT(*) FROM api_tokens WHERE team_id = ? AND user_id = ? AND revoked_at IS NULL", teamID, userID)
+	if err != nil {
+		return "", fmt.Errorf("error counting api tokens: %w", err)
+	}
+	if n >= MaxActiveTokens {
+		return "", ErrTooManyTokens
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("error reading random bytes: %w", err)
+	}
+	secret := tokenPrefix + base64.RawURLEncoding.EncodeToString(b)
+
+	_, err = a.db.ExecContext(ctx, "INSERT INTO api_tokens SET team_id=?, user_id=?, name=?, token_hash=?, scopes=?", teamID, userID, name, hashToken(secret), strings.Join(scopes, ","))
+	if err != nil {
+		return "", fmt.Errorf("error creating api token: %w", err)
+	}
+	return secret, nil
+}
+
+// ActiveTokens returns the user's tokens that haven't been revoked, oldest
+// first.
+func (a *API) ActiveTokens(ctx context.Context, teamID, userID string) ([]Token, error) {
+	var tt []Token
+	start := time.Now()
+	err := a.db.SelectContext(ctx, &tt, "SELECT "+tokenColumns+" FROM api_tokens WHERE team_id = ? AND user_id = ? AND revoked_at IS NULL ORDER BY created_at", teamID, userID)
+	a.metrics.ObserveQuery("active_api_tokens", start, err)
+	if err != nil {
+		return nil, fmt.Errorf("error getting active api tokens: %w", err)
+	}
+	return tt, nil
+}
+
+func (a *API) RevokeToken(ctx context.Context, teamID, userID string, id int64) error {
+	start := time.Now()
+	_, err := a.db.ExecContext(ctx, "UPDATE api_tokens SET revoked_at = ? WHERE id = ? AND team_id = ? AND user_id = ? AND revoked_at IS NULL", time.Now(), id, teamID, userID)
+	a.metrics.ObserveQuery("revoke_api_token", start, err)
+	if err != nil {
+		return fmt.Errorf("error revoking api token: %w", err)
+	}
+	return nil
+}
+
+// authenticate returns the token the request is authorized by, or
+// errUnauthorized if it has none or it isn't valid.
+func (a *API) authenticate(r *http.Request) (_ Token, err error) {
+	auth := r.Header.Get("Authorization")
+	if len(auth) < 7 || !strings.EqualFold(auth[:7], "bearer ") {
+		return Token{}, errUnauthorized
+	}
+	secret := strings.TrimSpace(auth[7:])
+	if !strings.HasPrefix(secret, tokenPrefix) {
+		return Token{}, errUnauthorized
+	}
+
+	defer func(t time.Time) { a.metrics.ObserveQuery("authenticate_api_token", t, err) }(time.Now())
+	var t Token
+	err = a.db.GetContext(r.Context(), &t, "SELECT "+tokenColumns+" FROM api_tokens WHERE token_hash = ? AND revoked_at IS NULL", hashToken(secret))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Token{}, errUnauthorized
+	} else if err != nil {
+		return Token{}, fmt.Errorf("error getting api token: %w", err)
+	}
+
+	// only for showing the user, so a failure shouldn't fail the request
+	if _, err := a.db.ExecContext(r.Context(), "UPDATE api_tokens SET last_used_at = ? WHERE id = ?", time.Now(), t.ID); err != nil {
+		log.Error().Err(err).Int64("token", t.ID).Msg("error recording api token use")
+	}
+	return t, nil
+}
+
+// hashToken is how a token's secret is stored. Secrets are random, so a fast
+// hash can't be brute forced.
+func hashToken(secret string) []byte {
+	sum := sha256.Sum256([]byte(secret))
+	return sum[:]
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/jharlap/good-day-app/api"
+	"github.com/jharlap/good-day-app/config"
+	"github.com/jharlap/good-day-app/dashboard"
+	"github.com/jharlap/good-day-app/heatmap"
+	"github.com/jharlap/good-day-app/metrics"
+	"github.com/jharlap/good-day-app/report"
+	"github.com/jharlap/good-day-app/slackupload"
+	"github.com/jharlap/good-day-app/store"
+	"github.com/jharlap/good-day-app/urlsigner"
+	"github.com/jharlap/good-day-app/webhook"
+	"github.com/jmoiron/sqlx"
+	"github.com/slack-go/slack"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// App is the Slack app, with everything it depends on.
+type App struct {
+	slack         slackClient
+	uploader      fileUploader
+	signingSecret string
+	baseURL       string
+	signer        *urlsigner.Engine
+	db            *sqlx.DB
+	heatmapper    *heatmap.Heatmap
+	reporter      *report.InterruptionsMeetingsReport
+	dashboard     *dashboard.Dashboard
+	api           *api.API
+	reflections   *store.Store
+	webhooks      *webhook.Dispatcher
+	metrics       *metrics.Metrics
+	jobs          *jobRunner
+	handler       http.Handler
+}
+
+// NewApp connects to the database and creates the app.
+func NewApp(cfg config.Config) (*App, error) {
+	db, err := sqlx.Open("mysql", cfg.DatabaseDSN)
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to database: %w", err)
+	}
+	db.SetConnMaxLifetime(time.Minute * 3)
+	db.SetMaxOpenConns(10)
+	db.SetMaxIdleConns(10)
+
+	return newApp(cfg, db)
+}
+
+// newApp creates the app with an open database.
+func newApp(cfg config.Config, db *sqlx.DB) (*App, error) {
+	var slackOpts []slack.Option
+	var uploadOpts []slackupload.Option
+	if len(cfg.SlackAPIURL) > 0 {
+		slackOpts = append(slackOpts, slack.OptionAPIURL(cfg.SlackAPIURL))
+		uploadOpts = append(uploadOpts, slackupload.OptionAPIURL(cfg.SlackAPIURL))
+	}
+
+	signer, err := urlsigner.New(cfg.URLSigningKeys...)
+	if err != nil {
+		return nil, fmt.Errorf("error creating url signer: %w", err)
+	}
+	// links from before tokens named their resource were only handed out for
+	// the default charts
+	signer.AllowLegacy(heatmap.Resource, report.Resource)
+
+	m := metrics.New()
+	a := &App{
+		slack:         instrumentedSlack{slack.New(cfg.SlackBotToken, slackOpts...), m},
+		uploader:      instrumentedUploader{slackupload.New(cfg.SlackBotToken, uploadOpts...), m},
+		signingSecret: cfg.SlackSigningSecret,
+		baseURL:       cfg.BaseURL,
+		signer:        signer,
+		db:            db,
+		heatmapper:    heatmap.New(cfg.BaseURL+"/heatmap/", signer, db, defaultFontFaceBytes, m),
+		reporter:      report.New(cfg.BaseURL+"/report/", signer, db, cfg.RenderURL, cfg.RenderCredsFile, m),
+		dashboard:     dashboard.New(cfg.BaseURL+"/dashboard/", signer, db, m),
+		reflections:   store.New(db, m),
+		webhooks:      webhook.New(db, m),
+		metrics:       m,
+		jobs:          newJobRunner(),
+	}
+	a.api = api.New(db, m, a.notifyWebhooks, a.refreshHomeView)
+	signer.CheckGenerations(a.linkGeneration)
+	a.handler = limitRequestBody(maxRequestBodySize, a.routes())
+	return a, nil
+}
+
+// Handler serves Slack's requests, the app's signed chart URLs, and its health
+// and metrics endpoints.
+func (a *App) Handler() http.Handler {
+	return a.handler
+}
+
+// Close releases the app's database connections.
+func (a *App) Close() error {
+	return a.db.Close()
+}
+
+func (a *App) routes() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", http.NotFound)
+	mux.HandleFunc("/healthz", handleHealthz)
+	mux.HandleFunc("/readyz", a.handleReadyz)
+	mux.HandleFunc("/version", handleVersion)
+	mux.Handle("/metrics", a.metrics.Handler())
+	mux.Handle("/event", a.instrument("event", a.verifySecret(http.HandlerFunc(a.handleEvent))))
+	mux.Handle("/interactive", a.instrument("interactive", a.verifySecret(http.HandlerFunc(a.handleInteractive))))
+	mux.Handle("/slash", a.instrument("slash", a.verifySecret(http.HandlerFunc(a.handleSlash))))
+	mux.Handle("/heatmap/", a.instrument("heatmap", a.heatmapper))                      // no verifySecret because this is a signed URL
+	mux.Handle("/report/", a.instrument("report", a.reporter))                          // no verifySecret because this is a signed URL
+	mux.Handle("/share/", a.instrument("share", http.HandlerFunc(a.handleShare)))       // no verifySecret because this is a signed URL
+	mux.Handle("/dashboard/", a.instrument("dashboard", a.dashboard))                   // no verifySecret because this is a signed URL
+	mux.Handle("/reflect/", a.instrument("reflect", http.HandlerFunc(a.handleWebForm))) // no verifySecret because this is a signed URL
+	mux.Handle(api.Prefix, a.instrument("api", a.api))                                  // no verifySecret because requests carry an access token
+	return mux
+}
+
+// instrument records metrics and a trace span for each request h serves.
+func (a *App) instrument(name string, h http.Handler) http.Handler {
+	return otelhttp.NewHandler(a.metrics.InstrumentHandler(name, h), name)
+}
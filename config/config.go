@@ -0,0 +1,311 @@
+// Package config loads the app's configuration from the environment and an
+// optional JSON file, and checks it before the app starts.
+package config
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jharlap/good-day-app/tracing"
+	"github.com/jharlap/good-day-app/urlsigner"
+)
+
+// MinURLSigningKeyLength is the shortest URL signing key accepted, in bytes.
+const MinURLSigningKeyLength = 32
+
+// DefaultURLSigningKeyID is the ID of the key from URL_SIGNING_KEY_BASE64.
+const DefaultURLSigningKeyID = "default"
+
+// Config is the app's configuration.
+type Config struct {
+	// Port is what the HTTP server listens on.
+	Port string `json:"port"`
+
+	SlackBotToken      string `json:"slack_bot_token"`
+	SlackSigningSecret string `json:"slack_signing_secret"`
+	// SlackAPIURL overrides the Slack API URL, which is only useful for testing.
+	SlackAPIURL string `json:"slack_api_url"`
+
+	DatabaseDSN string `json:"database_dsn"`
+
+	// BaseURL is where the app is reachable, for links to its charts.
+	BaseURL string `json:"base_url"`
+	// URLSigningKeys are comma separated id:base64 keys for signing links.
+	// The first signs new links, and the rest only verify old ones, so keys
+	// can be rotated without breaking links.
+	URLSigningKeysList string `json:"url_signing_keys"`
+	// URLSigningKeyBase64 is a single key, used after any URLSigningKeysList
+	// with the ID DefaultURLSigningKeyID.
+	URLSigningKeyBase64 string          `json:"url_signing_key_base64"`
+	URLSigningKeys      []urlsigner.Key `json:"-"`
+
+	RenderURL       string `json:"render_url"`
+	RenderCredsFile string `json:"render_creds_file"`
+
+	// TracingExporter is where traces are sent: "none", the default, or
+	// "otlp" for an OpenTelemetry collector at OTLPEndpoint.
+	TracingExporter string `json:"tracing_exporter"`
+	OTLPEndpoint    string `json:"otlp_endpoint"`
+}
+
+// env maps environment variables to the settings they set.
+var env = []struct {
+	name string
+	set  func(*Config, string)
+}{
+	{"PORT", func(c *Config, v string) { c.Port = v }},
+	{"SLACK_BOT_TOKEN", func(c *Config, v string) { c.SlackBotToken = v }},
+	{"SLACK_SIGNING_SECRET", func(c *Config, v string) { c.SlackSigningSecret = v }},
+	{"SLACK_API_URL", func(c *Config, v string) { c.SlackAPIURL = v }},
+	{"DATABASE_DSN", func(c *Config, v string) { c.DatabaseDSN = v }},
+	{"BASE_URL", func(c *Config, v string) { c.BaseURL = v }},
+	{"URL_SIGNING_KEYS", func(c *Config, v string) { c.URLSigningKeysList = v }},
+	{"URL_SIGNING_KEY_BASE64", func(c *Config, v string) { c.URLSigningKeyBase64 = v }},
+	{"RENDER_URL", func(c *Config, v string) { c.RenderURL = v }},
+	{"RENDER_CREDS_FILE", func(c *Config, v string) { c.RenderCredsFile = v }},
+	{"TRACING_EXPORTER", func(c *Config, v string) { c.TracingExporter = v }},
+	{"OTLP_ENDPOINT", func(c *Config, v string) { c.OTLPEndpoint = v }},
+}
+
+// ValidationError lists everything wrong with a configuration, so it can all
+// be fixed at once.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration: " + strings.Join(e.Problems, "; ")
+}
+
+// Load reads the JSON file at path, if path isn't empty, then overrides it
+// with environment variables, and validates the result.
+func Load(path string) (Config, error) {
+	return load(path, os.LookupEnv)
+}
+
+func load(path string, lookupEnv func(string) (string, bool)) (Config, error) {
+	c := Config{Port: "3000", TracingExporter: tracing.ExporterNone}
+
+	if len(path) > 0 {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return c, fmt.Errorf("error reading config file: %w", err)
+		}
+		if err := json.Unmarshal(b, &c); err != nil {
+			return c, fmt.Errorf("error parsing config file %s: %w", path, err)
+		}
+	}
+
+	for _, e := range env {
+		if v, ok := lookupEnv(e.name); ok && len(v) > 0 {
+			e.set(&c, v)
+		}
+	}
+
+	return c, c.normalize()
+}
+
+// normalize checks the configuration and puts values in the form the app
+// uses: the signing key decoded, the DSN with the params we need, and URLs
+// without trailing slashes.
+func (c *Config) normalize() error {
+	var problems []string
+	required := func(name, v string) bool {
+		if len(v) == 0 {
+			problems = append(problems, name+" is required")
+			return false
+		}
+		return true
+	}
+
+	required("SLACK_BOT_TOKEN", c.SlackBotToken)
+	required("SLACK_SIGNING_SECRET", c.SlackSigningSecret)
+
+	if len(c.URLSigningKeysList) == 0 && len(c.URLSigningKeyBase64) == 0 {
+		problems = append(problems, "URL_SIGNING_KEYS or URL_SIGNING_KEY_BASE64 is required")
+	} else {
+		keys, kp := parseURLSigningKeys(c.URLSigningKeysList, c.URLSigningKeyBase64)
+		problems = append(problems, kp...)
+		c.URLSigningKeys = keys
+	}
+
+	if required("DATABASE_DSN", c.DatabaseDSN) {
+		dsn, err := withDSNParams(c.DatabaseDSN)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("DATABASE_DSN is invalid: %s", err))
+		} else {
+			c.DatabaseDSN = dsn
+		}
+	}
+
+	for _, u := range []struct {
+		name string
+		v    *string
+	}{
+		{"BASE_URL", &c.BaseURL},
+		{"RENDER_URL", &c.RenderURL},
+	} {
+		if !required(u.name, *u.v) {
+			continue
+		}
+		if err := checkURL(*u.v); err != nil {
+			problems = append(problems, fmt.Sprintf("%s is invalid: %s", u.name, err))
+			continue
+		}
+		*u.v = strings.TrimRight(*u.v, "/")
+	}
+	if len(c.SlackAPIURL) > 0 {
+		if err := checkURL(c.SlackAPIURL); err != nil {
+			problems = append(problems, fmt.Sprintf("SLACK_API_URL is invalid: %s", err))
+		}
+	}
+
+	if !oneOf(c.TracingExporter, tracing.Exporters) {
+		problems = append(problems, fmt.Sprintf("TRACING_EXPORTER must be one of %s, not %q", strings.Join(tracing.Exporters, ", "), c.TracingExporter))
+	}
+	if len(c.OTLPEndpoint) > 0 {
+		if err := checkURL(c.OTLPEndpoint); err != nil {
+			problems = append(problems, fmt.Sprintf("OTLP_ENDPOINT is invalid: %s", err))
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// parseURLSigningKeys decodes the keys in list, then the single key, returning
+// every problem with them.
+func parseURLSigningKeys(list, single string) ([]urlsigner.Key, []string) {
+	var keys []urlsigner.Key
+	var problems []string
+	seen := make(map[string]bool)
+	add := func(name, id, b64 string) {
+		key, err := base64.StdEncoding.DecodeString(b64)
+		switch {
+		case !urlsigner.ValidKeyID(id):
+			problems = append(problems, fmt.Sprintf("%s key id %q must be 1 to 32 letters, digits, dashes or underscores", name, id))
+		case seen[id]:
+			problems = append(problems, fmt.Sprintf("%s key id %q is used more than once", name, id))
+		case err != nil:
+			problems = append(problems, fmt.Sprintf("%s key %q is not valid base64: %s", name, id, err))
+		case len(key) < MinURLSigningKeyLength:
+			problems = append(problems, fmt.Sprintf("%s key %q must decode to at least %d bytes, not %d", name, id, MinURLSigningKeyLength, len(key)))
+		default:
+			keys = append(keys, urlsigner.Key{ID: id, Secret: key})
+		}
+		seen[id] = true
+	}
+
+	if len(list) > 0 {
+		for _, kv := range strings.Split(list, ",") {
+			i := strings.Index(kv, ":")
+			if i < 0 {
+				problems = append(problems, "URL_SIGNING_KEYS must be comma separated id:base64 keys")
+				continue
+			}
+			add("URL_SIGNING_KEYS", strings.TrimSpace(kv[:i]), strings.TrimSpace(kv[i+1:]))
+		}
+	}
+	if len(single) > 0 {
+		add("URL_SIGNING_KEY_BASE64", DefaultURLSigningKeyID, single)
+	}
+	return keys, problems
+}
+
+// withDSNParams adds the params the app needs to a MySQL DSN, keeping any
+// it already has.
+func withDSNParams(dsn string) (string, error) {
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return "", err
+	}
+
+	cfg.ParseTime = true
+	if cfg.Loc == nil {
+		cfg.Loc = time.UTC
+	}
+	return cfg.FormatDSN(), nil
+}
+
+func checkURL(v string) error {
+	u, err := url.Parse(v)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%q is not an http or https URL", v)
+	}
+	if len(u.Host) == 0 {
+		return fmt.Errorf("%q has no host", v)
+	}
+	return nil
+}
+
+func oneOf(v string, vv []string) bool {
+	for _, o := range vv {
+		if v == o {
+			return true
+		}
+	}
+	return false
+}
+
+// Addr is the address for the HTTP server to listen on.
+func (c Config) Addr() string {
+	return ":" + strings.TrimPrefix(c.Port, ":")
+}
+
+// Summary describes the configuration without its secrets, for logging.
+func (c Config) Summary() string {
+	dsn := redacted(c.DatabaseDSN)
+	if cfg, err := mysql.ParseDSN(c.DatabaseDSN); err == nil {
+		if len(cfg.Passwd) > 0 {
+			cfg.Passwd = "redacted"
+		}
+		dsn = cfg.FormatDSN()
+	}
+
+	ss := []string{
+		"port=" + c.Port,
+		"base_url=" + c.BaseURL,
+		"render_url=" + c.RenderURL,
+		"render_creds_file=" + c.RenderCredsFile,
+		"database_dsn=" + dsn,
+		"slack_bot_token=" + redacted(c.SlackBotToken),
+		"slack_signing_secret=" + redacted(c.SlackSigningSecret),
+		"url_signing_keys=" + keyIDs(c.URLSigningKeys),
+		"tracing_exporter=" + c.TracingExporter,
+	}
+	if len(c.SlackAPIURL) > 0 {
+		ss = append(ss, "slack_api_url="+c.SlackAPIURL)
+	}
+	if len(c.OTLPEndpoint) > 0 {
+		ss = append(ss, "otlp_endpoint="+c.OTLPEndpoint)
+	}
+	return strings.Join(ss, " ")
+}
+
+// keyIDs lists the IDs of keys, which aren't secret.
+func keyIDs(kk []urlsigner.Key) string {
+	ids := make([]string, len(kk))
+	for i, k := range kk {
+		ids[i] = k.ID
+	}
+	return "[" + strings.Join(ids, ",") + "]"
+}
+
+// redacted hides a secret, saying only whether it is set.
+func redacted(v string) string {
+	if len(v) == 0 {
+		return "unset"
+	}
+	return "redacted"
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jharlap/good-day-app/i18n"
+	"github.com/jharlap/good-day-app/insights"
+	"github.com/jharlap/good-day-app/reflection"
+	"github.com/rs/zerolog/log"
+	"github.com/slack-go/slack"
+)
+
+const (
+	// comparisonDays is how far back the average a reflection is compared with goes.
+	comparisonDays = 28
+	// comparisonMinSamples is how many earlier answers are needed before comparing.
+	comparisonMinSamples = 5
+	// streakHistoryDays is how far back we look for the current streak.
+	streakHistoryDays = 366
+)
+
+// sendReflectionConfirmation DMs the user a summary of the reflection they just
+// saved, comparing each answer with their recent average.
+func (a *App) sendReflectionConfirmation(ctx context.Context, r reflection.Reflection, su slackUser, isEdit bool) {
+	rr, err := a.userReflectionsSince(ctx, r.TeamID, r.UserID, time.Now().AddDate(0, 0, -streakHistoryDays))
+	if err != nil {
+		// still confirm the save, just without comparisons
+		log.Error().Err(err).Str("tid", r.TeamID).Str("uid", r.UserID).Msg("error loading reflections for confirmation")
+	}
+
+	title := i18n.T(su.Lang, "reflection.saved")
+	if isEdit {
+		title = i18n.T(su.Lang, "reflection.updated")
+	}
+
+	_, _, err = a.slack.PostMessageContext(
+		ctx,
+		r.UserID,
+		slack.MsgOptionText(title, false),
+		slack.MsgOptionBlocks(a.reflectionConfirmationBlocks(r, rr, su, title)...),
+	)
+	if err != nil {
+		log.Error().Err(err).Msgf("error posting reflection confirmation to %s", r.UserID)
+	}
+}
+
+func (a *App) reflectionConfirmationBlocks(r reflection.Reflection, history []reflection.Reflection, su slackUser, title string) []slack.Block {
+	averages, counts := recentAverages(r, history)
+
+	bb := []slack.Block{
+		slack.NewHeaderBlock(slack.NewTextBlockObject(slack.PlainTextType, title, false, false)),
+		slack.NewContextBlock("",
+			slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf("%s · %s", slackDate(r.Date, su.Location), i18n.T(su.Lang, "confirm.compared")), false, false),
+		),
+	}
+
+	for _, q := range reflection.LocalizedQuestions(su.Lang) {
+		code := r.ValueForQuestion(q.Field)
+		text := fmt.Sprintf("*%s*\n%s", q.Text, q.Options.ValueFor(code))
+		if counts[q.Field] >= comparisonMinSamples {
+			if c := comparisonText(su.Lang, q, reflection.NumberPrefixedEnum(code), averages[q.Field]); len(c) > 0 {
+				text = fmt.Sprintf("%s  _(%s)_", text, c)
+			}
+		}
+		bb = append(bb, slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, text, false, false), nil, nil))
+	}
+
+	streakText := i18n.T(su.Lang, "confirm.streak_start")
+	if n := confirmationStreak(r, history, time.Now(), su.Location); n > 1 {
+		streakText = i18n.T(su.Lang, "confirm.streak", n)
+	}
+	bb = append(bb,
+		slack.NewDividerBlock(),
+		slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, streakText, false, false), nil, nil),
+	)
+
+	reportBtn := slack.NewButtonBlockElement(confirmationButtonOpenReport, "open-report-btn", slack.NewTextBlockObject(slack.PlainTextType, i18n.T(su.Lang, "confirm.button.report"), false, false))
+	reportBtn.URL = a.reporter.URLForTeamAndUser(r.TeamID, r.UserID, su.TZOffset/3600, su.Settings.LinkGeneration)
+	bb = append(bb, slack.NewActionBlock(
+		"confirmation-action-block",
+		slack.NewButtonBlockElement(confirmationButtonEditReflection, strconv.FormatInt(r.Date.Unix(), 10), slack.NewTextBlockObject(slack.PlainTextType, i18n.T(su.Lang, "confirm.button.edit"), false, false)),
+		reportBtn,
+	))
+
+	return bb
+}
+
+// recentAverages returns the average answer to each question over the
+// reflections in history from the comparisonDays before r, with how many
+// answered it. Unanswered questions aren't counted.
+func recentAverages(r reflection.Reflection, history []reflection.Reflection) (map[string]float64, map[string]int) {
+	var earlier []reflection.Reflection
+	windowStart := r.Date.AddDate(0, 0, -comparisonDays)
+	for _, h := range history {
+		if h.Date.Before(r.Date) && !h.Date.Before(windowStart) {
+			earlier = append(earlier, h)
+		}
+	}
+	return insights.Averages(earlier)
+}
+
+// confirmationStreak is the user's current streak at now in loc, counting r
+// whether or not it's in history yet.
+func confirmationStreak(r reflection.Reflection, history []reflection.Reflection, now time.Time, loc *time.Location) int {
+	dates := []time.Time{r.Date}
+	for _, h := range history {
+		dates = append(dates, h.Date)
+	}
+	return insights.CurrentStreak(dates, now, loc)
+}
+
+// comparisonText describes how an answer compares with the average, e.g.
+// "more interruptions than usual", or returns "" if it is about usual or the
+// question's answers can't be compared as more or less.
+func comparisonText(lang string, q reflection.Question, answer reflection.NumberPrefixedEnum, avg float64) string {
+	v := answer.IntVal()
+	if v < 0 || !q.Options.Ordered {
+		return ""
+	}
+
+	switch d := float64(v) - avg; {
+	case d >= 1:
+		return i18n.T(lang, "compare."+q.Field+".more")
+	case d <= -1:
+		return i18n.T(lang, "compare."+q.Field+".less")
+	default:
+		return ""
+	}
+}
+
+// slackDate formats t so Slack displays it as a date in the reader's own
+// timezone, falling back to the date in loc.
+func slackDate(t time.Time, loc *time.Location) string {
+	return fmt.Sprintf("<!date^%d^{date_long_pretty}|%s>", t.Unix(), t.In(loc).Format("Monday, January 2, 2006"))
+}
+
+// slackDateTime formats t as a date and time that Slack shows in the
+// reader's timezone.
+func slackDateTime(t time.Time, loc *time.Location) string {
+	return fmt.Sprintf("<!date^%d^{date_short_pretty} {time}|%s>", t.Unix(), t.In(loc).Format("Jan 2, 2006 15:04"))
+}
+
+const dateFormat = "2006-01-02"
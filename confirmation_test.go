@@ -0,0 +1,155 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jharlap/good-day-app/i18n"
+	"github.com/jharlap/good-day-app/metrics"
+	"github.com/jharlap/good-day-app/reflection"
+	"github.com/jharlap/good-day-app/report"
+	"github.com/jharlap/good-day-app/urlsigner"
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/require"
+)
+
+func reflectedAt(t *testing.T, s string) reflection.Reflection {
+	d, err := time.Parse("2006-01-02 15:04", s)
+	require.NoError(t, err)
+	return reflection.Reflection{Date: d, WorkDayQuality: "2-ok"}
+}
+
+func question(field string) reflection.Question {
+	for _, q := range reflection.Questions {
+		if q.Field == field {
+			return q
+		}
+	}
+	panic("no question " + field)
+}
+
+func TestConfirmationStreak(t *testing.T) {
+	// Wednesday July 7th 2021
+	now, err := time.Parse("2006-01-02 15:04", "2021-07-07 18:00")
+	require.NoError(t, err)
+
+	tcs := map[string]struct {
+		r       string
+		history []string
+		ex      int
+	}{
+		"first reflection":         {r: "2021-07-07 17:00", ex: 1},
+		"over a weekend":           {r: "2021-07-07 17:00", history: []string{"2021-07-01 17:00", "2021-07-02 17:00", "2021-07-05 17:00", "2021-07-06 17:00"}, ex: 5},
+		"today not yet reflected":  {r: "2021-07-06 17:00", history: []string{"2021-07-05 17:00", "2021-07-06 17:00"}, ex: 2},
+		"gap breaks the streak":    {r: "2021-07-07 17:00", history: []string{"2021-07-02 17:00", "2021-07-05 17:00"}, ex: 1},
+		"edit already in history":  {r: "2021-07-07 17:00", history: []string{"2021-07-06 17:00", "2021-07-07 17:00"}, ex: 2},
+		"editing an older one":     {r: "2021-06-30 17:00", history: []string{"2021-07-06 17:00"}, ex: 1},
+		"weekend reflection ahead": {r: "2021-07-07 17:00", history: []string{"2021-07-03 10:00", "2021-07-05 17:00", "2021-07-06 17:00"}, ex: 4},
+	}
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			var history []reflection.Reflection
+			for _, h := range tc.history {
+				history = append(history, reflectedAt(t, h))
+			}
+			require.Equal(t, tc.ex, confirmationStreak(reflectedAt(t, tc.r), history, now, time.UTC))
+		})
+	}
+}
+
+func TestRecentAverages(t *testing.T) {
+	r := reflectedAt(t, "2021-07-07 17:00")
+
+	tcs := map[string]struct {
+		history []reflection.Reflection
+		avg     float64
+		count   int
+	}{
+		"no prior data": {},
+		"only itself":   {history: []reflection.Reflection{r}},
+		"earlier days": {history: []reflection.Reflection{
+			{Date: r.Date.AddDate(0, 0, -1), WorkDayQuality: "4-awesome"},
+			{Date: r.Date.AddDate(0, 0, -2), WorkDayQuality: "1-bad"},
+		}, avg: 2.5, count: 2},
+		"some unanswered": {history: []reflection.Reflection{
+			{Date: r.Date.AddDate(0, 0, -1), WorkDayQuality: "3-good"},
+			{Date: r.Date.AddDate(0, 0, -2)},
+			{Date: r.Date.AddDate(0, 0, -3), WorkDayQuality: "0-terrible"},
+		}, avg: 1.5, count: 2},
+		"outside the window": {history: []reflection.Reflection{
+			{Date: r.Date.AddDate(0, 0, -comparisonDays-1), WorkDayQuality: "0-terrible"},
+			{Date: r.Date.AddDate(0, 0, -comparisonDays), WorkDayQuality: "4-awesome"},
+			{Date: r.Date.AddDate(0, 0, 1), WorkDayQuality: "0-terrible"},
+		}, avg: 4, count: 1},
+	}
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			averages, counts := recentAverages(r, tc.history)
+			require.Equal(t, tc.count, counts["work_day_quality"])
+			require.InDelta(t, tc.avg, averages["work_day_quality"], 1e-9)
+			require.Zero(t, counts["meeting_number"], "never answered")
+		})
+	}
+}
+
+func TestComparisonText(t *testing.T) {
+	lang := i18n.Default
+	tcs := map[string]struct {
+		field  string
+		answer reflection.NumberPrefixedEnum
+		avg    float64
+		ex     string
+	}{
+		"more":              {field: "interrupted_amount", answer: "3-much", avg: 1.5, ex: i18n.T(lang, "compare.interrupted_amount.more")},
+		"less":              {field: "work_day_quality", answer: "1-bad", avg: 3, ex: i18n.T(lang, "compare.work_day_quality.less")},
+		"about usual":       {field: "work_day_quality", answer: "3-good", avg: 2.5},
+		"just under a step": {field: "work_day_quality", answer: "3-good", avg: 2.01},
+		"exactly a step":    {field: "work_day_quality", answer: "3-good", avg: 2, ex: i18n.T(lang, "compare.work_day_quality.more")},
+		"unordered":         {field: "work_day_feeling", answer: "6-happy", avg: 1},
+		"unanswered":        {field: "work_day_quality", answer: "", avg: 3},
+	}
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, tc.ex, comparisonText(lang, question(tc.field), tc.answer, tc.avg))
+		})
+	}
+}
+
+func TestConfirmationComparesOnlyWithEnoughHistory(t *testing.T) {
+	signer, err := urlsigner.New(urlsigner.Key{ID: "test", Secret: []byte("test-url-signing-key")})
+	require.NoError(t, err)
+	a := &App{reporter: report.New("http://localhost/report/", signer, nil, "", "", metrics.New())}
+	su := slackUser{Lang: i18n.Default, Location: time.UTC}
+
+	r := reflectedAt(t, "2021-07-07 17:00")
+	r.WorkDayQuality = "4-awesome"
+	var history []reflection.Reflection
+	for i := 1; i <= comparisonMinSamples; i++ {
+		history = append(history, reflection.Reflection{Date: r.Date.AddDate(0, 0, -i), WorkDayQuality: "1-bad"})
+	}
+	// as many reflections, but one of them didn't answer
+	unanswered := append([]reflection.Reflection{{Date: r.Date.AddDate(0, 0, -1)}}, history[1:]...)
+	better := i18n.T(su.Lang, "compare.work_day_quality.more")
+
+	tcs := map[string]struct {
+		history  []reflection.Reflection
+		compared bool
+	}{
+		"no prior data": {},
+		"too few":       {history: history[1:]},
+		"enough":        {history: history, compared: true},
+		"null answers":  {history: unanswered},
+	}
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			var text []string
+			for _, b := range a.reflectionConfirmationBlocks(r, tc.history, su, "saved") {
+				if s, ok := b.(*slack.SectionBlock); ok && s.Text != nil {
+					text = append(text, s.Text.Text)
+				}
+			}
+			require.Equal(t, tc.compared, strings.Contains(strings.Join(text, "\n"), better))
+		})
+	}
+}
@@ -0,0 +1,363 @@
+// Package dashboard serves an HTML page of a user's reflections, with
+// interactive charts, behind a signed URL.
+package dashboard
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	_ "embed"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jharlap/good-day-app/i18n"
+	"github.com/jharlap/good-day-app/metrics"
+	"github.com/jharlap/good-day-app/reflection"
+	"github.com/jharlap/good-day-app/report"
+	"github.com/jharlap/good-day-app/tracing"
+	"github.com/jharlap/good-day-app/urlsigner"
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog/log"
+)
+
+// Resource is the kind of resource dashboard URLs are signed for.
+const Resource = "dashboard"
+
+const (
+	// DefaultRangeDays is how many days the dashboard shows until another
+	// range is chosen.
+	DefaultRangeDays = 30
+	// MaxRangeDays bounds the range that can be chosen, to keep the page a
+	// reasonable size.
+	MaxRangeDays = 366
+
+	// echartsURL is a pinned release of ECharts, which draws the charts in
+	// the browser.
+	echartsURL = "https://cdn.jsdelivr.net/npm/echarts@5.2.2/dist/echarts.min.js"
+)
+
+//go:embed page.html
+var pageHTML string
+
+type Dashboard struct {
+	baseURL string
+	signer  *urlsigner.Engine
+	db      *sqlx.DB
+	metrics *metrics.Metrics
+	page    *template.Template
+}
+
+func New(baseURL string, signer *urlsigner.Engine, db *sqlx.DB, m *metrics.Metrics) *Dashboard {
+	return &Dashboard{
+		baseURL: baseURL,
+		signer:  signer,
+		db:      db,
+		metrics: m,
+		page:    template.Must(template.New("page").Parse(pageHTML)),
+	}
+}
+
+// URLForTeamAndUser returns a signed URL for the user's dashboard in lang, in
+// the generation of the user's links.
+func (d *Dashboard) URLForTeamAndUser(teamID, userID string, tz int, generation uint64, lang string) string {
+	sig := d.signer.Sign(urlsigner.Params{
+		Resource:       Resource,
+		TeamID:         teamID,
+		UserID:         userID,
+		TZ:             tz,
+		Options:        map[string]string{"lang": lang},
+		Generation:     generation,
+		ExpiryDuration: time.Hour * 24 * 30,
+	})
+	return fmt.Sprintf("%s/%s", d.baseURL, sig)
+}
+
+func (d *Dashboard) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var rp urlsigner.Params
+	{
+		i := strings.LastIndex(r.URL.Path, "/")
+		if i < 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		p, err := d.signer.ParseContext(r.Context(), r.URL.Path[i+1:], Resource)
+		if errors.Is(err, urlsigner.ErrInvalidSignature) {
+			w.WriteHeader(http.StatusUnauthorized)
+			log.Debug().Err(err).Str("path", r.URL.Path).Msg("invalid url signing")
+			return
+		} else if errors.Is(err, urlsigner.ErrGenerationUnavailable) {
+			w.WriteHeader(http.StatusInternalServerError)
+			log.Error().Err(err).Msg("error checking dashboard url is current")
+			return
+		} else if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			log.Debug().Err(err).Str("path", r.URL.Path).Msg("invalid url signing")
+			return
+		}
+		rp = p
+	}
+
+	lang := i18n.FromLocale(rp.Options["lang"])
+	from, to, err := parseRange(r.URL.Query(), time.Now(), rp.TZ)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		log.Debug().Err(err).Str("query", r.URL.RawQuery).Msg("invalid dashboard range")
+		return
+	}
+
+	nonce, err := newNonce()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		log.Error().Err(err).Msg("error creating script nonce")
+		return
+	}
+
+	buf := new(bytes.Buffer)
+	err = d.Render(r.Context(), buf, rp.TeamID, rp.UserID, rp.TZ, lang, from, to, nonce)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		log.Error().Err(err).Str("tid", rp.TeamID).Str("uid", rp.UserID).Msg("error rendering dashboard")
+		return
+	}
+
+	h := w.Header()
+	h.Set("Content-Type", "text/html; charset=utf-8")
+	h.Set("Cache-Control", "no-store")
+	// the URL is the credential, so it mustn't leak to the CDN in a referrer
+	h.Set("Referrer-Policy", "no-referrer")
+	h.Set("X-Content-Type-Options", "nosniff")
+	// ECharts styles its tooltips inline
+	h.Set("Content-Security-Policy", fmt.Sprintf("default-src 'none'; script-src 'nonce-%s' %s; style-src 'unsafe-inline'; img-src data:; form-action 'self'; base-uri 'none'; frame-ancestors 'none'", nonce, echartsURL))
+	_, err = w.Write(buf.Bytes())
+	if err != nil {
+		log.Error().Err(err).Msg("error writing dashboard")
+	}
+}
+
+// page is what the dashboard template is executed with.
+type page struct {
+	Lang       string
+	Nonce      string
+	EChartsURL string
+	From, To   string
+	MaxDate    string
+
+	Text      map[string]string
+	Charts    []chart
+	Questions []string
+	Rows      []row
+}
+
+type chart struct {
+	ID      string                 `json:"id"`
+	Options map[string]interface{} `json:"options"`
+}
+
+type row struct {
+	Date    string
+	Answers []string
+}
+
+// Render writes the dashboard page for the user's reflections from the first
+// to the last day, in the user's timezone.
+func (d *Dashboard) Render(ctx context.Context, w io.Writer, teamID, userID string, tz int, lang string, from, to time.Time, nonce string) (err error) {
+	ctx, span := tracing.Start(ctx, "dashboard.Render")
+	defer func(start time.Time) {
+		d.metrics.ObserveRender("dashboard", start, err)
+		tracing.End(span, err)
+	}(time.Now())
+
+	rr, err := d.reflections(ctx, teamID, userID, tz, from, to)
+	if err != nil {
+		return err
+	}
+
+	questions := reflection.LocalizedQuestions(lang)
+	p := page{
+		Lang:       lang,
+		Nonce:      nonce,
+		EChartsURL: echartsURL,
+		From:       from.Format(dateFormat),
+		To:         to.Format(dateFormat),
+		MaxDate:    today(time.Now(), tz).Format(dateFormat),
+		Text:       pageText(lang),
+	}
+
+	end := to.AddDate(0, 0, 1)
+	p.Charts = append(p.Charts, chart{ID: "report", Options: report.ChartOptions(rr, from, end, report.ThemeLight)})
+	for _, q := range questions {
+		if q.Options.Ordered {
+			p.Charts = append(p.Charts, chart{ID: "trend-" + q.Field, Options: trendOptions(q, rr, from, end)})
+		}
+	}
+
+	for _, q := range questions {
+		p.Questions = append(p.Questions, q.Text)
+	}
+	// newest first, like the home tab history
+	for i := len(rr) - 1; i >= 0; i-- {
+		row := row{Date: rr[i].Date.Format(dateFormat)}
+		for _, q := range questions {
+			row.Answers = append(row.Answers, q.Options.ValueFor(rr[i].ValueForQuestion(q.Field)))
+		}
+		p.Rows = append(p.Rows, row)
+	}
+
+	if err := d.page.Execute(w, p); err != nil {
+		return fmt.Errorf("error executing dashboard template: %w", err)
+	}
+	return nil
+}
+
+// reflections returns the user's reflections from the first to the last day,
+// oldest first, with dates in the user's timezone.
+func (d *Dashboard) reflections(ctx context.Context, teamID, userID string, tz int, from, to time.Time) ([]reflection.Reflection, error) {
+	start := from.Add(-1 * time.Duration(tz) * time.Hour)
+	end := to.AddDate(0, 0, 1).Add(-1 * time.Duration(tz) * time.Hour)
+
+	const query = "SELECT * FROM reflections WHERE `date` >= ? AND `date` < ? AND team_id = ? AND user_id = ? ORDER BY `date`"
+	queryStart := time.Now()
+	qctx, qspan := tracing.StartQuery(ctx, "dashboard_reflections", query)
+	rows, err := d.db.QueryxContext(qctx, query, start.Format(mysqlDatetimeFormat), end.Format(mysqlDatetimeFormat), teamID, userID)
+	d.metrics.ObserveQuery("dashboard_reflections", queryStart, err)
+	tracing.End(qspan, err)
+	if err != nil {
+		return nil, fmt.Errorf("error querying for reflections: %w", err)
+	}
+	defer rows.Close()
+
+	var rr []reflection.Reflection
+	for rows.Next() {
+		var r reflection.Reflection
+		err := rows.StructScan(&r)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", err)
+		}
+
+		// display dates in user timezone
+		r.Date = r.Date.UTC().Add(time.Duration(tz) * time.Hour)
+
+		rr = append(rr, r)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error getting reflections data: %w", err)
+	}
+	return rr, nil
+}
+
+// trendOptions returns the ECharts options for a chart of the answers to an
+// ordered question over time.
+func trendOptions(q reflection.Question, rr []reflection.Reflection, start, end time.Time) map[string]interface{} {
+	var labels []string
+	for _, o := range q.Options.Options {
+		labels = append(labels, o.Text)
+	}
+
+	data := [][]interface{}{}
+	for _, r := range rr {
+		v := reflection.NumberPrefixedEnum(r.ValueForQuestion(q.Field))
+		if n := v.IntVal(); n >= 0 {
+			data = append(data, []interface{}{r.Date.Format(dateFormat), n})
+		}
+	}
+
+	return map[string]interface{}{
+		"title": map[string]interface{}{
+			"text": q.Text,
+			"textStyle": map[string]interface{}{
+				"fontSize": 14,
+			},
+		},
+		"tooltip": map[string]string{
+			"trigger": "axis",
+		},
+		"grid": map[string]interface{}{
+			"left":         "3%",
+			"right":        "3%",
+			"containLabel": true,
+		},
+		"xAxis": map[string]string{
+			"type": "time",
+			"min":  start.Format(dateFormat),
+			"max":  end.Format(dateFormat),
+		},
+		"yAxis": map[string]interface{}{
+			"type": "category",
+			"data": labels,
+		},
+		"series": []map[string]interface{}{
+			{
+				"name":       q.Text,
+				"type":       "line",
+				"data":       data,
+				"symbolSize": 8,
+				"color":      "#2ec4b6",
+			},
+		},
+	}
+}
+
+// parseRange returns the first and last days to show, from the page's from
+// and to query parameters, or the DefaultRangeDays up to today in the
+// user's timezone. Days are dates at midnight UTC.
+func parseRange(q url.Values, now time.Time, tz int) (from, to time.Time, err error) {
+	to = today(now, tz)
+	if v := q.Get("to"); len(v) > 0 {
+		to, err = time.Parse(dateFormat, v)
+		if err != nil {
+			return from, to, fmt.Errorf("error parsing to date: %w", err)
+		}
+	}
+
+	from = to.AddDate(0, 0, 1-DefaultRangeDays)
+	if v := q.Get("from"); len(v) > 0 {
+		from, err = time.Parse(dateFormat, v)
+		if err != nil {
+			return from, to, fmt.Errorf("error parsing from date: %w", err)
+		}
+	}
+
+	if to.Before(from) {
+		return from, to, fmt.Errorf("range from %s to %s is backwards", from.Format(dateFormat), to.Format(dateFormat))
+	}
+	if to.Sub(from) >= MaxRangeDays*24*time.Hour {
+		return from, to, fmt.Errorf("range from %s to %s is more than %d days", from.Format(dateFormat), to.Format(dateFormat), MaxRangeDays)
+	}
+	return from, to, nil
+}
+
+// today returns the date in the user's timezone, at midnight UTC.
+func today(now time.Time, tz int) time.Time {
+	t := now.UTC().Add(time.Duration(tz) * time.Hour)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// pageText is the page's text in lang, by the last part of its key.
+func pageText(lang string) map[string]string {
+	m := make(map[string]string)
+	for _, k := range []string{"title", "from", "to", "show", "report", "trends", "history", "date", "empty"} {
+		m[k] = i18n.T(lang, "dashboard."+k)
+	}
+	return m
+}
+
+func newNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+const (
+	dateFormat          = "2006-01-02"
+	mysqlDatetimeFormat = "2006-01-02 15:04:05"
+)
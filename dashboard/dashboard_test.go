@@ -0,0 +1,66 @@
+package dashboard
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/jharlap/good-day-app/reflection"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRange(t *testing.T) {
+	now, err := time.Parse("2006-01-02 15:04:05", "2021-07-02 22:25:01")
+	require.NoError(t, err)
+	day := func(s string) time.Time {
+		d, err := time.Parse(dateFormat, s)
+		require.NoError(t, err)
+		return d
+	}
+
+	tcs := map[string]struct {
+		q        url.Values
+		tz       int
+		from, to time.Time
+		err      bool
+	}{
+		"default":            {q: url.Values{}, from: day("2021-06-03"), to: day("2021-07-02")},
+		"default ahead":      {q: url.Values{}, tz: 3, from: day("2021-06-04"), to: day("2021-07-03")},
+		"explicit":           {q: url.Values{"from": {"2021-01-01"}, "to": {"2021-01-31"}}, from: day("2021-01-01"), to: day("2021-01-31")},
+		"only to":            {q: url.Values{"to": {"2021-01-31"}}, from: day("2021-01-02"), to: day("2021-01-31")},
+		"one day":            {q: url.Values{"from": {"2021-01-31"}, "to": {"2021-01-31"}}, from: day("2021-01-31"), to: day("2021-01-31")},
+		"longest":            {q: url.Values{"from": {"2021-01-01"}, "to": {"2022-01-01"}}, from: day("2021-01-01"), to: day("2022-01-01")},
+		"backwards":          {q: url.Values{"from": {"2021-02-01"}, "to": {"2021-01-31"}}, err: true},
+		"too long":           {q: url.Values{"from": {"2021-01-01"}, "to": {"2022-01-02"}}, err: true},
+		"bad from":           {q: url.Values{"from": {"yesterday"}}, err: true},
+		"bad to":             {q: url.Values{"to": {"2021-13-01"}}, err: true},
+		"datetime not dates": {q: url.Values{"from": {"2021-01-01 00:00:00"}}, err: true},
+	}
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			from, to, err := parseRange(tc.q, now, tc.tz)
+			if tc.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.from, from, "from mismatch")
+			require.Equal(t, tc.to, to, "to mismatch")
+		})
+	}
+}
+
+func TestTrendOptions(t *testing.T) {
+	q := reflection.Questions[0]
+	start := time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC)
+	rr := []reflection.Reflection{
+		{Date: start, WorkDayQuality: "3-good"},
+		{Date: start.AddDate(0, 0, 1)},
+		{Date: start.AddDate(0, 0, 2), WorkDayQuality: "0-terrible"},
+	}
+
+	o := trendOptions(q, rr, start, start.AddDate(0, 0, 7))
+	require.Equal(t, []string{"Terrible", "Bad", "OK", "Good", "Awesome"}, o["yAxis"].(map[string]interface{})["data"], "the axis should have the options in order")
+	series := o["series"].([]map[string]interface{})
+	require.Equal(t, [][]interface{}{{"2021-07-01", 3}, {"2021-07-03", 0}}, series[0]["data"], "unanswered days should be left out")
+}
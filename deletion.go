@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/jharlap/good-day-app/i18n"
+	"github.com/jharlap/good-day-app/store"
+	"github.com/jharlap/good-day-app/webhook"
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog/log"
+	"github.com/slack-go/slack"
+)
+
+const (
+	deletionScopeDay   = store.DeletionScopeDay
+	deletionScopeRange = store.DeletionScopeRange
+	deletionScopeAll   = store.DeletionScopeAll
+	// deletionScopeReflection is a single reflection deleted from the home tab history.
+	deletionScopeReflection = store.DeletionScopeReflection
+)
+
+func generateDeleteDataModal(lang string) slack.ModalViewRequest {
+	var opts []*slack.OptionBlockObject
+	for _, scope := range []string{deletionScopeDay, deletionScopeRange, deletionScopeAll} {
+		opts = append(opts, slack.NewOptionBlockObject(scope, slack.NewTextBlockObject(slack.PlainTextType, i18n.T(lang, "delete.scope."+scope), false, false), nil))
+	}
+
+	from := slack.NewInputBlock("delete_from", slack.NewTextBlockObject(slack.PlainTextType, i18n.T(lang, "delete.from"), false, false), slack.NewDatePickerBlockElement("select"))
+	from.Optional = true
+	to := slack.NewInputBlock("delete_to", slack.NewTextBlockObject(slack.PlainTextType, i18n.T(lang, "delete.to"), false, false), slack.NewDatePickerBlockElement("select"))
+	to.Optional = true
+
+	var modalRequest slack.ModalViewRequest
+	modalRequest.Type = slack.ViewType("modal")
+	modalRequest.Title = slack.NewTextBlockObject(slack.PlainTextType, i18n.T(lang, "delete.title"), false, false)
+	modalRequest.Close = slack.NewTextBlockObject(slack.PlainTextType, i18n.T(lang, "button.cancel"), false, false)
+	modalRequest.Submit = slack.NewTextBlockObject(slack.PlainTextType, i18n.T(lang, "delete.submit"), false, false)
+	modalRequest.Blocks = slack.Blocks{
+		BlockSet: []slack.Block{
+			markdownSection(i18n.T(lang, "delete.warning")),
+			slack.NewInputBlock("delete_scope", slack.NewTextBlockObject(slack.PlainTextType, i18n.T(lang, "delete.scope"), false, false), slack.NewRadioButtonsBlockElement("select", opts...)),
+			from,
+			to,
+		},
+	}
+	modalRequest.CallbackID = deleteDataModalCallbackID
+	return modalRequest
+}
+
+func (a *App) startDeleteDataDialog(ctx context.Context, triggerID, lang string) error {
+	v := generateDeleteDataModal(lang)
+	_, err := a.slack.OpenViewContext(ctx, triggerID, v)
+	if err != nil {
+		return fmt.Errorf("error opening delete data modal: %w", err)
+	}
+	return nil
+}
+
+// handleDeleteDataModalCallback validates the delete data modal, responding
+// with errors for Slack to show in the modal, or deletes the chosen data and
+// confirms by DM.
+func (a *App) handleDeleteDataModalCallback(ctx context.Context, w http.ResponseWriter, ic slack.InteractionCallback) {
+	tid, uid := ic.Team.ID, ic.User.ID
+	su := a.lookupUser(ctx, tid, uid)
+
+	values := ic.View.State.Values
+	scope := values["delete_scope"]["select"].SelectedOption.Value
+	fromDate := values["delete_from"]["select"].SelectedDate
+	toDate := values["delete_to"]["select"].SelectedDate
+
+	errs := make(map[string]string)
+	switch scope {
+	case deletionScopeDay, deletionScopeRange, deletionScopeAll:
+	default:
+		errs["delete_scope"] = i18n.T(su.Lang, "delete.error.scope")
+	}
+	if (scope == deletionScopeDay || scope == deletionScopeRange) && len(fromDate) == 0 {
+		errs["delete_from"] = i18n.T(su.Lang, "delete.error.from_required")
+	}
+	if scope == deletionScopeRange && len(toDate) == 0 {
+		errs["delete_to"] = i18n.T(su.Lang, "delete.error.to_required")
+	}
+
+	var start, end time.Time
+	if len(errs) == 0 && scope != deletionScopeAll {
+		var err error
+		start, err = time.ParseInLocation(dateFormat, fromDate, su.Location)
+		if err != nil {
+			errs["delete_from"] = i18n.T(su.Lang, "delete.error.from_required")
+		}
+		end = start.AddDate(0, 0, 1)
+		if scope == deletionScopeRange {
+			last, err := time.ParseInLocation(dateFormat, toDate, su.Location)
+			if err != nil {
+				errs["delete_to"] = i18n.T(su.Lang, "delete.error.to_required")
+			} else if last.Before(start) {
+				errs["delete_to"] = i18n.T(su.Lang, "delete.error.range_order")
+			}
+			end = last.AddDate(0, 0, 1)
+		}
+	}
+
+	if len(errs) > 0 {
+		b, err := json.Marshal(slack.NewErrorsViewSubmissionResponse(errs))
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(b)
+		return
+	}
+
+	n, err := a.deleteUserData(ctx, tid, uid, scope, start, end)
+	if err != nil {
+		a.reportErrorToUser(ctx, err, tid, uid, i18n.T(su.Lang, "delete.failed"))
+		return
+	}
+
+	switch scope {
+	case deletionScopeAll:
+		a.messageUser(ctx, tid, uid, i18n.T(su.Lang, "delete.done.all", n))
+	case deletionScopeRange:
+		a.messageUser(ctx, tid, uid, i18n.T(su.Lang, "delete.done.range", fromDate, toDate, n))
+	default:
+		a.messageUser(ctx, tid, uid, i18n.T(su.Lang, "delete.done.day", fromDate, n))
+	}
+	a.refreshHomeView(ctx, tid, uid)
+}
+
+// deleteUserData deletes the user's reflections dated in [start, end), or all
+// their reflections and settings for deletionScopeAll, and records the
+// deletion in the audit log. It returns the number of reflections deleted.
+//
+// Deleting everything also deletes the user's shares, access tokens and
+// webhooks, and every webhook delivery of their reflections, and revokes
+// their chart links, so their settings are reset rather than deleted to keep
+// the new link generation. Team webhooks of others in the team still hear
+// about the deletion.
+func (a *App) deleteUserData(ctx context.Context, tid, uid, scope string, start, end time.Time) (int64, error) {
+	var also func(context.Context, *sqlx.Tx) error
+	if scope == deletionScopeAll {
+		also = deleteUserSettings(tid, uid)
+	}
+	n, err := a.reflections.Delete(ctx, tid, uid, scope, start, end, also)
+	if err != nil {
+		return 0, fmt.Errorf("error deleting user data: %w", err)
+	}
+
+	log.Info().Str("tid", tid).Str("uid", uid).Str("scope", scope).Int64("count", n).Msg("deleted user data")
+	a.metrics.ReflectionsDeleted(int(n))
+	if n > 0 {
+		a.notifyWebhooks(ctx, webhook.ReflectionsDeleted(tid, uid, start, end, n))
+	}
+	return n, nil
+}
+
+// deleteUserSettings deletes everything of the user's that goes with their
+// reflections when they delete all of them.
+func deleteUserSettings(tid, uid string) func(context.Context, *sqlx.Tx) error {
+	return func(ctx context.Context, tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(ctx, "INSERT INTO user_settings SET team_id=?, user_id=?, link_generation=1 ON DUPLICATE KEY UPDATE home_section=DEFAULT(home_section), language=NULL, link_generation=link_generation+1", tid, uid)
+		if err == nil {
+			_, err = tx.ExecContext(ctx, "DELETE FROM shares WHERE team_id = ? AND user_id = ?", tid, uid)
+		}
+		if err == nil {
+			_, err = tx.ExecContext(ctx, "DELETE FROM api_tokens WHERE team_id = ? AND user_id = ?", tid, uid)
+		}
+		if err == nil {
+			// deliveries of the user's reflections, including to others'
+			// team webhooks, since they hold the reflections too
+			_, err = tx.ExecContext(ctx, "DELETE FROM webhook_deliveries WHERE team_id = ? AND user_id = ?", tid, uid)
+		}
+		if err == nil {
+			_, err = tx.ExecContext(ctx, "DELETE d FROM webhook_deliveries d JOIN webhooks w ON w.id = d.webhook_id WHERE w.team_id = ? AND w.user_id = ?", tid, uid)
+		}
+		if err == nil {
+			_, err = tx.ExecContext(ctx, "DELETE FROM webhooks WHERE team_id = ? AND user_id = ?", tid, uid)
+		}
+		if err != nil {
+			return fmt.Errorf("error deleting user settings: %w", err)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jharlap/good-day-app/fakeslack"
+	"github.com/jharlap/good-day-app/i18n"
+	"github.com/jmoiron/sqlx"
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeleteUserSettings(t *testing.T) {
+	mdb, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer mdb.Close()
+	db := sqlx.NewDb(mdb, "mysql")
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO user_settings").WithArgs(testTeamID, testUserID).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM shares WHERE team_id = \\? AND user_id = \\?").WithArgs(testTeamID, testUserID).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM api_tokens WHERE team_id = \\? AND user_id = \\?").WithArgs(testTeamID, testUserID).WillReturnResult(sqlmock.NewResult(0, 1))
+	// pending deliveries of the user's reflections to a teammate's team webhook
+	mock.ExpectExec("DELETE FROM webhook_deliveries WHERE team_id = \\? AND user_id = \\?").WithArgs(testTeamID, testUserID).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("DELETE d FROM webhook_deliveries d JOIN webhooks w ON w.id = d.webhook_id WHERE w.team_id = \\? AND w.user_id = \\?").WithArgs(testTeamID, testUserID).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM webhooks WHERE team_id = \\? AND user_id = \\?").WithArgs(testTeamID, testUserID).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	ctx := context.Background()
+	tx, err := db.BeginTxx(ctx, nil)
+	require.NoError(t, err)
+	require.NoError(t, deleteUserSettings(testTeamID, testUserID)(ctx, tx))
+	require.NoError(t, tx.Commit())
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+// sameInstant matches a time argument at the same instant, in any location.
+type sameInstant time.Time
+
+func (t sameInstant) Match(v driver.Value) bool {
+	got, ok := v.(time.Time)
+	return ok && got.Equal(time.Time(t))
+}
+
+func TestDeleteDataModalCallback(t *testing.T) {
+	// the test user is in Toronto, 4 hours behind UTC in July
+	loc, err := time.LoadLocation("America/Toronto")
+	require.NoError(t, err, "programmer error: unknown test timezone")
+	day := func(d int) time.Time { return time.Date(2021, 7, d, 0, 0, 0, 0, loc) }
+
+	tcs := map[string]struct {
+		scope, from, to string
+		start, end      time.Time
+		errs            map[string]string
+		done            string
+	}{
+		"day":              {scope: deletionScopeDay, from: "2021-07-02", start: day(2), end: day(3), done: i18n.T(i18n.English, "delete.done.day", "2021-07-02", 0)},
+		"range":            {scope: deletionScopeRange, from: "2021-07-01", to: "2021-07-05", start: day(1), end: day(6), done: i18n.T(i18n.English, "delete.done.range", "2021-07-01", "2021-07-05", 0)},
+		"one day range":    {scope: deletionScopeRange, from: "2021-07-05", to: "2021-07-05", start: day(5), end: day(6), done: i18n.T(i18n.English, "delete.done.range", "2021-07-05", "2021-07-05", 0)},
+		"no day":           {scope: deletionScopeDay, errs: map[string]string{"delete_from": i18n.T(i18n.English, "delete.error.from_required")}},
+		"invalid day":      {scope: deletionScopeDay, from: "07/02/2021", errs: map[string]string{"delete_from": i18n.T(i18n.English, "delete.error.from_required")}},
+		"no last day":      {scope: deletionScopeRange, from: "2021-07-01", errs: map[string]string{"delete_to": i18n.T(i18n.English, "delete.error.to_required")}},
+		"invalid last day": {scope: deletionScopeRange, from: "2021-07-01", to: "2021-13-01", errs: map[string]string{"delete_to": i18n.T(i18n.English, "delete.error.to_required")}},
+		"backwards range":  {scope: deletionScopeRange, from: "2021-07-05", to: "2021-07-01", errs: map[string]string{"delete_to": i18n.T(i18n.English, "delete.error.range_order")}},
+		"unknown scope":    {scope: "everything", from: "2021-07-01", errs: map[string]string{"delete_scope": i18n.T(i18n.English, "delete.error.scope")}},
+		"reflection scope": {scope: deletionScopeReflection, from: "2021-07-01", errs: map[string]string{"delete_scope": i18n.T(i18n.English, "delete.error.scope")}},
+	}
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			fs, mock, h := newTestApp(t)
+			allowUserSettings(mock, 1)
+			if tc.errs == nil {
+				mock.ExpectBegin()
+				mock.ExpectExec("DELETE FROM reflections WHERE team_id = \\? AND user_id = \\? AND date >= \\? AND date < \\?").
+					WithArgs(testTeamID, testUserID, sameInstant(tc.start), sameInstant(tc.end)).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("INSERT INTO deletion_audit").WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectCommit()
+			}
+
+			r, err := fakeslack.NewInteractionRequest(testSigningSecret, "/interactive", slack.InteractionCallback{
+				Type: slack.InteractionTypeViewSubmission,
+				Team: slack.Team{ID: testTeamID},
+				User: slack.User{ID: testUserID},
+				View: slack.View{CallbackID: deleteDataModalCallbackID, State: &slack.ViewState{Values: map[string]map[string]slack.BlockAction{
+					"delete_scope": {"select": {SelectedOption: slack.OptionBlockObject{Value: tc.scope}}},
+					"delete_from":  {"select": {SelectedDate: tc.from}},
+					"delete_to":    {"select": {SelectedDate: tc.to}},
+				}}},
+			})
+			require.NoError(t, err)
+			w := serve(h, r)
+			require.Equal(t, http.StatusOK, w.Code)
+
+			if tc.errs != nil {
+				var res slack.ViewSubmissionResponse
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+				require.Equal(t, slack.RAErrors, res.ResponseAction)
+				require.Equal(t, tc.errs, res.Errors)
+				require.Empty(t, fs.Calls("chat.postMessage"), "nothing should be deleted")
+			} else {
+				cc, err := fs.WaitForCalls("chat.postMessage", 1, testWait)
+				require.NoError(t, err)
+				require.Equal(t, tc.done, cc[0].Values.Get("text"))
+			}
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/jharlap/good-day-app/export"
+	"github.com/jharlap/good-day-app/heatmap"
+	"github.com/jharlap/good-day-app/i18n"
+	"github.com/jharlap/good-day-app/report"
+	"github.com/jharlap/good-day-app/slackupload"
+	"github.com/rs/zerolog/log"
+	"github.com/slack-go/slack"
+)
+
+// sendDataDownload DMs the user a file with all their reflections in the
+// given export format. The file is spooled to disk as it is written, since
+// Slack needs its size before it is uploaded, rather than held in memory.
+func (a *App) sendDataDownload(ctx context.Context, tid, uid, format string) {
+	log.Info().Str("tid", tid).Str("uid", uid).Str("format", format).Msg("sendDataDownload")
+	su := a.lookupUser(ctx, tid, uid)
+
+	if !validExportFormat(format) {
+		a.messageUser(ctx, tid, uid, i18n.T(su.Lang, "download.unknown_format", format))
+		return
+	}
+
+	n, err := a.countUserReflections(ctx, tid, uid)
+	if err != nil {
+		a.reportErrorToUser(ctx, err, tid, uid, i18n.T(su.Lang, "download.failed"))
+		return
+	}
+	if n == 0 {
+		a.messageUser(ctx, tid, uid, i18n.T(su.Lang, "download.empty"))
+		return
+	}
+
+	// files are shared in a DM by its channel ID rather than the user ID
+	ch, _, _, err := a.slack.OpenConversationContext(ctx, &slack.OpenConversationParameters{Users: []string{uid}})
+	if err != nil {
+		a.reportErrorToUser(ctx, fmt.Errorf("error opening dm: %w", err), tid, uid, i18n.T(su.Lang, "download.failed"))
+		return
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(a.writeExport(ctx, pw, tid, uid, format, su))
+	}()
+
+	fn := fmt.Sprintf("reflections_%s_%d.%s", uid, time.Now().Unix(), format)
+	_, err = a.uploader.Upload(ctx, slackupload.Params{
+		Filename:       fn,
+		Title:          fn,
+		Reader:         pr,
+		ChannelID:      ch.ID,
+		InitialComment: i18n.T(su.Lang, "download.explanation", su.Location),
+	})
+	// unblock the writer if the upload stopped reading early
+	pr.Close()
+	if err != nil {
+		a.reportErrorToUser(ctx, err, tid, uid, i18n.T(su.Lang, "download.failed"))
+		return
+	}
+	a.metrics.ExportSent(format)
+}
+
+func validExportFormat(format string) bool {
+	for _, f := range export.Formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// writeExport writes the user's reflections to w in the export format. A ZIP
+// archive bundles the data as CSV and JSON with the user's charts.
+func (a *App) writeExport(ctx context.Context, w io.Writer, tid, uid, format string, su slackUser) error {
+	if format != export.FormatZIP {
+		return a.writeReflections(ctx, w, tid, uid, format, su)
+	}
+
+	zw := zip.NewWriter(w)
+	for _, f := range []string{export.FormatCSV, export.FormatJSON} {
+		fw, err := zw.Create("reflections." + f)
+		if err != nil {
+			return fmt.Errorf("error adding %s to archive: %w", f, err)
+		}
+		if err := a.writeReflections(ctx, fw, tid, uid, f, su); err != nil {
+			return err
+		}
+	}
+
+	charts := []struct {
+		name   string
+		render func(context.Context, io.Writer, string, string, int) error
+	}{
+		{"heatmap.png", func(ctx context.Context, w io.Writer, tid, uid string, tz int) error {
+			return a.heatmapper.Render(ctx, w, tid, uid, tz, heatmap.DefaultOptions)
+		}},
+		{"report.png", func(ctx context.Context, w io.Writer, tid, uid string, tz int) error {
+			return a.reporter.Render(ctx, w, tid, uid, tz, report.DefaultOptions)
+		}},
+	}
+	for _, c := range charts {
+		// render to memory first, so a failed chart is left out rather than
+		// failing the whole archive
+		buf := new(bytes.Buffer)
+		if err := c.render(ctx, buf, tid, uid, su.TZOffset/3600); err != nil {
+			log.Error().Err(err).Str("tid", tid).Str("uid", uid).Str("chart", c.name).Msg("error rendering chart for export archive")
+			continue
+		}
+
+		fw, err := zw.Create(c.name)
+		if err != nil {
+			return fmt.Errorf("error adding %s to archive: %w", c.name, err)
+		}
+		if _, err := fw.Write(buf.Bytes()); err != nil {
+			return fmt.Errorf("error writing %s to archive: %w", c.name, err)
+		}
+	}
+
+	return zw.Close()
+}
+
+func (a *App) writeReflections(ctx context.Context, w io.Writer, tid, uid, format string, su slackUser) error {
+	ew, err := export.New(format, w, su.Lang, su.Location)
+	if errors.Is(err, export.ErrUnknownFormat) {
+		return fmt.Errorf("error exporting %s: %w", format, err)
+	} else if err != nil {
+		return err
+	}
+
+	_, err = a.forEachUserReflection(ctx, tid, uid, ew.Write)
+	if err != nil {
+		return err
+	}
+	return ew.Close()
+}
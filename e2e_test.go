@@ -0,0 +1,960 @@
+package main
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jharlap/good-day-app/api"
+	"github.com/jharlap/good-day-app/config"
+	"github.com/jharlap/good-day-app/dashboard"
+	"github.com/jharlap/good-day-app/fakeslack"
+	"github.com/jharlap/good-day-app/heatmap"
+	"github.com/jharlap/good-day-app/i18n"
+	"github.com/jharlap/good-day-app/insights"
+	"github.com/jharlap/good-day-app/reflection"
+	"github.com/jharlap/good-day-app/report"
+	"github.com/jharlap/good-day-app/store"
+	"github.com/jharlap/good-day-app/urlsigner"
+	"github.com/jharlap/good-day-app/webhook"
+	"github.com/jmoiron/sqlx"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+const (
+	testSigningSecret = "test-signing-secret"
+	testTeamID        = "T1"
+	testUserID        = "U1"
+	// testWait is how long to wait for the app's background Slack calls.
+	testWait = 5 * time.Second
+)
+
+// newTestApp points the app at a fake Slack and a mock database, returning
+// the app's router.
+func newTestApp(t *testing.T) (*fakeslack.Server, sqlmock.Sqlmock, http.Handler) {
+	fs := fakeslack.New()
+	t.Cleanup(fs.Close)
+	fs.AddUser(slack.User{ID: testUserID, Name: "ada", TZ: "America/Toronto", TZOffset: -4 * 3600, Locale: "en-US"})
+
+	mdb, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	require.NoError(t, err)
+	t.Cleanup(func() { mdb.Close() })
+	mock.MatchExpectationsInOrder(false)
+
+	render := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}))
+	t.Cleanup(render.Close)
+
+	a, err := newApp(config.Config{
+		SlackBotToken:      "xoxb-test",
+		SlackSigningSecret: testSigningSecret,
+		SlackAPIURL:        fs.APIURL(),
+		BaseURL:            "http://localhost",
+		URLSigningKeys:     []urlsigner.Key{{ID: "test", Secret: []byte("test-url-signing-key")}},
+		RenderURL:          render.URL,
+	}, sqlx.NewDb(mdb, "mysql"))
+	require.NoError(t, err)
+
+	return fs, mock, a.Handler()
+}
+
+// allowUserSettings lets the app look up the test user's settings up to n
+// times, finding the defaults.
+func allowUserSettings(mock sqlmock.Sqlmock, n int) {
+	for i := 0; i < n; i++ {
+		mock.ExpectQuery("FROM user_settings").WillReturnRows(sqlmock.NewRows([]string{"team_id", "user_id", "home_section", "language"}))
+	}
+}
+
+func serve(h http.Handler, r *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestUnsignedRequestsAreRejected(t *testing.T) {
+	_, _, h := newTestApp(t)
+
+	for _, path := range []string{"/event", "/interactive", "/slash"} {
+		t.Run(path, func(t *testing.T) {
+			r := fakeslack.NewSignedRequest("wrong secret", path, "application/x-www-form-urlencoded", []byte("command=%2Freflect"))
+			require.Equal(t, http.StatusUnauthorized, serve(h, r).Code)
+
+			r = httptest.NewRequest(http.MethodPost, path, strings.NewReader("command=%2Freflect"))
+			require.Equal(t, http.StatusBadRequest, serve(h, r).Code, "requests without a signature should be refused")
+		})
+	}
+}
+
+func TestEventURLVerification(t *testing.T) {
+	_, _, h := newTestApp(t)
+
+	r, err := fakeslack.NewEventRequest(testSigningSecret, "/event", testTeamID, slackevents.EventsAPIURLVerificationEvent{Type: "url_verification", Challenge: "abc123"})
+	require.NoError(t, err)
+	w := serve(h, r)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "abc123", w.Body.String())
+}
+
+func TestAppHomeOpenedPublishesHomeTab(t *testing.T) {
+	fs, mock, h := newTestApp(t)
+	allowUserSettings(mock, 1)
+
+	r, err := fakeslack.NewEventRequest(testSigningSecret, "/event", testTeamID, map[string]interface{}{
+		"type": "app_home_opened",
+		"user": testUserID,
+		"tab":  "home",
+		"view": map[string]interface{}{"team_id": testTeamID},
+	})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, serve(h, r).Code)
+
+	cc := fs.Calls("views.publish")
+	require.Len(t, cc, 1, "the home tab should be published before responding")
+	var req struct {
+		UserID string                   `json:"user_id"`
+		View   slack.HomeTabViewRequest `json:"view"`
+	}
+	require.NoError(t, json.Unmarshal(cc[0].Body, &req))
+	require.Equal(t, testUserID, req.UserID)
+	require.Equal(t, slack.VTHomeTab, req.View.Type)
+	require.Contains(t, string(cc[0].Body), "*ada*", "the home tab should greet the user")
+}
+
+func TestSlashReflectOpensModal(t *testing.T) {
+	fs, mock, h := newTestApp(t)
+	allowUserSettings(mock, 1)
+
+	r := fakeslack.NewSlashRequest(testSigningSecret, "/slash", slack.SlashCommand{Command: "/reflect", TeamID: testTeamID, UserID: testUserID, TriggerID: "trigger-1"})
+	w := serve(h, r)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Contains(t, w.Body.String(), "text")
+
+	cc, err := fs.WaitForCalls("views.open", 1, testWait)
+	require.NoError(t, err)
+	var req struct {
+		TriggerID string                 `json:"trigger_id"`
+		View      slack.ModalViewRequest `json:"view"`
+	}
+	require.NoError(t, json.Unmarshal(cc[0].Body, &req))
+	require.Equal(t, "trigger-1", req.TriggerID)
+	require.Equal(t, reflectionModalCallbackID, req.View.CallbackID)
+	require.Len(t, req.View.Blocks.BlockSet, 14, "the modal should have a header and every question")
+}
+
+func TestSlashUnknownCommand(t *testing.T) {
+	_, _, h := newTestApp(t)
+
+	r := fakeslack.NewSlashRequest(testSigningSecret, "/slash", slack.SlashCommand{Command: "/other", TeamID: testTeamID, UserID: testUserID})
+	require.Equal(t, http.StatusBadRequest, serve(h, r).Code)
+}
+
+func TestReflectionSubmissionIsSavedAndConfirmed(t *testing.T) {
+	fs, mock, h := newTestApp(t)
+	allowUserSettings(mock, 3)
+
+	// answer every question with its first option, except for a good, happy day
+	answers := map[string]string{"work_day_quality": "3-good", "work_day_feeling": "6-happy"}
+	values := make(map[string]map[string]slack.BlockAction)
+	args := []driver.Value{testTeamID, testUserID, sqlmock.AnyArg()}
+	for _, q := range reflection.Questions {
+		code, ok := answers[q.Field]
+		if !ok {
+			code = q.Options.Options[0].Code
+		}
+		values[q.Field] = map[string]slack.BlockAction{"select": {SelectedOption: slack.OptionBlockObject{Value: code}}}
+		args = append(args, code)
+	}
+	mock.ExpectExec("INSERT INTO reflections").WithArgs(args...).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("FROM reflections").WillReturnRows(sqlmock.NewRows([]string{"team_id", "user_id", "date"}))
+
+	r, err := fakeslack.NewInteractionRequest(testSigningSecret, "/interactive", slack.InteractionCallback{
+		Type: slack.InteractionTypeViewSubmission,
+		Team: slack.Team{ID: testTeamID},
+		User: slack.User{ID: testUserID},
+		View: slack.View{CallbackID: reflectionModalCallbackID, State: &slack.ViewState{Values: values}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, serve(h, r).Code)
+
+	cc, err := fs.WaitForCalls("chat.postMessage", 1, testWait)
+	require.NoError(t, err)
+	require.Equal(t, testUserID, cc[0].Values.Get("channel"))
+	require.Equal(t, i18n.T(i18n.English, "reflection.saved"), cc[0].Values.Get("text"))
+	require.Contains(t, cc[0].Values.Get("blocks"), "Good")
+
+	_, err = fs.WaitForCalls("views.publish", 1, testWait)
+	require.NoError(t, err, "the home tab should be refreshed")
+}
+
+func TestWebFormIsSavedAndConfirmed(t *testing.T) {
+	fs, mock, h := newTestApp(t)
+	signer, err := urlsigner.New(urlsigner.Key{ID: "test", Secret: []byte("test-url-signing-key")})
+	require.NoError(t, err)
+	path := "/reflect/" + signer.Sign(urlsigner.Params{Resource: webFormResource, TeamID: testTeamID, UserID: testUserID, ExpiryDuration: time.Hour})
+	// each request checks the link's generation and looks up the user
+	allowUserSettings(mock, 6)
+
+	w := serve(h, httptest.NewRequest(http.MethodGet, path, nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Contains(t, w.Body.String(), reflection.Questions[0].Text)
+	cookies := w.Result().Cookies()
+	require.Len(t, cookies, 1)
+	require.Equal(t, csrfCookie, cookies[0].Name)
+	require.Equal(t, http.SameSiteStrictMode, cookies[0].SameSite)
+	require.Contains(t, w.Body.String(), `name="csrf" value="`+cookies[0].Value+`"`)
+
+	form := url.Values{"csrf": {cookies[0].Value}}
+	args := []driver.Value{testTeamID, testUserID, sqlmock.AnyArg()}
+	for _, q := range reflection.Questions {
+		form.Set(q.Field, q.Options.Options[0].Code)
+		args = append(args, q.Options.Options[0].Code)
+	}
+	post := func(form url.Values, cookie bool) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if cookie {
+			r.AddCookie(cookies[0])
+		}
+		return serve(h, r)
+	}
+
+	w = post(form, false)
+	require.Equal(t, http.StatusForbidden, w.Code, "forms posted without the cookie should be refused")
+	require.Contains(t, w.Body.String(), `value="0-terrible" selected`, "the answers should be kept to post again")
+
+	invalid := url.Values{}
+	for k, v := range form {
+		invalid[k] = v
+	}
+	invalid.Set("work_day_quality", "9-perfect")
+	invalid.Del("meeting_number")
+	w = post(invalid, true)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Contains(t, w.Body.String(), template.HTMLEscapeString(i18n.T(i18n.English, "webform.invalid")))
+	require.Contains(t, w.Body.String(), template.HTMLEscapeString(i18n.T(i18n.English, "webform.required")))
+	require.NoError(t, mock.ExpectationsWereMet(), "nothing should be saved yet")
+
+	allowUserSettings(mock, 4)
+	mock.ExpectExec("INSERT INTO reflections").WithArgs(args...).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("FROM reflections").WillReturnRows(sqlmock.NewRows([]string{"team_id", "user_id", "date"}))
+	w = post(form, true)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Contains(t, w.Body.String(), template.HTMLEscapeString(i18n.T(i18n.English, "webform.saved")))
+	require.Less(t, w.Result().Cookies()[0].MaxAge, 0, "the token should be spent")
+
+	cc, err := fs.WaitForCalls("chat.postMessage", 1, testWait)
+	require.NoError(t, err)
+	require.Equal(t, i18n.T(i18n.English, "reflection.saved"), cc[0].Values.Get("text"), "web reflections should be confirmed like the modal's")
+	_, err = fs.WaitForCalls("views.publish", 1, testWait)
+	require.NoError(t, err, "the home tab should be refreshed")
+
+	w = serve(h, httptest.NewRequest(http.MethodGet, strings.Replace(path, "/reflect/", "/dashboard/", 1), nil))
+	require.Equal(t, http.StatusUnauthorized, w.Code, "form links shouldn't open the dashboard")
+}
+
+func TestTokenCommand(t *testing.T) {
+	_, mock, h := newTestApp(t)
+	allowUserSettings(mock, 2)
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM api_tokens").WithArgs(testTeamID, testUserID).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectExec("INSERT INTO api_tokens").WithArgs(testTeamID, testUserID, "My notebook", sqlmock.AnyArg(), api.ScopeRead+","+api.ScopeWrite).WillReturnResult(sqlmock.NewResult(1, 1))
+
+	r := fakeslack.NewSlashRequest(testSigningSecret, "/slash", slack.SlashCommand{Command: "/reflect", Text: "token WRITE My notebook", TeamID: testTeamID, UserID: testUserID})
+	w := serve(h, r)
+	require.Equal(t, http.StatusOK, w.Code)
+	var msg slack.Msg
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &msg))
+	require.Empty(t, msg.ResponseType, "the token should only be shown to the user")
+	require.Contains(t, msg.Text, "`gdt_")
+	require.Contains(t, msg.Text, "http://localhost/api/v1/openapi.json")
+
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM api_tokens").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(api.MaxActiveTokens))
+	w = serve(h, fakeslack.NewSlashRequest(testSigningSecret, "/slash", slack.SlashCommand{Command: "/reflect", Text: "token", TeamID: testTeamID, UserID: testUserID}))
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &msg))
+	require.Equal(t, i18n.T(i18n.English, "token.too_many", api.MaxActiveTokens), msg.Text)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStatsCommand(t *testing.T) {
+	_, mock, h := newTestApp(t)
+	slash := func(text string) string {
+		w := serve(h, fakeslack.NewSlashRequest(testSigningSecret, "/slash", slack.SlashCommand{Command: "/reflect", Text: text, TeamID: testTeamID, UserID: testUserID}))
+		require.Equal(t, http.StatusOK, w.Code)
+		var msg slack.Msg
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &msg))
+		require.Empty(t, msg.ResponseType, "stats should only be shown to the user")
+		return msg.Text
+	}
+
+	// better days always came with more progress
+	n := insights.MinCorrelationSamples + 2
+	rows := sqlmock.NewRows([]string{"team_id", "user_id", "date", "work_day_quality", "progress_goals_amount"})
+	for i := n; i > 0; i-- {
+		rows.AddRow(testTeamID, testUserID, time.Now().AddDate(0, 0, -i), reflection.QualityOptions.Options[i%5].Code, reflection.AmountOfDayOptions.Options[i%5].Code)
+	}
+	allowUserSettings(mock, 1)
+	mock.ExpectQuery("FROM reflections WHERE team_id = \\? AND user_id = \\? AND date >= \\?").WithArgs(testTeamID, testUserID, sqlmock.AnyArg()).WillReturnRows(rows)
+	text := slash("stats")
+	require.Contains(t, text, i18n.T(i18n.English, "insights.count", n, defaultStatsDays))
+	require.Contains(t, text, i18n.T(i18n.English, "insights.weekdays"))
+	require.Contains(t, text, reflection.LocalizedQuestions(i18n.English)[4].Text+": "+i18n.T(i18n.English, "insights.correlation.more", 1.0))
+
+	allowUserSettings(mock, 1)
+	mock.ExpectQuery("FROM reflections").WillReturnRows(sqlmock.NewRows([]string{"team_id", "user_id", "date"}))
+	require.Equal(t, i18n.T(i18n.English, "insights.empty", 7), slash("stats 7"))
+
+	allowUserSettings(mock, 1)
+	require.Equal(t, i18n.T(i18n.English, "stats.usage", streakHistoryDays), slash("stats forever"))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestParseStatsCommand(t *testing.T) {
+	tcs := map[string]struct {
+		days int
+		ok   bool
+	}{
+		"stats":       {days: defaultStatsDays, ok: true},
+		"stats 7":     {days: 7, ok: true},
+		"stats 366":   {days: streakHistoryDays, ok: true},
+		"stats 0":     {},
+		"stats 367":   {},
+		"stats -1":    {},
+		"stats week":  {},
+		"stats 7 now": {},
+	}
+	for text, tc := range tcs {
+		t.Run(text, func(t *testing.T) {
+			days, ok := parseStatsCommand(text)
+			require.Equal(t, tc.ok, ok)
+			require.Equal(t, tc.days, days)
+		})
+	}
+}
+
+func TestParseTokenCommand(t *testing.T) {
+	tcs := map[string]struct {
+		scopes []string
+		name   string
+	}{
+		"token":                  {scopes: []string{api.ScopeRead}, name: i18n.T(i18n.English, "token.default_name")},
+		"token read":             {scopes: []string{api.ScopeRead}, name: i18n.T(i18n.English, "token.default_name")},
+		"token write  Jupyter":   {scopes: []string{api.ScopeRead, api.ScopeWrite}, name: "Jupyter"},
+		"token my read notebook": {scopes: []string{api.ScopeRead}, name: "my read notebook"},
+	}
+	for text, tc := range tcs {
+		t.Run(text, func(t *testing.T) {
+			scopes, name := parseTokenCommand(text, i18n.English)
+			require.Equal(t, tc.scopes, scopes)
+			require.Equal(t, tc.name, name)
+		})
+	}
+}
+
+func TestWebhookCommand(t *testing.T) {
+	fs, mock, h := newTestApp(t)
+	slash := func(text string) slack.Msg {
+		w := serve(h, fakeslack.NewSlashRequest(testSigningSecret, "/slash", slack.SlashCommand{Command: "/reflect", Text: text, TeamID: testTeamID, UserID: testUserID}))
+		require.Equal(t, http.StatusOK, w.Code)
+		var msg slack.Msg
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &msg))
+		return msg
+	}
+
+	allowUserSettings(mock, 2)
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM webhooks").WithArgs(testTeamID, testUserID).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectExec("INSERT INTO webhooks").WithArgs(testTeamID, testUserID, webhook.ScopeUser, "https://example.com/hook", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+	msg := slash("webhook add <https://example.com/hook>")
+	require.Empty(t, msg.ResponseType, "the secret should only be shown to the user")
+	require.Contains(t, msg.Text, "`whsec_")
+	require.Contains(t, msg.Text, webhook.SignatureHeader)
+	_, err := fs.WaitForCalls("views.publish", 1, testWait)
+	require.NoError(t, err, "the home tab should be refreshed")
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	allowUserSettings(mock, 2)
+	require.Equal(t, i18n.T(i18n.English, "webhook.team_admin_only"), slash("webhook add https://example.com/hook team").Text)
+
+	allowUserSettings(mock, 1)
+	require.Equal(t, i18n.T(i18n.English, "webhook.invalid_url"), slash("webhook add http://example.com/hook").Text)
+
+	allowUserSettings(mock, 1)
+	require.Equal(t, i18n.T(i18n.English, "webhook.usage"), slash("webhook").Text)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestParseWebhookCommand(t *testing.T) {
+	tcs := map[string]struct {
+		url, scope string
+		ok         bool
+	}{
+		"webhook add https://example.com/hook":                         {url: "https://example.com/hook", scope: webhook.ScopeUser, ok: true},
+		"webhook ADD <https://example.com/hook|example.com/hook> Team": {url: "https://example.com/hook", scope: webhook.ScopeTeam, ok: true},
+		"webhook add <https://example.com/hook>":                       {url: "https://example.com/hook", scope: webhook.ScopeUser, ok: true},
+		"webhook":                                                      {},
+		"webhook add":                                                  {},
+		"webhook remove https://example.com/hook":                      {},
+		"webhook add https://example.com/hook everyone":                {},
+		"webhook add https://example.com/hook team now":                {},
+	}
+	for text, tc := range tcs {
+		t.Run(text, func(t *testing.T) {
+			u, scope, ok := parseWebhookCommand(text)
+			require.Equal(t, tc.ok, ok)
+			require.Equal(t, tc.url, u)
+			require.Equal(t, tc.scope, scope)
+		})
+	}
+}
+
+func TestExportUploadsFile(t *testing.T) {
+	fs, mock, h := newTestApp(t)
+	allowUserSettings(mock, 2)
+	mock.ExpectQuery("SELECT COUNT").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery("FROM reflections WHERE team_id = \\? AND user_id = \\? ORDER BY date").
+		WillReturnRows(sqlmock.NewRows(strings.Split(store.Columns, ", ")).
+			AddRow(testTeamID, testUserID, time.Date(2021, 7, 2, 21, 0, 0, 0, time.UTC), "3-good", "2-some", nil, nil, nil, nil, nil, "6-happy", nil, nil, "1-one", nil, nil, time.Now()))
+
+	r := fakeslack.NewSlashRequest(testSigningSecret, "/slash", slack.SlashCommand{Command: "/reflect", Text: "export csv", TeamID: testTeamID, UserID: testUserID})
+	require.Equal(t, http.StatusOK, serve(h, r).Code)
+
+	_, err := fs.WaitForCalls("files.completeUploadExternal", 1, testWait)
+	require.NoError(t, err)
+	ff := fs.Files()
+	require.Len(t, ff, 1)
+	require.Equal(t, "D"+testUserID, ff[0].ChannelID, "the file should be shared in the user's DM")
+	require.True(t, strings.HasSuffix(ff[0].Name, ".csv"))
+
+	lines := strings.Split(strings.TrimSpace(string(ff[0].Content)), "\n")
+	require.Len(t, lines, 2)
+	require.True(t, strings.HasPrefix(lines[1], "2021-07-02,17:00,America/Toronto,Good,3,Some of the day,2"), "dates should be in the user's timezone: %s", lines[1])
+}
+
+func TestUnknownPathsAreNotHandledAsSlack(t *testing.T) {
+	_, _, h := newTestApp(t)
+
+	w := serve(h, httptest.NewRequest(http.MethodGet, "/heatmap/not-a-signature", nil))
+	require.NotEqual(t, http.StatusOK, w.Code)
+	b, _ := ioutil.ReadAll(w.Body)
+	require.NotContains(t, string(b), "PNG")
+}
+
+func TestAppsAreIndependent(t *testing.T) {
+	fs1, mock1, h1 := newTestApp(t)
+	fs2, _, h2 := newTestApp(t)
+	allowUserSettings(mock1, 1)
+
+	r := fakeslack.NewSlashRequest(testSigningSecret, "/slash", slack.SlashCommand{Command: "/reflect", TeamID: testTeamID, UserID: testUserID, TriggerID: "trigger-1"})
+	require.Equal(t, http.StatusOK, serve(h1, r).Code)
+	_, err := fs1.WaitForCalls("views.open", 1, testWait)
+	require.NoError(t, err)
+
+	require.Equal(t, http.StatusUnauthorized, serve(h2, fakeslack.NewSignedRequest("other secret", "/slash", "application/x-www-form-urlencoded", nil)).Code)
+	require.Empty(t, fs2.Calls("views.open"), "each app should call its own Slack")
+}
+
+func TestHealthz(t *testing.T) {
+	_, _, h := newTestApp(t)
+
+	w := serve(h, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestReadyz(t *testing.T) {
+	tcs := []struct {
+		name   string
+		dbErr  error
+		code   int
+		status string
+	}{
+		{"ready", nil, http.StatusOK, "ok"},
+		{"database down", errors.New("connection refused"), http.StatusServiceUnavailable, "unavailable"},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			fs, mock, h := newTestApp(t)
+			mock.ExpectPing().WillReturnError(tc.dbErr)
+
+			w := serve(h, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			require.Equal(t, tc.code, w.Code)
+
+			var res struct {
+				Status string            `json:"status"`
+				Checks map[string]string `json:"checks"`
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+			require.Equal(t, tc.status, res.Status)
+			require.Equal(t, "ok", res.Checks["slack"])
+			require.Equal(t, "ok", res.Checks["render"])
+			if tc.dbErr != nil {
+				require.Contains(t, res.Checks["database"], tc.dbErr.Error())
+			} else {
+				require.Equal(t, "ok", res.Checks["database"])
+			}
+			require.Len(t, fs.Calls("auth.test"), 1)
+		})
+	}
+}
+
+func TestVersion(t *testing.T) {
+	_, _, h := newTestApp(t)
+
+	w := serve(h, httptest.NewRequest(http.MethodGet, "/version", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var v map[string]string
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &v))
+	require.Equal(t, version, v["version"])
+	require.NotEmpty(t, v["go"])
+}
+
+func TestUnknownPathsAreNotFound(t *testing.T) {
+	_, _, h := newTestApp(t)
+
+	for _, path := range []string{"/", "/wp-login.php", "/eventz"} {
+		w := serve(h, httptest.NewRequest(http.MethodPost, path, strings.NewReader("secret=1")))
+		require.Equal(t, http.StatusNotFound, w.Code, path)
+	}
+}
+
+func TestSignedURLsAreScopedToTheirResource(t *testing.T) {
+	_, mock, h := newTestApp(t)
+	signer, err := urlsigner.New(urlsigner.Key{ID: "test", Secret: []byte("test-url-signing-key")})
+	require.NoError(t, err)
+	sign := func(resource string, opts map[string]string) string {
+		return signer.Sign(urlsigner.Params{Resource: resource, TeamID: testTeamID, UserID: testUserID, Options: opts, ExpiryDuration: time.Hour})
+	}
+
+	tcs := map[string]struct {
+		path string
+		code int
+	}{
+		"heatmap link for report":  {path: "/report/" + sign(heatmap.Resource, nil), code: http.StatusUnauthorized},
+		"report link for heatmap":  {path: "/heatmap/" + sign(report.Resource, nil), code: http.StatusUnauthorized},
+		"unscoped link":            {path: "/report/" + sign("", nil), code: http.StatusUnauthorized},
+		"unknown heatmap option":   {path: "/heatmap/" + sign(heatmap.Resource, map[string]string{"colour": "red"}), code: http.StatusBadRequest},
+		"unordered heatmap metric": {path: "/heatmap/" + sign(heatmap.Resource, map[string]string{"metric": "work_day_feeling"}), code: http.StatusBadRequest},
+		"report range too long":    {path: "/report/" + sign(report.Resource, map[string]string{"range": "365"}), code: http.StatusBadRequest},
+		"unknown report theme":     {path: "/report/" + sign(report.Resource, map[string]string{"theme": "neon"}), code: http.StatusBadRequest},
+	}
+	allowUserSettings(mock, len(tcs))
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			w := serve(h, httptest.NewRequest(http.MethodGet, tc.path, nil))
+			require.Equal(t, tc.code, w.Code)
+		})
+	}
+}
+
+func TestRevokedLinksAreRejected(t *testing.T) {
+	_, mock, h := newTestApp(t)
+	signer, err := urlsigner.New(urlsigner.Key{ID: "test", Secret: []byte("test-url-signing-key")})
+	require.NoError(t, err)
+	sig := signer.Sign(urlsigner.Params{Resource: report.Resource, TeamID: testTeamID, UserID: testUserID, Generation: 1, ExpiryDuration: time.Hour})
+
+	mock.ExpectQuery("FROM user_settings").WillReturnRows(sqlmock.NewRows([]string{"team_id", "user_id", "link_generation"}).AddRow(testTeamID, testUserID, 2))
+	w := serve(h, httptest.NewRequest(http.MethodGet, "/report/"+sig, nil))
+	require.Equal(t, http.StatusUnauthorized, w.Code, "links from before a reset should be rejected")
+
+	mock.ExpectQuery("FROM user_settings").WillReturnError(errors.New("database down"))
+	w = serve(h, httptest.NewRequest(http.MethodGet, "/report/"+sig, nil))
+	require.Equal(t, http.StatusInternalServerError, w.Code, "links shouldn't be served when they can't be checked")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestResetLinks(t *testing.T) {
+	fs, mock, h := newTestApp(t)
+	allowUserSettings(mock, 3)
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO user_settings .* link_generation=link_generation\\+1").WithArgs(testTeamID, testUserID).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("UPDATE shares SET revoked_at = \\? WHERE team_id = \\? AND user_id = \\? AND revoked_at IS NULL").WithArgs(sqlmock.AnyArg(), testTeamID, testUserID).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	r, err := fakeslack.NewInteractionRequest(testSigningSecret, "/interactive", slack.InteractionCallback{
+		Type:           slack.InteractionTypeBlockActions,
+		Team:           slack.Team{ID: testTeamID},
+		User:           slack.User{ID: testUserID},
+		ActionCallback: slack.ActionCallbacks{BlockActions: []*slack.BlockAction{{ActionID: homeButtonResetLinks}}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, serve(h, r).Code)
+
+	cc, err := fs.WaitForCalls("chat.postMessage", 1, testWait)
+	require.NoError(t, err)
+	require.Equal(t, i18n.T(i18n.English, "links.reset_done"), cc[0].Values.Get("text"))
+
+	_, err = fs.WaitForCalls("views.publish", 1, testWait)
+	require.NoError(t, err, "the home tab should get new links")
+}
+
+func TestResetLinksRevokesShares(t *testing.T) {
+	fs, mock, h := newTestApp(t)
+	allowUserSettings(mock, 1)
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO user_settings .* link_generation=link_generation\\+1").WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("UPDATE shares SET revoked_at").WillReturnError(errors.New("database down"))
+	mock.ExpectRollback()
+
+	r, err := fakeslack.NewInteractionRequest(testSigningSecret, "/interactive", slack.InteractionCallback{
+		Type:           slack.InteractionTypeBlockActions,
+		Team:           slack.Team{ID: testTeamID},
+		User:           slack.User{ID: testUserID},
+		ActionCallback: slack.ActionCallbacks{BlockActions: []*slack.BlockAction{{ActionID: homeButtonResetLinks}}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, serve(h, r).Code)
+
+	cc, err := fs.WaitForCalls("chat.postMessage", 1, testWait)
+	require.NoError(t, err)
+	require.Equal(t, i18n.T(i18n.English, "links.reset_failed"), cc[0].Values.Get("text"), "links shouldn't be reset while shares are still listed")
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	signer, err := urlsigner.New(urlsigner.Key{ID: "test", Secret: []byte("test-url-signing-key")})
+	require.NoError(t, err)
+	sig := signer.Sign(urlsigner.Params{Resource: shareResource, TeamID: testTeamID, UserID: testUserID, Options: map[string]string{"id": "7"}, Generation: 1, ExpiryDuration: time.Hour})
+	mock.ExpectQuery("FROM user_settings").WillReturnRows(sqlmock.NewRows([]string{"team_id", "user_id", "link_generation"}).AddRow(testTeamID, testUserID, 2))
+	w := serve(h, httptest.NewRequest(http.MethodGet, "/share/"+sig, nil))
+	require.Equal(t, http.StatusUnauthorized, w.Code, "shares from before a reset should be rejected without counting a view")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestShareLinksCountViews(t *testing.T) {
+	fs, mock, h := newTestApp(t)
+	allowUserSettings(mock, 6)
+	mock.ExpectQuery("FROM shares WHERE team_id").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectExec("INSERT INTO shares").WithArgs(testTeamID, testUserID, "My coach", shareChartHeatmap, sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(7, 1))
+
+	r, err := fakeslack.NewInteractionRequest(testSigningSecret, "/interactive", slack.InteractionCallback{
+		Type: slack.InteractionTypeViewSubmission,
+		Team: slack.Team{ID: testTeamID},
+		User: slack.User{ID: testUserID},
+		View: slack.View{CallbackID: shareModalCallbackID, State: &slack.ViewState{Values: map[string]map[string]slack.BlockAction{
+			"share_name":      {"input": {Value: " My coach "}},
+			"share_chart":     {"select": {SelectedOption: slack.OptionBlockObject{Value: shareChartHeatmap}}},
+			"share_expiry":    {"select": {SelectedOption: slack.OptionBlockObject{Value: "7"}}},
+			"share_max_views": {"input": {Value: "2"}},
+		}}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, serve(h, r).Code)
+
+	cc, err := fs.WaitForCalls("chat.postMessage", 1, testWait)
+	require.NoError(t, err)
+	text := cc[0].Values.Get("text")
+	require.Contains(t, text, "My coach")
+	require.Equal(t, "false", cc[0].Values.Get("unfurl_media"), "unfurling would use up a view")
+	i := strings.Index(text, "http://localhost/share/")
+	require.GreaterOrEqual(t, i, 0, "the message should have the link")
+	path := strings.TrimPrefix(strings.Fields(text[i:])[0], "http://localhost")
+
+	shareRow := sqlmock.NewRows([]string{"id", "team_id", "user_id", "name", "chart", "max_views", "views", "expires_at"}).
+		AddRow(7, testTeamID, testUserID, "My coach", shareChartHeatmap, 2, 1, time.Now().Add(time.Hour))
+	mock.ExpectQuery("FROM shares WHERE id = \\? AND team_id = \\? AND user_id = \\? AND revoked_at IS NULL").WithArgs(int64(7), testTeamID, testUserID, sqlmock.AnyArg()).WillReturnRows(shareRow)
+	mock.ExpectQuery("FROM reflections").WillReturnRows(sqlmock.NewRows([]string{"team_id", "user_id", "date"}))
+	mock.ExpectExec("UPDATE shares SET views = views \\+ 1").WithArgs(int64(7), testTeamID, testUserID, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+	w := serve(h, httptest.NewRequest(http.MethodGet, path, nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "image/png", w.Header().Get("Content-Type"))
+
+	mock.ExpectQuery("FROM shares WHERE id").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	w = serve(h, httptest.NewRequest(http.MethodGet, path, nil))
+	require.Equal(t, http.StatusGone, w.Code, "used up, expired and revoked shares shouldn't be served")
+
+	// the last view was taken while the chart was rendering
+	mock.ExpectQuery("FROM shares WHERE id").WillReturnRows(sqlmock.NewRows([]string{"id", "team_id", "user_id", "name", "chart", "max_views", "views", "expires_at"}).
+		AddRow(7, testTeamID, testUserID, "My coach", shareChartHeatmap, 2, 1, time.Now().Add(time.Hour)))
+	mock.ExpectQuery("FROM reflections").WillReturnRows(sqlmock.NewRows([]string{"team_id", "user_id", "date"}))
+	mock.ExpectExec("UPDATE shares SET views = views \\+ 1").WillReturnResult(sqlmock.NewResult(0, 0))
+	w = serve(h, httptest.NewRequest(http.MethodGet, path, nil))
+	require.Equal(t, http.StatusGone, w.Code, "shares shouldn't go over their views")
+
+	mock.ExpectQuery("FROM shares WHERE id").WillReturnRows(sqlmock.NewRows([]string{"id", "team_id", "user_id", "name", "chart", "max_views", "views", "expires_at"}).
+		AddRow(7, testTeamID, testUserID, "My coach", shareChartHeatmap, 2, 1, time.Now().Add(time.Hour)))
+	mock.ExpectQuery("FROM reflections").WillReturnError(errors.New("database down"))
+	w = serve(h, httptest.NewRequest(http.MethodGet, path, nil))
+	require.Equal(t, http.StatusInternalServerError, w.Code)
+	require.NoError(t, mock.ExpectationsWereMet(), "a failed render shouldn't use up a view")
+
+	for _, method := range []string{http.MethodHead, http.MethodPost} {
+		w = serve(h, httptest.NewRequest(method, path, nil))
+		require.Equal(t, http.StatusMethodNotAllowed, w.Code, method)
+		require.Equal(t, "GET", w.Header().Get("Allow"))
+	}
+
+	w = serve(h, httptest.NewRequest(http.MethodGet, strings.Replace(path, "/share/", "/heatmap/", 1), nil))
+	require.Equal(t, http.StatusUnauthorized, w.Code, "share links shouldn't work as chart links")
+}
+
+func TestDashboard(t *testing.T) {
+	_, mock, h := newTestApp(t)
+	signer, err := urlsigner.New(urlsigner.Key{ID: "test", Secret: []byte("test-url-signing-key")})
+	require.NoError(t, err)
+	sig := signer.Sign(urlsigner.Params{Resource: dashboard.Resource, TeamID: testTeamID, UserID: testUserID, TZ: -4, Options: map[string]string{"lang": "fr"}, ExpiryDuration: time.Hour})
+
+	allowUserSettings(mock, 2)
+	mock.ExpectQuery("FROM reflections WHERE `date` >= \\? AND `date` < \\?").WithArgs("2021-07-01 04:00:00", "2021-07-08 04:00:00", testTeamID, testUserID).
+		WillReturnRows(sqlmock.NewRows(strings.Split(store.Columns, ", ")).
+			AddRow(testTeamID, testUserID, time.Date(2021, 7, 2, 4, 0, 0, 0, time.UTC), "3-good", "2-some", nil, nil, nil, nil, nil, "6-happy", nil, nil, "1-one", nil, nil, time.Now()))
+	w := serve(h, httptest.NewRequest(http.MethodGet, "/dashboard/"+sig+"?from=2021-07-01&to=2021-07-07", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "text/html; charset=utf-8", w.Header().Get("Content-Type"))
+	require.Contains(t, w.Header().Get("Content-Security-Policy"), "script-src 'nonce-")
+	body := w.Body.String()
+	require.Contains(t, body, i18n.T("fr", "dashboard.title"), "the page should be in the link's language")
+	require.Contains(t, body, "<td>2021-07-02</td>", "reflections should be dated in the user's timezone")
+	require.Contains(t, body, i18n.T("fr", "option.quality.3-good"))
+	require.Contains(t, body, `"id":"trend-work_day_quality"`)
+
+	w = serve(h, httptest.NewRequest(http.MethodGet, "/dashboard/"+sig+"?from=2021-07-07&to=2021-07-01", nil))
+	require.Equal(t, http.StatusBadRequest, w.Code, "backwards ranges should be rejected")
+
+	w = serve(h, httptest.NewRequest(http.MethodGet, "/dashboard/"+signer.Sign(urlsigner.Params{Resource: report.Resource, TeamID: testTeamID, UserID: testUserID, ExpiryDuration: time.Hour}), nil))
+	require.Equal(t, http.StatusUnauthorized, w.Code, "chart links shouldn't open the dashboard")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestShareModalValidation(t *testing.T) {
+	_, mock, h := newTestApp(t)
+	allowUserSettings(mock, 1)
+
+	r, err := fakeslack.NewInteractionRequest(testSigningSecret, "/interactive", slack.InteractionCallback{
+		Type: slack.InteractionTypeViewSubmission,
+		Team: slack.Team{ID: testTeamID},
+		User: slack.User{ID: testUserID},
+		View: slack.View{CallbackID: shareModalCallbackID, State: &slack.ViewState{Values: map[string]map[string]slack.BlockAction{
+			"share_name":      {"input": {Value: "  "}},
+			"share_chart":     {"select": {SelectedOption: slack.OptionBlockObject{Value: shareChartHeatmap}}},
+			"share_expiry":    {"select": {SelectedOption: slack.OptionBlockObject{Value: "7"}}},
+			"share_max_views": {"input": {Value: "lots"}},
+		}}},
+	})
+	require.NoError(t, err)
+	w := serve(h, r)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var res slack.ViewSubmissionResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	require.Equal(t, slack.RAErrors, res.ResponseAction)
+	require.Contains(t, res.Errors, "share_name")
+	require.Contains(t, res.Errors, "share_max_views")
+	require.NoError(t, mock.ExpectationsWereMet(), "nothing should be saved")
+}
+
+func TestRevokeShare(t *testing.T) {
+	fs, mock, h := newTestApp(t)
+	allowUserSettings(mock, 2)
+	mock.ExpectExec("UPDATE shares SET revoked_at").WithArgs(sqlmock.AnyArg(), int64(7), testTeamID, testUserID).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	r, err := fakeslack.NewInteractionRequest(testSigningSecret, "/interactive", slack.InteractionCallback{
+		Type:           slack.InteractionTypeBlockActions,
+		Team:           slack.Team{ID: testTeamID},
+		User:           slack.User{ID: testUserID},
+		ActionCallback: slack.ActionCallbacks{BlockActions: []*slack.BlockAction{{ActionID: homeButtonRevokeShare, Value: "7"}}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, serve(h, r).Code)
+
+	_, err = fs.WaitForCalls("views.publish", 1, testWait)
+	require.NoError(t, err, "the home tab should stop listing the share")
+}
+
+func TestMetrics(t *testing.T) {
+	fs, mock, h := newTestApp(t)
+	allowUserSettings(mock, 1)
+
+	r := fakeslack.NewSlashRequest(testSigningSecret, "/slash", slack.SlashCommand{Command: "/reflect", TeamID: testTeamID, UserID: testUserID, TriggerID: "trigger-1"})
+	require.Equal(t, http.StatusOK, serve(h, r).Code)
+	_, err := fs.WaitForCalls("views.open", 1, testWait)
+	require.NoError(t, err)
+
+	// the call is recorded once the client has the response, just after the
+	// fake server has seen the request
+	require.Eventually(t, func() bool {
+		body := serve(h, httptest.NewRequest(http.MethodGet, "/metrics", nil)).Body.String()
+		return strings.Contains(body, `goodday_slack_calls_total{method="views.open",result="ok"} 1`)
+	}, testWait, 10*time.Millisecond)
+
+	w := serve(h, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Contains(t, w.Body.String(), `goodday_http_requests_total{code="200",handler="slash"} 1`)
+	require.Contains(t, w.Body.String(), `goodday_db_queries_total{query="get_user_settings",result="ok"} 1`)
+}
+
+func TestSlackCallsAreTracedWithTheirRequest(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)))
+
+	fs, mock, h := newTestApp(t)
+	allowUserSettings(mock, 1)
+
+	r := fakeslack.NewSlashRequest(testSigningSecret, "/slash", slack.SlashCommand{Command: "/reflect", TeamID: testTeamID, UserID: testUserID, TriggerID: "trigger-1"})
+	require.Equal(t, http.StatusOK, serve(h, r).Code)
+	_, err := fs.WaitForCalls("views.open", 1, testWait)
+	require.NoError(t, err)
+
+	spans := func() map[string]sdktrace.ReadOnlySpan {
+		m := make(map[string]sdktrace.ReadOnlySpan)
+		for _, s := range sr.Ended() {
+			m[s.Name()] = s
+		}
+		return m
+	}
+	require.Eventually(t, func() bool {
+		_, ok := spans()["slack views.open"]
+		return ok
+	}, testWait, 10*time.Millisecond)
+
+	ss := spans()
+	require.Contains(t, ss, "slash")
+	require.Equal(t, ss["slash"].SpanContext().TraceID(), ss["slack views.open"].SpanContext().TraceID(), "the modal should be opened in the slash command's trace")
+	require.Equal(t, ss["slash"].SpanContext().TraceID(), ss["slack users.info"].SpanContext().TraceID())
+}
+
+func TestLargeRequestBodiesAreRejected(t *testing.T) {
+	_, _, h := newTestApp(t)
+
+	body := "text=" + strings.Repeat("a", maxRequestBodySize)
+	r := fakeslack.NewSignedRequest(testSigningSecret, "/slash", "application/x-www-form-urlencoded", []byte(body))
+	require.Equal(t, http.StatusRequestEntityTooLarge, serve(h, r).Code)
+}
+
+// confirmImport clicks the confirm button of an import preview.
+func confirmImport(t *testing.T, h http.Handler, uid string, req importRequest) {
+	v, err := json.Marshal(req)
+	require.NoError(t, err)
+	r, err := fakeslack.NewInteractionRequest(testSigningSecret, "/interactive", slack.InteractionCallback{
+		Type:           slack.InteractionTypeBlockActions,
+		Team:           slack.Team{ID: testTeamID},
+		User:           slack.User{ID: uid},
+		Channel:        slack.Channel{GroupConversation: slack.GroupConversation{Conversation: slack.Conversation{ID: "D" + uid}}},
+		Message:        slack.Message{Msg: slack.Msg{Timestamp: "1625259600.000100"}},
+		ActionCallback: slack.ActionCallbacks{BlockActions: []*slack.BlockAction{{ActionID: importButtonConfirm, Value: string(v)}}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, serve(h, r).Code)
+}
+
+// payloadContaining matches a webhook payload containing s.
+type payloadContaining string
+
+func (s payloadContaining) Match(v driver.Value) bool {
+	b, ok := v.([]byte)
+	return ok && strings.Contains(string(b), string(s))
+}
+
+// importArgs are the insert arguments of reflections on each date, in order.
+func importArgs(dates ...time.Time) []driver.Value {
+	var args []driver.Value
+	for _, d := range dates {
+		args = append(args, testTeamID, testUserID, d)
+		for range reflection.Questions {
+			args = append(args, sqlmock.AnyArg())
+		}
+	}
+	return args
+}
+
+func TestImportForSomeoneElseNeedsAdmin(t *testing.T) {
+	tcs := map[string]struct {
+		admin   bool
+		allowed bool
+	}{
+		"member": {},
+		"admin":  {admin: true, allowed: true},
+	}
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			fs, mock, h := newTestApp(t)
+			fs.AddUser(slack.User{ID: "U2", Name: "grace", TZ: "America/Toronto", IsAdmin: tc.admin, Locale: "en-US"})
+			allowUserSettings(mock, 2)
+
+			confirmImport(t, h, "U2", importRequest{FileID: "F123456", UserID: testUserID})
+
+			cc, err := fs.WaitForCalls("chat.postMessage", 1, testWait)
+			require.NoError(t, err)
+			if tc.allowed {
+				require.Equal(t, i18n.T(i18n.English, "import.failed"), cc[0].Values.Get("text"), "the missing file should be looked up")
+				require.Len(t, fs.Calls("files.info"), 1)
+			} else {
+				require.Equal(t, i18n.T(i18n.English, "import.admin_only"), cc[0].Values.Get("text"))
+				require.Empty(t, fs.Calls("files.info"), "the file shouldn't be read")
+			}
+			require.Empty(t, fs.Calls("chat.delete"), "the preview should be kept")
+		})
+	}
+}
+
+func TestImportSkipsConflictingDays(t *testing.T) {
+	fs, mock, h := newTestApp(t)
+	fs.AddFile(fakeslack.File{ID: "F123456", Name: "reflections.csv", Filetype: "csv", User: testUserID, Content: []byte(`date,work_day_quality
+2021-07-01T21:00:00Z,2-ok
+2021-07-02T21:00:00Z,3-good
+2021-07-05T21:00:00Z,1-bad
+`)})
+	allowUserSettings(mock, 3)
+	// the 2nd already has a reflection, later in the day
+	mock.ExpectQuery("FROM reflections WHERE team_id = \\? AND user_id = \\? ORDER BY date").WithArgs(testTeamID, testUserID).
+		WillReturnRows(sqlmock.NewRows([]string{"team_id", "user_id", "date"}).AddRow(testTeamID, testUserID, time.Date(2021, 7, 2, 23, 30, 0, 0, time.UTC)))
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO reflections").
+		WithArgs(importArgs(time.Date(2021, 7, 1, 21, 0, 0, 0, time.UTC), time.Date(2021, 7, 5, 21, 0, 0, 0, time.UTC))...).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+	// webhooks get one event for the whole import
+	mock.ExpectExec("INSERT INTO webhook_deliveries").
+		WithArgs(testTeamID, testUserID, webhook.EventReflectionsImported, payloadContaining(`"imported":{"from":"2021-07-01T21:00:00Z","to":"2021-07-05T21:00:00Z","count":2}`), sqlmock.AnyArg(), testTeamID, testUserID, webhook.ScopeTeam).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	confirmImport(t, h, testUserID, importRequest{FileID: "F123456", UserID: testUserID})
+
+	cc, err := fs.WaitForCalls("chat.postMessage", 1, testWait)
+	require.NoError(t, err)
+	require.Equal(t, i18n.T(i18n.English, "import.done", 2, 1), cc[0].Values.Get("text"))
+	dd := fs.Calls("chat.delete")
+	require.Len(t, dd, 1, "the preview should be removed")
+	require.Equal(t, "1625259600.000100", dd[0].Values.Get("ts"))
+	_, err = fs.WaitForCalls("views.publish", 1, testWait)
+	require.NoError(t, err, "the home tab should be refreshed")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestImportRollsBackFailedBatch(t *testing.T) {
+	fs, mock, h := newTestApp(t)
+	csv := []string{"date,work_day_quality"}
+	var dates []time.Time
+	for i := 0; i < importBatchSize+1; i++ {
+		d := time.Date(2019, 1, 1, 21, 0, 0, 0, time.UTC).AddDate(0, 0, i)
+		csv = append(csv, d.Format(time.RFC3339)+",2-ok")
+		dates = append(dates, d)
+	}
+	fs.AddFile(fakeslack.File{ID: "F123456", Name: "reflections.csv", Filetype: "csv", User: testUserID, Content: []byte(strings.Join(csv, "\n"))})
+	allowUserSettings(mock, 2)
+	mock.ExpectQuery("FROM reflections WHERE team_id = \\? AND user_id = \\? ORDER BY date").WillReturnRows(sqlmock.NewRows([]string{"team_id", "user_id", "date"}))
+	mock.MatchExpectationsInOrder(true)
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO reflections").WithArgs(importArgs(dates[:importBatchSize]...)...).WillReturnResult(sqlmock.NewResult(0, importBatchSize))
+	mock.ExpectExec("INSERT INTO reflections").WithArgs(importArgs(dates[importBatchSize:]...)...).WillReturnError(errors.New("database down"))
+	mock.ExpectRollback()
+
+	confirmImport(t, h, testUserID, importRequest{FileID: "F123456", UserID: testUserID})
+
+	cc, err := fs.WaitForCalls("chat.postMessage", 1, testWait)
+	require.NoError(t, err)
+	require.Equal(t, i18n.T(i18n.English, "import.failed"), cc[0].Values.Get("text"))
+	require.Empty(t, fs.Calls("chat.delete"), "the preview should be kept to try again")
+	require.NoError(t, mock.ExpectationsWereMet(), "the first batch should be rolled back")
+}
@@ -0,0 +1,89 @@
+// Package export writes reflections in the formats users can download them in.
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/jharlap/good-day-app/i18n"
+	"github.com/jharlap/good-day-app/reflection"
+)
+
+// CSVWriter writes reflections as CSV, one row per reflection, with a label
+// and a numeric score column per question. Dates are in the user's timezone.
+type CSVWriter struct {
+	cw        *csv.Writer
+	lang      string
+	loc       *time.Location
+	questions []reflection.Question
+	wroteHead bool
+}
+
+func NewCSV(w io.Writer, lang string, loc *time.Location) *CSVWriter {
+	return &CSVWriter{
+		cw:        csv.NewWriter(w),
+		lang:      lang,
+		loc:       loc,
+		questions: reflection.LocalizedQuestions(lang),
+	}
+}
+
+// Header returns the CSV header row.
+func (c *CSVWriter) Header() []string {
+	h := []string{
+		i18n.T(c.lang, "export.date"),
+		i18n.T(c.lang, "export.time"),
+		i18n.T(c.lang, "export.timezone"),
+	}
+	for _, q := range c.questions {
+		h = append(h, q.Text, i18n.T(c.lang, "export.score", q.Text))
+	}
+	return h
+}
+
+// Write writes a reflection as a row, preceded by the header for the first row.
+func (c *CSVWriter) Write(r reflection.Reflection) error {
+	if !c.wroteHead {
+		if err := c.cw.Write(c.Header()); err != nil {
+			return fmt.Errorf("error writing csv header: %w", err)
+		}
+		c.wroteHead = true
+	}
+
+	d := r.Date.In(c.loc)
+	row := []string{d.Format(DateFormat), d.Format(TimeFormat), c.loc.String()}
+	for _, q := range c.questions {
+		code := reflection.NumberPrefixedEnum(r.ValueForQuestion(q.Field))
+		var score string
+		if v := code.IntVal(); v >= 0 {
+			score = strconv.Itoa(v)
+		}
+		row = append(row, q.Options.ValueFor(string(code)), score)
+	}
+
+	if err := c.cw.Write(row); err != nil {
+		return fmt.Errorf("error writing csv row: %w", err)
+	}
+	return nil
+}
+
+// Close writes the header if no reflections were written, and flushes.
+func (c *CSVWriter) Close() error {
+	if !c.wroteHead {
+		if err := c.cw.Write(c.Header()); err != nil {
+			return fmt.Errorf("error writing csv header: %w", err)
+		}
+		c.wroteHead = true
+	}
+
+	c.cw.Flush()
+	return c.cw.Error()
+}
+
+const (
+	DateFormat = "2006-01-02"
+	TimeFormat = "15:04"
+)
@@ -0,0 +1,48 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+	"time"
+
+	"github.com/jharlap/good-day-app/i18n"
+	"github.com/jharlap/good-day-app/reflection"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCSVWriter(t *testing.T) {
+	loc, err := time.LoadLocation("America/Toronto")
+	require.NoError(t, err, "programmer error: unknown test timezone")
+
+	buf := new(bytes.Buffer)
+	w := NewCSV(buf, i18n.English, loc)
+	require.NoError(t, w.Write(reflection.Reflection{
+		Date:              time.Date(2021, 7, 2, 1, 30, 0, 0, time.UTC),
+		WorkDayQuality:    "3-good",
+		InterruptedAmount: "4-most",
+	}))
+	require.NoError(t, w.Write(reflection.Reflection{Date: time.Date(2021, 7, 2, 21, 0, 0, 0, time.UTC)}))
+	require.NoError(t, w.Close())
+
+	rows, err := csv.NewReader(buf).ReadAll()
+	require.NoError(t, err, "output should be valid csv")
+	require.Len(t, rows, 3, "should have a header and two rows")
+
+	require.Equal(t, []string{"Date", "Time", "Timezone", "How was your work day?", "How was your work day? (score)"}, rows[0][:5], "header mismatch")
+	require.Equal(t, []string{"2021-07-01", "21:30", "America/Toronto", "Good", "3"}, rows[1][:5], "date should be in user timezone, with label and score")
+	require.Equal(t, "Most or all of the day", rows[1][9], "interruptions label mismatch")
+	require.Equal(t, "4", rows[1][10], "interruptions score mismatch")
+	require.Equal(t, []string{"2021-07-02", "17:00", "America/Toronto", "", ""}, rows[2][:5], "unanswered questions should be empty")
+}
+
+func TestCSVWriterEmpty(t *testing.T) {
+	buf := new(bytes.Buffer)
+	w := NewCSV(buf, i18n.French, time.UTC)
+	require.NoError(t, w.Close())
+
+	rows, err := csv.NewReader(buf).ReadAll()
+	require.NoError(t, err, "output should be valid csv")
+	require.Len(t, rows, 1, "should have only a header")
+	require.Equal(t, "Comment s'est passée votre journée de travail ?", rows[0][3], "header should be translated")
+}
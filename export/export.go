@@ -0,0 +1,44 @@
+package export
+
+import (
+	"errors"
+	"io"
+	"time"
+
+	"github.com/jharlap/good-day-app/reflection"
+)
+
+const (
+	FormatCSV    = "csv"
+	FormatJSON   = "json"
+	FormatNDJSON = "ndjson"
+	// FormatZIP is an archive of the data in other formats plus charts, so
+	// it is assembled by the caller rather than by a Writer.
+	FormatZIP = "zip"
+)
+
+// Formats lists the export formats users can choose, default first.
+var Formats = []string{FormatCSV, FormatJSON, FormatNDJSON, FormatZIP}
+
+var ErrUnknownFormat = errors.New("unknown export format")
+
+// Writer writes reflections one at a time in an export format. Close must be
+// called after the last reflection to complete the output.
+type Writer interface {
+	Write(r reflection.Reflection) error
+	Close() error
+}
+
+// New returns a Writer for the data format, with text in lang and dates in loc.
+func New(format string, w io.Writer, lang string, loc *time.Location) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return NewCSV(w, lang, loc), nil
+	case FormatJSON:
+		return NewJSON(w, lang, loc), nil
+	case FormatNDJSON:
+		return NewNDJSON(w, lang, loc), nil
+	default:
+		return nil, ErrUnknownFormat
+	}
+}
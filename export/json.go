@@ -0,0 +1,165 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/jharlap/good-day-app/reflection"
+)
+
+// Record is a reflection as exported to JSON, with each answer described so
+// the data can be understood without the app's question definitions.
+type Record struct {
+	Date     time.Time `json:"date"`
+	Timezone string    `json:"timezone"`
+	Answers  []Answer  `json:"answers"`
+}
+
+type Answer struct {
+	Field    string `json:"field"`
+	Question string `json:"question"`
+	Code     string `json:"code,omitempty"`
+	Label    string `json:"label,omitempty"`
+	Score    *int   `json:"score,omitempty"`
+}
+
+// QuestionMeta describes a question and its possible answers.
+type QuestionMeta struct {
+	Field   string       `json:"field"`
+	Text    string       `json:"text"`
+	Ordered bool         `json:"ordered"`
+	Options []OptionMeta `json:"options"`
+}
+
+type OptionMeta struct {
+	Code  string `json:"code"`
+	Label string `json:"label"`
+	Score int    `json:"score"`
+}
+
+// NewRecord describes a reflection's answers to the questions, dated in loc.
+func NewRecord(r reflection.Reflection, questions []reflection.Question, loc *time.Location) Record {
+	rec := Record{Date: r.Date.In(loc), Timezone: loc.String()}
+	for _, q := range questions {
+		code := reflection.NumberPrefixedEnum(r.ValueForQuestion(q.Field))
+		a := Answer{Field: q.Field, Question: q.Text, Code: string(code), Label: q.Options.ValueFor(string(code))}
+		if v := code.IntVal(); v >= 0 {
+			a.Score = &v
+		}
+		rec.Answers = append(rec.Answers, a)
+	}
+	return rec
+}
+
+// Reflection is a reflection as served by the API and posted to webhooks,
+// with the id it can be got, updated and deleted by.
+type Reflection struct {
+	ID string `json:"id"`
+	Record
+}
+
+// NewReflection describes a reflection's answers to the questions, dated in
+// UTC, with its id.
+func NewReflection(r reflection.Reflection, questions []reflection.Question) Reflection {
+	return Reflection{
+		ID:     strconv.FormatInt(r.Date.Unix(), 10),
+		Record: NewRecord(r, questions, time.UTC),
+	}
+}
+
+// QuestionsMeta describes the questions, for exports and API clients.
+func QuestionsMeta(questions []reflection.Question) []QuestionMeta {
+	var qq []QuestionMeta
+	for _, q := range questions {
+		qm := QuestionMeta{Field: q.Field, Text: q.Text, Ordered: q.Options.Ordered}
+		for _, o := range q.Options.Options {
+			code := reflection.NumberPrefixedEnum(o.Code)
+			qm.Options = append(qm.Options, OptionMeta{Code: o.Code, Label: o.Text, Score: code.IntVal()})
+		}
+		qq = append(qq, qm)
+	}
+	return qq
+}
+
+// JSONWriter writes reflections as a single JSON document with the question
+// definitions followed by the reflections, streaming each reflection as it is
+// written rather than building the document in memory.
+type JSONWriter struct {
+	w         io.Writer
+	loc       *time.Location
+	questions []reflection.Question
+	n         int
+}
+
+func NewJSON(w io.Writer, lang string, loc *time.Location) *JSONWriter {
+	return &JSONWriter{w: w, loc: loc, questions: reflection.LocalizedQuestions(lang)}
+}
+
+func (j *JSONWriter) writeHead() error {
+	qb, err := json.Marshal(QuestionsMeta(j.questions))
+	if err != nil {
+		return fmt.Errorf("error marshaling questions: %w", err)
+	}
+	tb, err := json.Marshal(j.loc.String())
+	if err != nil {
+		return fmt.Errorf("error marshaling timezone: %w", err)
+	}
+	_, err = fmt.Fprintf(j.w, `{"timezone":%s,"questions":%s,"reflections":[`, tb, qb)
+	return err
+}
+
+func (j *JSONWriter) Write(r reflection.Reflection) error {
+	sep := ","
+	if j.n == 0 {
+		if err := j.writeHead(); err != nil {
+			return err
+		}
+		sep = ""
+	}
+
+	b, err := json.Marshal(NewRecord(r, j.questions, j.loc))
+	if err != nil {
+		return fmt.Errorf("error marshaling reflection: %w", err)
+	}
+	if _, err := fmt.Fprintf(j.w, "%s%s", sep, b); err != nil {
+		return fmt.Errorf("error writing reflection: %w", err)
+	}
+	j.n++
+	return nil
+}
+
+func (j *JSONWriter) Close() error {
+	if j.n == 0 {
+		if err := j.writeHead(); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(j.w, "]}\n")
+	return err
+}
+
+// NDJSONWriter writes one JSON record per line. Each record describes its
+// answers, so lines can be processed independently.
+type NDJSONWriter struct {
+	enc       *json.Encoder
+	loc       *time.Location
+	questions []reflection.Question
+}
+
+func NewNDJSON(w io.Writer, lang string, loc *time.Location) *NDJSONWriter {
+	return &NDJSONWriter{enc: json.NewEncoder(w), loc: loc, questions: reflection.LocalizedQuestions(lang)}
+}
+
+func (n *NDJSONWriter) Write(r reflection.Reflection) error {
+	if err := n.enc.Encode(NewRecord(r, n.questions, n.loc)); err != nil {
+		return fmt.Errorf("error writing reflection: %w", err)
+	}
+	return nil
+}
+
+func (n *NDJSONWriter) Close() error {
+	return nil
+}
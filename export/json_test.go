@@ -0,0 +1,97 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/jharlap/good-day-app/i18n"
+	"github.com/jharlap/good-day-app/reflection"
+	"github.com/stretchr/testify/require"
+)
+
+var testReflections = []reflection.Reflection{
+	{Date: time.Date(2021, 7, 2, 1, 30, 0, 0, time.UTC), WorkDayQuality: "3-good", MostProductiveTime: "0-morning"},
+	{Date: time.Date(2021, 7, 3, 21, 0, 0, 0, time.UTC)},
+}
+
+func TestJSONWriter(t *testing.T) {
+	buf := new(bytes.Buffer)
+	w := NewJSON(buf, i18n.English, time.UTC)
+	for _, r := range testReflections {
+		require.NoError(t, w.Write(r))
+	}
+	require.NoError(t, w.Close())
+
+	var doc struct {
+		Timezone    string         `json:"timezone"`
+		Questions   []QuestionMeta `json:"questions"`
+		Reflections []Record       `json:"reflections"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &doc), "output should be a valid json document")
+	require.Equal(t, "UTC", doc.Timezone)
+	require.Len(t, doc.Questions, len(reflection.Questions), "all questions should be described")
+	require.Equal(t, "work_day_quality", doc.Questions[0].Field)
+	require.True(t, doc.Questions[0].Ordered)
+	require.Equal(t, OptionMeta{Code: "4-awesome", Label: "Awesome", Score: 4}, doc.Questions[0].Options[4])
+
+	require.Len(t, doc.Reflections, 2)
+	a := doc.Reflections[0].Answers[0]
+	require.Equal(t, "Good", a.Label)
+	require.NotNil(t, a.Score)
+	require.Equal(t, 3, *a.Score)
+	require.Nil(t, doc.Reflections[1].Answers[0].Score, "unanswered questions should have no score")
+}
+
+func TestJSONWriterEmpty(t *testing.T) {
+	buf := new(bytes.Buffer)
+	w := NewJSON(buf, i18n.English, time.UTC)
+	require.NoError(t, w.Close())
+
+	var doc map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &doc), "output should be a valid json document")
+	require.Empty(t, doc["reflections"])
+}
+
+func TestNDJSONWriter(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Paris")
+	require.NoError(t, err, "programmer error: unknown test timezone")
+
+	buf := new(bytes.Buffer)
+	w := NewNDJSON(buf, i18n.French, loc)
+	for _, r := range testReflections {
+		require.NoError(t, w.Write(r))
+	}
+	require.NoError(t, w.Close())
+
+	var recs []Record
+	s := bufio.NewScanner(buf)
+	for s.Scan() {
+		var rec Record
+		require.NoError(t, json.Unmarshal(s.Bytes(), &rec), "each line should be valid json")
+		recs = append(recs, rec)
+	}
+	require.Len(t, recs, 2)
+	require.Equal(t, "Europe/Paris", recs[0].Timezone)
+	require.Equal(t, 3, recs[0].Date.Hour(), "date should be in user timezone")
+	require.Equal(t, "Bonne", recs[0].Answers[0].Label, "labels should be translated")
+	require.Equal(t, "0-morning", recs[0].Answers[11].Code)
+}
+
+func TestNewReflection(t *testing.T) {
+	loc, err := time.LoadLocation("America/Toronto")
+	require.NoError(t, err, "programmer error: unknown test timezone")
+	r := testReflections[0]
+	r.Date = r.Date.In(loc)
+
+	got := NewReflection(r, reflection.LocalizedQuestions(i18n.Default))
+	require.Equal(t, "1625189400", got.ID)
+	require.Equal(t, "UTC", got.Timezone, "reflections should be dated in UTC")
+	require.True(t, r.Date.Equal(got.Date))
+
+	b, err := json.Marshal(got)
+	require.NoError(t, err)
+	require.Contains(t, string(b), `{"id":"1625189400","date":"2021-07-02T01:30:00Z"`, "the record should be flattened")
+}
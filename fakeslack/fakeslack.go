@@ -0,0 +1,327 @@
+// Package fakeslack is an in-process fake of the parts of the Slack Web API
+// the app uses, for end-to-end tests. It records every call so tests can
+// check what the app sent, and helps build signed requests as Slack sends
+// them to the app.
+package fakeslack
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// BotUserID is the bot user the fake authenticates as.
+const BotUserID = "UBOT"
+
+// Call is a Slack API method call the app made.
+type Call struct {
+	Method string
+	// Values are the form values of form-encoded calls.
+	Values url.Values
+	// Body is the body of JSON calls.
+	Body []byte
+}
+
+// File is a file uploaded to the fake, or added to it for the app to download.
+type File struct {
+	ID        string
+	Name      string
+	Title     string
+	Filetype  string
+	User      string
+	ChannelID string
+	Content   []byte
+}
+
+// Server is a fake Slack API server.
+type Server struct {
+	srv *httptest.Server
+
+	mu      sync.Mutex
+	calls   []Call
+	users   map[string]slack.User
+	files   map[string]*File
+	nextID  int
+	changed chan struct{}
+}
+
+// New starts a fake Slack server, which must be closed when done.
+func New() *Server {
+	s := &Server{
+		users:   make(map[string]slack.User),
+		files:   make(map[string]*File),
+		changed: make(chan struct{}),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/auth.test", s.authTest)
+	mux.HandleFunc("/api/users.info", s.usersInfo)
+	mux.HandleFunc("/api/views.open", s.view)
+	mux.HandleFunc("/api/views.publish", s.view)
+	mux.HandleFunc("/api/chat.postMessage", s.postMessage)
+	mux.HandleFunc("/api/chat.delete", s.deleteMessage)
+	mux.HandleFunc("/api/conversations.open", s.openConversation)
+	mux.HandleFunc("/api/files.info", s.filesInfo)
+	mux.HandleFunc("/api/files.getUploadURLExternal", s.getUploadURL)
+	mux.HandleFunc("/api/files.completeUploadExternal", s.completeUpload)
+	mux.HandleFunc("/upload/", s.upload)
+	mux.HandleFunc("/download/", s.download)
+	s.srv = httptest.NewServer(mux)
+	return s
+}
+
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// APIURL is the URL to point Slack clients at, e.g. with slack.OptionAPIURL.
+func (s *Server) APIURL() string {
+	return s.srv.URL + "/api/"
+}
+
+// AddUser makes a user known to users.info.
+func (s *Server) AddUser(u slack.User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users[u.ID] = u
+}
+
+// AddFile makes a file available to files.info and for download.
+func (s *Server) AddFile(f File) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.files[f.ID] = &f
+}
+
+// Calls returns the calls made to method so far.
+func (s *Server) Calls(method string) []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var cc []Call
+	for _, c := range s.calls {
+		if c.Method == method {
+			cc = append(cc, c)
+		}
+	}
+	return cc
+}
+
+// WaitForCalls waits until at least n calls were made to method, since the
+// app makes many calls in the background, and returns them.
+func (s *Server) WaitForCalls(method string, n int, timeout time.Duration) ([]Call, error) {
+	deadline := time.After(timeout)
+	for {
+		s.mu.Lock()
+		changed := s.changed
+		s.mu.Unlock()
+
+		if cc := s.Calls(method); len(cc) >= n {
+			return cc, nil
+		}
+
+		select {
+		case <-changed:
+		case <-deadline:
+			return s.Calls(method), fmt.Errorf("timed out waiting for %d calls to %s", n, method)
+		}
+	}
+}
+
+// Files returns the files shared with the completed external upload flow.
+func (s *Server) Files() []File {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var ff []File
+	for _, f := range s.files {
+		if len(f.ChannelID) > 0 {
+			ff = append(ff, *f)
+		}
+	}
+	return ff
+}
+
+// record saves a call, reading its form values or JSON body.
+func (s *Server) record(method string, r *http.Request) Call {
+	c := Call{Method: method}
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		c.Body, _ = ioutil.ReadAll(r.Body)
+	} else {
+		r.ParseForm()
+		c.Values = r.PostForm
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = append(s.calls, c)
+	close(s.changed)
+	s.changed = make(chan struct{})
+	return c
+}
+
+func (s *Server) id(prefix string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	return prefix + strconv.Itoa(s.nextID)
+}
+
+func reply(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func replyError(w http.ResponseWriter, code string) {
+	reply(w, map[string]interface{}{"ok": false, "error": code})
+}
+
+func (s *Server) authTest(w http.ResponseWriter, r *http.Request) {
+	s.record("auth.test", r)
+	reply(w, map[string]interface{}{"ok": true, "user_id": BotUserID, "user": "goodday", "team_id": "T1"})
+}
+
+func (s *Server) usersInfo(w http.ResponseWriter, r *http.Request) {
+	c := s.record("users.info", r)
+
+	s.mu.Lock()
+	u, ok := s.users[c.Values.Get("user")]
+	s.mu.Unlock()
+	if !ok {
+		replyError(w, "user_not_found")
+		return
+	}
+	reply(w, map[string]interface{}{"ok": true, "user": u})
+}
+
+func (s *Server) view(w http.ResponseWriter, r *http.Request) {
+	c := s.record(strings.TrimPrefix(r.URL.Path, "/api/"), r)
+
+	var req struct {
+		View json.RawMessage `json:"view"`
+	}
+	if err := json.Unmarshal(c.Body, &req); err != nil || len(req.View) == 0 {
+		replyError(w, "invalid_arguments")
+		return
+	}
+	reply(w, map[string]interface{}{"ok": true, "view": json.RawMessage(req.View)})
+}
+
+func (s *Server) postMessage(w http.ResponseWriter, r *http.Request) {
+	c := s.record("chat.postMessage", r)
+	reply(w, map[string]interface{}{"ok": true, "channel": c.Values.Get("channel"), "ts": fmt.Sprintf("%d.000100", time.Now().Unix())})
+}
+
+func (s *Server) deleteMessage(w http.ResponseWriter, r *http.Request) {
+	c := s.record("chat.delete", r)
+	reply(w, map[string]interface{}{"ok": true, "channel": c.Values.Get("channel"), "ts": c.Values.Get("ts")})
+}
+
+func (s *Server) openConversation(w http.ResponseWriter, r *http.Request) {
+	c := s.record("conversations.open", r)
+	users := c.Values.Get("users")
+	if len(users) == 0 || strings.Contains(users, ",") {
+		replyError(w, "not_implemented")
+		return
+	}
+	reply(w, map[string]interface{}{"ok": true, "channel": map[string]interface{}{"id": "D" + users}})
+}
+
+func (s *Server) filesInfo(w http.ResponseWriter, r *http.Request) {
+	c := s.record("files.info", r)
+
+	s.mu.Lock()
+	f, ok := s.files[c.Values.Get("file")]
+	s.mu.Unlock()
+	if !ok {
+		replyError(w, "file_not_found")
+		return
+	}
+	reply(w, map[string]interface{}{"ok": true, "file": map[string]interface{}{
+		"id":                   f.ID,
+		"name":                 f.Name,
+		"title":                f.Title,
+		"filetype":             f.Filetype,
+		"user":                 f.User,
+		"size":                 len(f.Content),
+		"url_private_download": s.srv.URL + "/download/" + f.ID,
+	}})
+}
+
+func (s *Server) download(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	f, ok := s.files[strings.TrimPrefix(r.URL.Path, "/download/")]
+	s.mu.Unlock()
+	if !ok || !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.Write(f.Content)
+}
+
+func (s *Server) getUploadURL(w http.ResponseWriter, r *http.Request) {
+	c := s.record("files.getUploadURLExternal", r)
+	if _, err := strconv.Atoi(c.Values.Get("length")); err != nil {
+		replyError(w, "invalid_arguments")
+		return
+	}
+
+	id := s.id("F")
+	s.mu.Lock()
+	s.files[id] = &File{ID: id, Name: c.Values.Get("filename"), User: BotUserID}
+	s.mu.Unlock()
+
+	reply(w, map[string]interface{}{"ok": true, "file_id": id, "upload_url": s.srv.URL + "/upload/" + id})
+}
+
+func (s *Server) upload(w http.ResponseWriter, r *http.Request) {
+	b, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, ok := s.files[strings.TrimPrefix(r.URL.Path, "/upload/")]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	f.Content = b
+}
+
+func (s *Server) completeUpload(w http.ResponseWriter, r *http.Request) {
+	c := s.record("files.completeUploadExternal", r)
+
+	var req []struct {
+		ID    string `json:"id"`
+		Title string `json:"title"`
+	}
+	if err := json.Unmarshal([]byte(c.Values.Get("files")), &req); err != nil {
+		replyError(w, "invalid_arguments")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, rf := range req {
+		f, ok := s.files[rf.ID]
+		if !ok {
+			replyError(w, "file_not_found")
+			return
+		}
+		f.Title = rf.Title
+		f.ChannelID = c.Values.Get("channel_id")
+	}
+	reply(w, map[string]interface{}{"ok": true, "files": req})
+}
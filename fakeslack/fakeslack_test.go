@@ -0,0 +1,70 @@
+package fakeslack
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/jharlap/good-day-app/slackupload"
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignedRequestVerifies(t *testing.T) {
+	r := NewSlashRequest("secret", "/slash", slack.SlashCommand{Command: "/reflect", UserID: "U1"})
+	body, err := ioutil.ReadAll(r.Body)
+	require.NoError(t, err)
+
+	sv, err := slack.NewSecretsVerifier(r.Header, "secret")
+	require.NoError(t, err)
+	sv.Write(body)
+	require.NoError(t, sv.Ensure())
+
+	sv, err = slack.NewSecretsVerifier(r.Header, "other secret")
+	require.NoError(t, err)
+	sv.Write(body)
+	require.Error(t, sv.Ensure(), "the signature should depend on the secret")
+}
+
+func TestServer(t *testing.T) {
+	s := New()
+	defer s.Close()
+	s.AddUser(slack.User{ID: "U1", Name: "ada", TZ: "Europe/London"})
+	s.AddFile(File{ID: "FIN", Name: "in.csv", Filetype: "csv", Content: []byte("a,b\n")})
+
+	api := slack.New("xoxb-test", slack.OptionAPIURL(s.APIURL()))
+	ctx := context.Background()
+
+	u, err := api.GetUserInfoContext(ctx, "U1")
+	require.NoError(t, err)
+	require.Equal(t, "Europe/London", u.TZ)
+	_, err = api.GetUserInfoContext(ctx, "U2")
+	require.Error(t, err, "unknown users should not be found")
+
+	_, err = api.PublishViewContext(ctx, "U1", slack.HomeTabViewRequest{Type: slack.VTHomeTab}, "")
+	require.NoError(t, err)
+	_, _, err = api.PostMessageContext(ctx, "U1", slack.MsgOptionText("hi", false))
+	require.NoError(t, err)
+
+	cc, err := s.WaitForCalls("chat.postMessage", 1, time.Second)
+	require.NoError(t, err)
+	require.Equal(t, "hi", cc[0].Values.Get("text"))
+	require.Len(t, s.Calls("views.publish"), 1)
+
+	f, _, _, err := api.GetFileInfoContext(ctx, "FIN", 0, 0)
+	require.NoError(t, err)
+	buf := new(bytes.Buffer)
+	require.NoError(t, api.GetFile(f.URLPrivateDownload, buf))
+	require.Equal(t, "a,b\n", buf.String())
+
+	ch, _, _, err := api.OpenConversationContext(ctx, &slack.OpenConversationParameters{Users: []string{"U1"}})
+	require.NoError(t, err)
+	up := slackupload.New("xoxb-test", slackupload.OptionAPIURL(s.APIURL()))
+	_, err = up.Upload(ctx, slackupload.Params{Filename: "out.csv", Reader: bytes.NewReader([]byte("c,d\n")), ChannelID: ch.ID})
+	require.NoError(t, err)
+	require.Len(t, s.Files(), 1)
+	require.Equal(t, "c,d\n", string(s.Files()[0].Content))
+	require.Equal(t, ch.ID, s.Files()[0].ChannelID)
+}
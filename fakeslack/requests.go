@@ -0,0 +1,96 @@
+package fakeslack
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// Sign signs a request the way Slack does, with the app's signing secret.
+func Sign(r *http.Request, secret string, body []byte, now time.Time) {
+	ts := strconv.FormatInt(now.Unix(), 10)
+	h := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(h, "v0:%s:%s", ts, body)
+
+	r.Header.Set("X-Slack-Request-Timestamp", ts)
+	r.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(h.Sum(nil)))
+}
+
+// NewSignedRequest returns a POST request to target as Slack would send it.
+func NewSignedRequest(secret, target, contentType string, body []byte) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, target, bytes.NewReader(body))
+	r.Header.Set("Content-Type", contentType)
+	Sign(r, secret, body, time.Now())
+	return r
+}
+
+// NewSlashRequest returns a signed slash command request.
+func NewSlashRequest(secret, target string, s slack.SlashCommand) *http.Request {
+	v := url.Values{
+		"token":        {s.Token},
+		"team_id":      {s.TeamID},
+		"team_domain":  {s.TeamDomain},
+		"channel_id":   {s.ChannelID},
+		"channel_name": {s.ChannelName},
+		"user_id":      {s.UserID},
+		"user_name":    {s.UserName},
+		"command":      {s.Command},
+		"text":         {s.Text},
+		"response_url": {s.ResponseURL},
+		"trigger_id":   {s.TriggerID},
+	}
+	return NewSignedRequest(secret, target, "application/x-www-form-urlencoded", []byte(v.Encode()))
+}
+
+// NewInteractionRequest returns a signed interactive component request, such
+// as a block action or view submission.
+func NewInteractionRequest(secret, target string, ic slack.InteractionCallback) (*http.Request, error) {
+	b, err := json.Marshal(&ic)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding interaction: %w", err)
+	}
+	v := url.Values{"payload": {string(b)}}
+	return NewSignedRequest(secret, target, "application/x-www-form-urlencoded", []byte(v.Encode())), nil
+}
+
+// NewEventRequest returns a signed Events API request wrapping the inner
+// event, or sending it as is for url_verification.
+func NewEventRequest(secret, target, teamID string, event interface{}) (*http.Request, error) {
+	inner, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding event: %w", err)
+	}
+
+	var typ struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(inner, &typ); err != nil {
+		return nil, fmt.Errorf("error reading event type: %w", err)
+	}
+
+	body := inner
+	if typ.Type != "url_verification" {
+		body, err = json.Marshal(map[string]interface{}{
+			"type":    "event_callback",
+			"team_id": teamID,
+			"event":   json.RawMessage(inner),
+			"authorizations": []map[string]interface{}{
+				{"team_id": teamID, "user_id": BotUserID, "is_bot": true},
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error encoding event callback: %w", err)
+		}
+	}
+	return NewSignedRequest(secret, target, "application/json", body), nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Build info, set at build time with -ldflags "-X main.version=...".
+var (
+	version = "dev"
+	commit  = "unknown"
+	date    = "unknown"
+)
+
+// readinessTimeout is how long each readiness check may take.
+const readinessTimeout = 2 * time.Second
+
+// readinessCheck checks one dependency the app needs to serve requests.
+type readinessCheck struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+func (a *App) readinessChecks() []readinessCheck {
+	return []readinessCheck{
+		{"database", a.db.PingContext},
+		{"slack", func(ctx context.Context) error {
+			_, err := a.slack.AuthTestContext(ctx)
+			return err
+		}},
+		{"render", a.reporter.Ping},
+	}
+}
+
+// handleHealthz reports the process is alive, without checking dependencies,
+// so a slow database doesn't get the app restarted.
+func handleHealthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write([]byte("ok\n"))
+}
+
+// handleReadyz checks every dependency concurrently, each with a timeout, and
+// responds 503 with the failures if any are unavailable.
+func (a *App) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	checks := a.readinessChecks()
+	results := make(map[string]string, len(checks))
+	ready := true
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, c := range checks {
+		wg.Add(1)
+		go func(c readinessCheck) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+			defer cancel()
+
+			res := "ok"
+			if err := c.check(ctx); err != nil {
+				log.Error().Err(err).Str("check", c.name).Msg("readiness check failed")
+				res = err.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			results[c.name] = res
+			ready = ready && res == "ok"
+		}(c)
+	}
+	wg.Wait()
+
+	status := "ok"
+	code := http.StatusOK
+	if !ready {
+		status = "unavailable"
+		code = http.StatusServiceUnavailable
+	}
+	writeJSON(w, code, map[string]interface{}{"status": status, "checks": results})
+}
+
+func handleVersion(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{
+		"version": version,
+		"commit":  commit,
+		"date":    date,
+		"go":      runtime.Version(),
+	})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Debug().Err(err).Msg("error writing json response")
+	}
+}
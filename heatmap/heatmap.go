@@ -1,18 +1,24 @@
 package heatmap
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"image/color"
+	"io"
 	"net/http"
 	"strings"
 	"time"
 
+	"github.com/jharlap/good-day-app/metrics"
 	"github.com/jharlap/good-day-app/reflection"
+	"github.com/jharlap/good-day-app/tracing"
 	"github.com/jharlap/good-day-app/urlsigner"
 	"github.com/jmoiron/sqlx"
 	"github.com/nikolaydubina/calendarheatmap/charts"
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
 	"golang.org/x/image/font"
 )
 
@@ -22,9 +28,10 @@ type Heatmap struct {
 	defaultFontFace   font.Face
 	signer            *urlsigner.Engine
 	db                *sqlx.DB
+	metrics           *metrics.Metrics
 }
 
-func New(baseURL string, signer *urlsigner.Engine, db *sqlx.DB, fontFaceBytes []byte) *Heatmap {
+func New(baseURL string, signer *urlsigner.Engine, db *sqlx.DB, fontFaceBytes []byte, m *metrics.Metrics) *Heatmap {
 	fontFace, err := charts.LoadFontFace(fontFaceBytes)
 	if err != nil {
 		log.Fatal().Err(err).Msg("error loading font face")
@@ -41,16 +48,26 @@ func New(baseURL string, signer *urlsigner.Engine, db *sqlx.DB, fontFaceBytes []
 			color.RGBA{0xCB, 0xF3, 0xF0, 255},
 			color.RGBA{0x2E, 0xC4, 0xB6, 255},
 		},
-		signer: signer,
-		db:     db,
+		signer:  signer,
+		db:      db,
+		metrics: m,
 	}
 }
 
-func (h *Heatmap) URLForTeamAndUser(teamID, userID string, tz int) string {
+func (h *Heatmap) URLForTeamAndUser(teamID, userID string, tz int, generation uint64) string {
+	return h.URL(teamID, userID, tz, generation, DefaultOptions)
+}
+
+// URL returns a signed URL for the user's heatmap drawn with o, in the
+// generation of the user's links.
+func (h *Heatmap) URL(teamID, userID string, tz int, generation uint64, o Options) string {
 	sig := h.signer.Sign(urlsigner.Params{
+		Resource:       Resource,
 		TeamID:         teamID,
 		UserID:         userID,
 		TZ:             tz,
+		Options:        o.signed(),
+		Generation:     generation,
 		ExpiryDuration: time.Hour * 24 * 30,
 	})
 	return fmt.Sprintf("%s/%s", h.baseURL, sig)
@@ -65,11 +82,15 @@ func (h *Heatmap) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		p, err := h.signer.Parse(r.URL.Path[i+1:])
+		p, err := h.signer.ParseContext(r.Context(), r.URL.Path[i+1:], Resource)
 		if errors.Is(err, urlsigner.ErrInvalidSignature) {
 			w.WriteHeader(http.StatusUnauthorized)
 			log.Debug().Err(err).Str("path", r.URL.Path).Msg("invalid url signing")
 			return
+		} else if errors.Is(err, urlsigner.ErrGenerationUnavailable) {
+			w.WriteHeader(http.StatusInternalServerError)
+			log.Error().Err(err).Msg("error checking heatmap url is current")
+			return
 		} else if err != nil {
 			w.WriteHeader(http.StatusBadRequest)
 			log.Debug().Err(err).Str("path", r.URL.Path).Msg("invalid url signing")
@@ -78,13 +99,51 @@ func (h *Heatmap) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		rp = p
 	}
 
-	startOfYear := time.Date(time.Now().Year(), 1, 1, 0, 0, 0, 0, time.UTC).Format(mysqlDateFormat)
-	rows, err := h.db.QueryxContext(r.Context(), "SELECT * FROM reflections WHERE DATE(`date`) >= ? AND team_id = ? AND user_id = ?", startOfYear, rp.TeamID, rp.UserID)
+	o, err := optionsFromSigned(rp.Options)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		log.Debug().Err(err).Str("path", r.URL.Path).Msg("invalid heatmap options")
+		return
+	}
+
+	buf := new(bytes.Buffer)
+	err = h.Render(r.Context(), buf, rp.TeamID, rp.UserID, rp.TZ, o)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
-		log.Error().Err(err).Msgf("error querying for day quality calendar for uid %s", rp.UserID)
+		log.Error().Err(err).Str("tid", rp.TeamID).Str("uid", rp.UserID).Msg("error rendering heatmap")
 		return
 	}
+
+	w.Header().Set("Content-Type", contentTypes[o.Format])
+	_, err = w.Write(buf.Bytes())
+	if err != nil {
+		log.Error().Err(err).Msg("error writing heatmap image")
+	}
+}
+
+// Render writes a heatmap of the user's answers to the metric question this
+// year, with days in the user's timezone.
+func (h *Heatmap) Render(ctx context.Context, w io.Writer, teamID, userID string, tz int, o Options) (err error) {
+	ctx, span := tracing.Start(ctx, "heatmap.Render", attribute.String("metric", o.Metric), attribute.String("format", o.Format))
+	defer func(start time.Time) {
+		h.metrics.ObserveRender("heatmap", start, err)
+		tracing.End(span, err)
+	}(time.Now())
+
+	if err := o.Validate(); err != nil {
+		return fmt.Errorf("error drawing heatmap: %w", err)
+	}
+
+	startOfYear := time.Date(time.Now().Year(), 1, 1, 0, 0, 0, 0, time.UTC).Format(mysqlDateFormat)
+	const query = "SELECT * FROM reflections WHERE DATE(`date`) >= ? AND team_id = ? AND user_id = ?"
+	start := time.Now()
+	qctx, qspan := tracing.StartQuery(ctx, "heatmap_reflections", query)
+	rows, err := h.db.QueryxContext(qctx, query, startOfYear, teamID, userID)
+	h.metrics.ObserveQuery("heatmap_reflections", start, err)
+	tracing.End(qspan, err)
+	if err != nil {
+		return fmt.Errorf("error querying for day quality calendar: %w", err)
+	}
 	defer rows.Close()
 
 	counts := make(map[string]int)
@@ -92,16 +151,13 @@ func (h *Heatmap) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		var r reflection.Reflection
 		err := rows.StructScan(&r)
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			log.Error().Err(err).Msg("error scanning row")
-			return
+			return fmt.Errorf("error scanning row: %w", err)
 		}
-		counts[r.Date.Add(-1*time.Duration(rp.TZ)*time.Hour).Format(mysqlDateFormat)] = r.WorkDayQuality.IntVal()
+		v := reflection.NumberPrefixedEnum(r.ValueForQuestion(o.Metric))
+		counts[r.Date.Add(-1*time.Duration(tz)*time.Hour).Format(mysqlDateFormat)] = v.IntVal()
 	}
 	if err = rows.Err(); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		log.Error().Err(err).Msg("error getting calendar data")
-		return
+		return fmt.Errorf("error getting calendar data: %w", err)
 	}
 
 	conf := charts.HeatmapConfig{
@@ -116,7 +172,7 @@ func (h *Heatmap) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		TextColor:          color.RGBA{0, 0, 0, 255},
 		BorderColor:        color.RGBA{200, 200, 200, 255},
 		Locale:             "en_US",
-		Format:             "png",
+		Format:             o.Format,
 		FontFace:           h.defaultFontFace,
 		ShowWeekdays: map[time.Weekday]bool{
 			time.Monday:    true,
@@ -125,11 +181,7 @@ func (h *Heatmap) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		},
 	}
 
-	w.Header().Set("Content-Type", "image/png")
-	err = charts.WriteHeatmap(conf, w)
-	if err != nil {
-		log.Error().Err(err).Msg("error writing heatmap image")
-	}
+	return charts.WriteHeatmap(conf, w)
 }
 
 const mysqlDateFormat = "2006-01-02"
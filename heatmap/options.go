@@ -0,0 +1,85 @@
+package heatmap
+
+import (
+	"fmt"
+
+	"github.com/jharlap/good-day-app/reflection"
+)
+
+// Resource is the kind of resource heatmap URLs are signed for.
+const Resource = "heatmap"
+
+const (
+	FormatPNG = "png"
+	FormatSVG = "svg"
+)
+
+var contentTypes = map[string]string{
+	FormatPNG: "image/png",
+	FormatSVG: "image/svg+xml",
+}
+
+// Options are how a heatmap is drawn. They are signed into its URL, so
+// whoever it's shared with can't change them.
+type Options struct {
+	// Metric is the field of the question to chart, which must have ordered
+	// options.
+	Metric string
+	// Format is the image format, FormatPNG or FormatSVG.
+	Format string
+}
+
+var DefaultOptions = Options{Metric: "work_day_quality", Format: FormatPNG}
+
+// Validate checks the options can be drawn.
+func (o Options) Validate() error {
+	q, ok := questionFor(o.Metric)
+	if !ok {
+		return fmt.Errorf("unknown metric %q", o.Metric)
+	}
+	if !q.Options.Ordered {
+		return fmt.Errorf("metric %q can't be charted because its answers aren't ordered", o.Metric)
+	}
+	if _, ok := contentTypes[o.Format]; !ok {
+		return fmt.Errorf("unknown format %q", o.Format)
+	}
+	return nil
+}
+
+// signed returns the options to sign, leaving out defaults to keep URLs short.
+func (o Options) signed() map[string]string {
+	m := make(map[string]string)
+	if o.Metric != DefaultOptions.Metric {
+		m["metric"] = o.Metric
+	}
+	if o.Format != DefaultOptions.Format {
+		m["format"] = o.Format
+	}
+	return m
+}
+
+// optionsFromSigned returns the options from a verified URL, with defaults for
+// any left out.
+func optionsFromSigned(m map[string]string) (Options, error) {
+	o := DefaultOptions
+	for k, v := range m {
+		switch k {
+		case "metric":
+			o.Metric = v
+		case "format":
+			o.Format = v
+		default:
+			return Options{}, fmt.Errorf("unknown option %q", k)
+		}
+	}
+	return o, o.Validate()
+}
+
+func questionFor(field string) (reflection.Question, bool) {
+	for _, q := range reflection.Questions {
+		if q.Field == field {
+			return q, true
+		}
+	}
+	return reflection.Question{}, false
+}
@@ -0,0 +1,31 @@
+package heatmap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOptionsRoundTrip(t *testing.T) {
+	for _, o := range []Options{DefaultOptions, {Metric: "stressful_amount", Format: FormatSVG}, {Metric: "meeting_number", Format: FormatPNG}} {
+		got, err := optionsFromSigned(o.signed())
+		require.NoError(t, err)
+		require.Equal(t, o, got)
+	}
+	require.Empty(t, DefaultOptions.signed(), "defaults should be left out of URLs")
+}
+
+func TestOptionsFromSigned(t *testing.T) {
+	tcs := map[string]map[string]string{
+		"unknown option":   {"theme": "dark"},
+		"unknown metric":   {"metric": "created_at"},
+		"unordered metric": {"metric": "most_productive_time"},
+		"unknown format":   {"format": "bmp"},
+	}
+	for name, in := range tcs {
+		t.Run(name, func(t *testing.T) {
+			_, err := optionsFromSigned(in)
+			require.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,345 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jharlap/good-day-app/export"
+	"github.com/jharlap/good-day-app/i18n"
+	"github.com/jharlap/good-day-app/reflection"
+	"github.com/jharlap/good-day-app/store"
+	"github.com/rs/zerolog/log"
+	"github.com/slack-go/slack"
+)
+
+const (
+	homeSectionOverview = "overview"
+	homeSectionHistory  = "history"
+	homeSectionInsights = "insights"
+	homeSectionSettings = "settings"
+
+	// homeHistoryLength is how many recent reflections the history section lists.
+	homeHistoryLength = 10
+)
+
+var homeSections = []string{homeSectionOverview, homeSectionHistory, homeSectionInsights, homeSectionSettings}
+
+// publishHomeView renders the user's home tab and publishes it, so it is
+// current both when the user opens it and after their data changes.
+func (a *App) publishHomeView(ctx context.Context, tid, uid string) error {
+	bb, err := a.renderHomeView(ctx, tid, uid)
+	if err != nil {
+		return fmt.Errorf("error rendering home view: %w", err)
+	}
+
+	v := slack.HomeTabViewRequest{
+		Type:   slack.VTHomeTab,
+		Blocks: bb,
+	}
+	r, err := a.slack.PublishViewContext(ctx, uid, v, "")
+	if err != nil {
+		if r != nil {
+			return fmt.Errorf("error publishing home view: %w: %+v", err, r.ResponseMetadata.Messages)
+		}
+		return fmt.Errorf("error publishing home view: %w", err)
+	}
+	return nil
+}
+
+// refreshHomeView republishes the user's home tab in the background after a
+// change to their reflections, so its images aren't stale.
+func (a *App) refreshHomeView(ctx context.Context, tid, uid string) {
+	a.jobs.Go(ctx, "home-refresh", func(ctx context.Context) {
+		if err := a.publishHomeView(ctx, tid, uid); err != nil {
+			log.Error().Err(err).Str("tid", tid).Str("uid", uid).Msg("error refreshing home view")
+		}
+	})
+}
+
+// cacheBusted makes an image URL unique to this render, since Slack caches
+// images by URL and would otherwise keep showing a chart from before a change.
+func cacheBusted(u string, t time.Time) string {
+	return fmt.Sprintf("%s?v=%d", u, t.UnixNano())
+}
+
+func (a *App) renderHomeView(ctx context.Context, tid, uid string) (slack.Blocks, error) {
+	var bb slack.Blocks
+
+	su, err := a.fetchUser(ctx, tid, uid)
+	if err != nil {
+		return bb, fmt.Errorf("error getting user info for uid %s: %w", uid, err)
+	}
+
+	section := su.Settings.HomeSection
+	bb.BlockSet = append(bb.BlockSet,
+		slack.NewSectionBlock(nil, []*slack.TextBlockObject{slack.NewTextBlockObject(slack.MarkdownType, i18n.T(su.Lang, "home.greeting", su.Name), false, false)}, nil),
+		homeNavigation(su.Lang, section),
+		slack.NewDividerBlock(),
+	)
+
+	var sb []slack.Block
+	switch section {
+	case homeSectionHistory:
+		sb, err = a.renderHomeHistory(ctx, tid, uid, su)
+	case homeSectionInsights:
+		sb, err = a.renderHomeInsights(ctx, tid, uid, su)
+	case homeSectionSettings:
+		sb, err = a.renderHomeSettings(ctx, tid, uid, su)
+	default:
+		sb, err = a.renderHomeOverview(tid, uid, su)
+	}
+	if err != nil {
+		return bb, fmt.Errorf("error rendering %s section for tid %s uid %s: %w", section, tid, uid, err)
+	}
+
+	bb.BlockSet = append(bb.BlockSet, sb...)
+	return bb, nil
+}
+
+func homeNavigation(lang, selected string) slack.Block {
+	var ee []slack.BlockElement
+	for _, s := range homeSections {
+		b := slack.NewButtonBlockElement(homeButtonSectionPrefix+s, s, slack.NewTextBlockObject(slack.PlainTextType, i18n.T(lang, "home.section."+s), false, false))
+		if s == selected || (len(selected) == 0 && s == homeSectionOverview) {
+			b = b.WithStyle(slack.StylePrimary)
+		}
+		ee = append(ee, b)
+	}
+	return slack.NewActionBlock("home-navigation-action-block", ee...)
+}
+
+func (a *App) renderHomeOverview(tid, uid string, su slackUser) ([]slack.Block, error) {
+	var bb []slack.Block
+
+	now := time.Now()
+	hmURL := a.heatmapper.URLForTeamAndUser(tid, uid, su.TZOffset/3600, su.Settings.LinkGeneration)
+	if len(hmURL) == 0 {
+		return bb, fmt.Errorf("error getting heatmap URL for tid %s uid %s", tid, uid)
+	}
+	hmURL = cacheBusted(hmURL, now)
+
+	bb = append(bb, slack.NewImageBlock(hmURL, i18n.T(su.Lang, "home.heatmap"), "", slack.NewTextBlockObject(slack.PlainTextType, i18n.T(su.Lang, "home.heatmap"), false, false)))
+
+	webFormBtn := slack.NewButtonBlockElement(homeButtonReflectInBrowser, "reflect-in-browser-btn", slack.NewTextBlockObject(slack.PlainTextType, i18n.T(su.Lang, "home.button.reflect_web"), false, false))
+	webFormBtn.URL = a.webFormURL(tid, uid, su)
+	dashboardBtn := slack.NewButtonBlockElement(homeButtonOpenDashboard, "open-dashboard-btn", slack.NewTextBlockObject(slack.PlainTextType, i18n.T(su.Lang, "home.button.dashboard"), false, false))
+	dashboardBtn.URL = a.dashboard.URLForTeamAndUser(tid, uid, su.TZOffset/3600, su.Settings.LinkGeneration, su.Lang)
+
+	bb = append(bb, slack.NewActionBlock(
+		"home-start-reflection-action-block",
+		slack.NewButtonBlockElement(homeButtonStartReflection, "start-today-btn", slack.NewTextBlockObject(slack.PlainTextType, i18n.T(su.Lang, "home.button.reflect"), false, false)),
+		webFormBtn,
+		dashboardBtn,
+		slack.NewButtonBlockElement(homeButtonDownloadData, "download-data-btn", slack.NewTextBlockObject(slack.PlainTextType, i18n.T(su.Lang, "home.button.download"), false, false)),
+	))
+
+	repURL := a.reporter.URLForTeamAndUser(tid, uid, su.TZOffset/3600, su.Settings.LinkGeneration)
+	if len(repURL) == 0 {
+		return bb, fmt.Errorf("error getting detailed report URL for tid %s uid %s", tid, uid)
+	}
+	repURL = cacheBusted(repURL, now)
+
+	bb = append(bb, slack.NewImageBlock(repURL, i18n.T(su.Lang, "home.report"), "", slack.NewTextBlockObject(slack.PlainTextType, i18n.T(su.Lang, "home.report"), false, false)))
+
+	return bb, nil
+}
+
+func (a *App) renderHomeHistory(ctx context.Context, tid, uid string, su slackUser) ([]slack.Block, error) {
+	rr, err := a.userRecentReflections(ctx, tid, uid, homeHistoryLength)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rr) == 0 {
+		return []slack.Block{markdownSection(i18n.T(su.Lang, "home.history.empty"))}, nil
+	}
+
+	quality := localizedQuestion(su.Lang, "work_day_quality")
+	feeling := localizedQuestion(su.Lang, "work_day_feeling")
+
+	bb := []slack.Block{markdownSection(i18n.T(su.Lang, "home.history.title", len(rr)))}
+	for _, r := range rr {
+		ts := strconv.FormatInt(r.Date.Unix(), 10)
+
+		summary := fmt.Sprintf("*%s*\n%s · %s", slackDate(r.Date, su.Location), quality.Options.ValueFor(string(r.WorkDayQuality)), feeling.Options.ValueFor(string(r.WorkDayFeeling)))
+		bb = append(bb, markdownSection(summary))
+
+		deleteBtn := slack.NewButtonBlockElement(homeButtonDeleteReflection, ts, slack.NewTextBlockObject(slack.PlainTextType, i18n.T(su.Lang, "home.history.delete"), false, false)).WithStyle(slack.StyleDanger)
+		deleteBtn.Confirm = slack.NewConfirmationBlockObject(
+			slack.NewTextBlockObject(slack.PlainTextType, i18n.T(su.Lang, "home.history.delete_confirm.title"), false, false),
+			slack.NewTextBlockObject(slack.MarkdownType, i18n.T(su.Lang, "home.history.delete_confirm.text", slackDate(r.Date, su.Location)), false, false),
+			slack.NewTextBlockObject(slack.PlainTextType, i18n.T(su.Lang, "home.history.delete"), false, false),
+			slack.NewTextBlockObject(slack.PlainTextType, i18n.T(su.Lang, "button.cancel"), false, false),
+		)
+		bb = append(bb, slack.NewActionBlock(
+			"history-action-block-"+ts,
+			slack.NewButtonBlockElement(confirmationButtonEditReflection, ts, slack.NewTextBlockObject(slack.PlainTextType, i18n.T(su.Lang, "confirm.button.edit"), false, false)),
+			deleteBtn,
+		))
+	}
+
+	return bb, nil
+}
+
+// renderHomeInsights shows the stats of the user's reflections over the last
+// comparisonDays, with their streaks over the last year.
+func (a *App) renderHomeInsights(ctx context.Context, tid, uid string, su slackUser) ([]slack.Block, error) {
+	rr, err := a.userReflectionsSince(ctx, tid, uid, time.Now().AddDate(0, 0, -streakHistoryDays))
+	if err != nil {
+		return nil, err
+	}
+
+	var bb []slack.Block
+	for _, p := range insightsText(summarizeDays(rr, comparisonDays, time.Now(), su.Location), comparisonDays, su.Lang) {
+		bb = append(bb, markdownSection(p))
+	}
+	return bb, nil
+}
+
+func (a *App) renderHomeSettings(ctx context.Context, tid, uid string, su slackUser) ([]slack.Block, error) {
+	auto := slack.NewOptionBlockObject(languageAuto, slack.NewTextBlockObject(slack.PlainTextType, i18n.T(su.Lang, "home.settings.language_auto"), false, false), nil)
+	opts := []*slack.OptionBlockObject{auto}
+	initial := auto
+	for _, l := range i18n.Languages {
+		o := slack.NewOptionBlockObject(l, slack.NewTextBlockObject(slack.PlainTextType, i18n.Names[l], false, false), nil)
+		if su.Settings.Language.Valid && su.Settings.Language.String == l {
+			initial = o
+		}
+		opts = append(opts, o)
+	}
+
+	sel := slack.NewOptionsSelectBlockElement(slack.OptTypeStatic, nil, homeSelectLanguage, opts...)
+	sel.InitialOption = initial
+
+	resetLinksBtn := slack.NewButtonBlockElement(homeButtonResetLinks, "reset-links-btn", slack.NewTextBlockObject(slack.PlainTextType, i18n.T(su.Lang, "home.button.reset_links"), false, false))
+	resetLinksBtn.Confirm = slack.NewConfirmationBlockObject(
+		slack.NewTextBlockObject(slack.PlainTextType, i18n.T(su.Lang, "home.settings.reset_links_confirm.title"), false, false),
+		slack.NewTextBlockObject(slack.MarkdownType, i18n.T(su.Lang, "home.settings.reset_links_confirm.text"), false, false),
+		slack.NewTextBlockObject(slack.PlainTextType, i18n.T(su.Lang, "home.button.reset_links"), false, false),
+		slack.NewTextBlockObject(slack.PlainTextType, i18n.T(su.Lang, "button.cancel"), false, false),
+	)
+
+	bb := []slack.Block{
+		slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, i18n.T(su.Lang, "home.settings.language"), false, false), nil, slack.NewAccessory(sel)),
+		slack.NewContextBlock("", slack.NewTextBlockObject(slack.MarkdownType, i18n.T(su.Lang, "home.settings.timezone", su.Location), false, false)),
+		slack.NewDividerBlock(),
+		markdownSection(i18n.T(su.Lang, "home.settings.data")),
+		slack.NewActionBlock(
+			"home-settings-data-action-block",
+			exportFormatSelect(su.Lang),
+			slack.NewButtonBlockElement(homeButtonDeleteData, "delete-data-btn", slack.NewTextBlockObject(slack.PlainTextType, i18n.T(su.Lang, "home.button.delete"), false, false)).WithStyle(slack.StyleDanger),
+		),
+		markdownSection(i18n.T(su.Lang, "home.settings.links")),
+		slack.NewActionBlock(
+			"home-settings-links-action-block",
+			resetLinksBtn,
+		),
+		slack.NewDividerBlock(),
+	}
+
+	sb, err := a.renderHomeShares(ctx, tid, uid, su)
+	if err != nil {
+		return nil, err
+	}
+	bb = append(bb, sb...)
+
+	tb, err := a.renderHomeTokens(ctx, tid, uid, su)
+	if err != nil {
+		return nil, err
+	}
+	bb = append(append(bb, slack.NewDividerBlock()), tb...)
+
+	wb, err := a.renderHomeWebhooks(ctx, tid, uid, su)
+	if err != nil {
+		return nil, err
+	}
+	return append(append(bb, slack.NewDividerBlock()), wb...), nil
+}
+
+func exportFormatSelect(lang string) *slack.SelectBlockElement {
+	var opts []*slack.OptionBlockObject
+	for _, f := range export.Formats {
+		opts = append(opts, slack.NewOptionBlockObject(f, slack.NewTextBlockObject(slack.PlainTextType, i18n.T(lang, "download.format."+f), false, false), nil))
+	}
+	return slack.NewOptionsSelectBlockElement(slack.OptTypeStatic, slack.NewTextBlockObject(slack.PlainTextType, i18n.T(lang, "home.button.download"), false, false), homeSelectExportFormat, opts...)
+}
+
+// localizedQuestion returns the question for field translated to lang.
+func localizedQuestion(lang, field string) reflection.Question {
+	for _, q := range reflection.Questions {
+		if q.Field == field {
+			return q.Localize(lang)
+		}
+	}
+	return reflection.Question{Field: field}
+}
+
+func markdownSection(text string) *slack.SectionBlock {
+	return slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, text, false, false), nil, nil)
+}
+
+// handleHomeSectionSelected switches the user's home tab to the section
+// named by the action and remembers the choice.
+func (a *App) handleHomeSectionSelected(ctx context.Context, tid, uid, section string) {
+	err := a.saveUserHomeSection(ctx, tid, uid, section)
+	if err != nil {
+		log.Error().Err(err).Str("tid", tid).Str("uid", uid).Msg("error saving home section")
+		return
+	}
+
+	if err := a.publishHomeView(ctx, tid, uid); err != nil {
+		log.Error().Err(err).Str("tid", tid).Str("uid", uid).Msg("error publishing home view")
+	}
+}
+
+func (a *App) handleLanguageSelected(ctx context.Context, tid, uid, lang string) {
+	if lang == languageAuto {
+		lang = ""
+	}
+	err := a.saveUserLanguage(ctx, tid, uid, lang)
+	if err != nil {
+		log.Error().Err(err).Str("tid", tid).Str("uid", uid).Msg("error saving language")
+		return
+	}
+
+	if err := a.publishHomeView(ctx, tid, uid); err != nil {
+		log.Error().Err(err).Str("tid", tid).Str("uid", uid).Msg("error publishing home view")
+	}
+}
+
+// handleDeleteReflection deletes the user's reflection identified by the unix
+// timestamp ts, from the history section of the home tab.
+func (a *App) handleDeleteReflection(ctx context.Context, tid, uid, ts string) {
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		log.Error().Err(err).Str("ts", ts).Msg("error parsing reflection date to delete")
+		return
+	}
+
+	start, end := store.ReflectionRange(time.Unix(sec, 0))
+	_, err = a.deleteUserData(ctx, tid, uid, deletionScopeReflection, start, end)
+	if err != nil {
+		a.reportErrorToUser(ctx, err, tid, uid, i18n.T(a.lookupUser(ctx, tid, uid).Lang, "home.history.delete_failed"))
+		return
+	}
+
+	a.refreshHomeView(ctx, tid, uid)
+}
+
+// handleResetLinks revokes all of the user's chart links, then republishes
+// their home tab with new ones.
+func (a *App) handleResetLinks(ctx context.Context, tid, uid string) {
+	err := a.resetUserLinks(ctx, tid, uid)
+	if err != nil {
+		a.reportErrorToUser(ctx, err, tid, uid, i18n.T(a.lookupUser(ctx, tid, uid).Lang, "links.reset_failed"))
+		return
+	}
+
+	log.Info().Str("tid", tid).Str("uid", uid).Msg("reset chart links")
+	a.messageUser(ctx, tid, uid, i18n.T(a.lookupUser(ctx, tid, uid).Lang, "links.reset_done"))
+	a.refreshHomeView(ctx, tid, uid)
+}
+
+const languageAuto = "auto"
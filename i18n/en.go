@@ -0,0 +1,237 @@
+package i18n
+
+// en holds the English bot messages. English question and option text is
+// defined alongside the questions in the reflection package.
+var en = map[string]string{
+	"slash.reflect":          "Yay! Reflection time!",
+	"modal.title":            "Good Day Tracker",
+	"modal.close":            "Close",
+	"modal.submit":           "Submit",
+	"modal.header":           "Time to think about how the day went. Pick the answers that are closest to how you felt today went, and we'll review for patterns at the end of the week.",
+	"reflection.date":        "Date (UTC)",
+	"reflection.save_failed": "Sorry, I hit a snag and couldn't save your reflection. To make it easier to save, here's your answers: %s",
+	"home.greeting":          "hello *%s*!",
+	"home.heatmap":           "Daily feeling at a glance",
+	"home.report":            "Interruptions and meetings with good days overlay",
+	"home.button.reflect":    "Reflect on Today",
+	"home.button.download":   "Download Reflections Data",
+	"download.failed":        "Sorry, there was an error uploading your data to Slack - please try again in a few minutes.",
+	"download.explanation":   "All your reflections to date are in this file. Dates and times are in your timezone, %s.",
+	"download.empty":         "No reflections found.",
+
+	"reflection.saved":       "Well done! I saved your reflection.",
+	"reflection.updated":     "Got it! I updated your reflection.",
+	"reflection.edit_failed": "Sorry, I couldn't find that reflection to edit.",
+	"modal.edit_header":      "Update your answers for %s.",
+	"confirm.compared":       "Compared with your average over the last 4 weeks",
+	"confirm.streak":         ":fire: You've reflected %d days in a row - keep it up!",
+	"confirm.streak_start":   ":seedling: You've started a new reflection streak!",
+	"confirm.button.edit":    "Edit reflection",
+	"confirm.button.report":  "Open detailed report",
+
+	"compare.work_day_quality.more":         "a better day than usual",
+	"compare.work_day_quality.less":         "a tougher day than usual",
+	"compare.work_other_people_amount.more": "more time with other people than usual",
+	"compare.work_other_people_amount.less": "less time with other people than usual",
+	"compare.help_other_people_amount.more": "more helping others than usual",
+	"compare.help_other_people_amount.less": "less helping others than usual",
+	"compare.interrupted_amount.more":       "more interruptions than usual",
+	"compare.interrupted_amount.less":       "fewer interruptions than usual",
+	"compare.progress_goals_amount.more":    "more progress than usual",
+	"compare.progress_goals_amount.less":    "less progress than usual",
+	"compare.quality_work_amount.more":      "more high-quality work than usual",
+	"compare.quality_work_amount.less":      "less high-quality work than usual",
+	"compare.lot_of_work_amount.more":       "more work than usual",
+	"compare.lot_of_work_amount.less":       "less work than usual",
+	"compare.stressful_amount.more":         "more stressful than usual",
+	"compare.stressful_amount.less":         "less stressful than usual",
+	"compare.breaks_amount.more":            "more breaks than usual",
+	"compare.breaks_amount.less":            "fewer breaks than usual",
+	"compare.meeting_number.more":           "more meetings than usual",
+	"compare.meeting_number.less":           "fewer meetings than usual",
+
+	"button.cancel":                     "Cancel",
+	"home.section.overview":             "Overview",
+	"home.section.history":              "History",
+	"home.section.insights":             "Insights",
+	"home.section.settings":             "Settings",
+	"home.history.title":                "Your last %d reflections:",
+	"home.history.empty":                "You haven't saved any reflections yet.",
+	"home.history.delete":               "Delete",
+	"home.history.delete_confirm.title": "Delete reflection?",
+	"home.history.delete_confirm.text":  "Your reflection from %s will be permanently deleted.",
+	"home.history.delete_failed":        "Sorry, I couldn't delete that reflection - please try again in a few minutes.",
+	"home.settings.language":            "*Language*\nThe language I use for questions and messages.",
+	"home.settings.language_auto":       "Same as Slack",
+	"home.settings.timezone":            "Dates use the timezone from your Slack profile: %s",
+
+	"home.settings.data":         "*Your data*\nDownload a copy of your reflections, or delete some or all of them.",
+	"home.button.delete":         "Delete my data",
+	"delete.title":               "Delete my data",
+	"delete.warning":             "Deleted reflections can't be recovered, so you may want to download your data first.",
+	"delete.scope":               "What should I delete?",
+	"delete.scope.day":           "One day",
+	"delete.scope.range":         "A range of days",
+	"delete.scope.all":           "Everything, including my settings",
+	"delete.from":                "Day, or first day of the range",
+	"delete.to":                  "Last day of the range",
+	"delete.submit":              "Delete",
+	"delete.error.from_required": "Pick a day.",
+	"delete.error.to_required":   "Pick the last day of the range.",
+	"delete.error.range_order":   "The last day can't be before the first day.",
+	"delete.error.scope":         "Pick what to delete.",
+	"delete.done.day":            "Done! Reflections deleted from %s: %d.",
+	"delete.done.range":          "Done! Reflections deleted from %s to %s: %d.",
+	"delete.done.all":            "Done! I deleted all %d of your reflections and your settings, and your old chart links no longer work.",
+	"delete.failed":              "Sorry, I couldn't delete your data - please try again in a few minutes.",
+
+	"export.date":     "Date",
+	"export.time":     "Time",
+	"export.timezone": "Timezone",
+	"export.score":    "%s (score)",
+
+	"download.started":        "I'm preparing your data - I'll send you the file in a moment.",
+	"download.unknown_format": "Sorry, I can't export to %s. Try csv, json, ndjson or zip.",
+	"download.format.csv":     "Spreadsheet (CSV)",
+	"download.format.json":    "JSON",
+	"download.format.ndjson":  "NDJSON (one reflection per line)",
+	"download.format.zip":     "ZIP with charts",
+
+	"import.started":            "I'm reading the file - I'll send you a preview before importing anything.",
+	"import.usage":              "To import reflections, share a CSV file in a direct message with me, or use `/reflect import <file link>`. Admins can add `@user` to import for someone else.",
+	"import.admin_only":         "Sorry, only workspace admins can import reflections for someone else.",
+	"import.failed":             "Sorry, there was an error importing your reflections - please try again in a few minutes.",
+	"import.error.not_csv":      "Sorry, I can only import CSV files.",
+	"import.error.too_large":    "Sorry, that file is too large to import.",
+	"import.error.no_date":      "Sorry, I couldn't find a date column in that file.",
+	"import.error.no_questions": "Sorry, I couldn't find any reflection questions in that file.",
+	"import.preview.title":      "Import preview",
+	"import.preview.summary":    "I found *%d* reflections: *%d* new, *%d* on days that already have a reflection, which will be skipped, and *%d* lines I couldn't read.",
+	"import.preview.for":        "These reflections will be imported for <@%s>.",
+	"import.preview.conflicts":  "*Already reflected on:*",
+	"import.preview.errors":     "*Couldn't read:*",
+	"import.preview.line":       "• Line %d: %v",
+	"import.preview.more":       "• …and %d more",
+	"import.preview.ignored":    "Ignored columns: %s",
+	"import.button.confirm":     "Import %d reflections",
+	"import.done":               "Imported %d reflections. %d rows were skipped.",
+	"import.cancelled":          "Import cancelled - nothing was saved.",
+
+	"home.settings.links":                     "*Chart links*\nLinks to your charts work for 30 days, even outside Slack. If one was shared by mistake, reset your links to stop all of them working, including your shared links.",
+	"home.button.reset_links":                 "Reset my links",
+	"home.settings.reset_links_confirm.title": "Reset your links?",
+	"home.settings.reset_links_confirm.text":  "Every link to your charts I've sent you will stop working, including the ones in past messages, and your shared links will be revoked. Your home tab will get new ones.",
+	"links.reset_done":                        "Done! Your old chart links and shared links no longer work.",
+	"links.reset_failed":                      "Sorry, I couldn't reset your links. Please try again.",
+
+	"share.title":                      "Share a chart",
+	"share.submit":                     "Create link",
+	"share.intro":                      "Make a link to one of your charts for someone outside Slack, like your manager or a coach. Anyone with the link can see the chart until it expires.",
+	"share.name":                       "Name",
+	"share.name.placeholder":           "Who it's for, like \"My coach\"",
+	"share.chart":                      "Chart",
+	"share.chart.heatmap":              "Heatmap",
+	"share.chart.report":               "Detailed report",
+	"share.expiry":                     "Expires after",
+	"share.expiry.1":                   "1 day",
+	"share.expiry.7":                   "7 days",
+	"share.expiry.30":                  "30 days",
+	"share.expiry.90":                  "90 days",
+	"share.max_views":                  "Maximum views",
+	"share.max_views.placeholder":      "Unlimited",
+	"share.error.name":                 "Please give the link a name.",
+	"share.error.chart":                "Please choose a chart.",
+	"share.error.expiry":               "Please choose when the link expires.",
+	"share.error.max_views":            "Please enter a number from 1 to %d, or leave it empty.",
+	"share.error.too_many":             "You already have %d shared links. Revoke some from the home tab first.",
+	"share.failed":                     "Sorry, I couldn't create your link. Please try again.",
+	"share.created":                    "Here's your link for *%s*. It works until %s:\n%s",
+	"share.created_limited":            "Here's your link for *%s*. It works until %s, for %d views:\n%s",
+	"home.shares":                      "*Shared links*\nLinks to your charts you've made with `/reflect share`.",
+	"home.shares.empty":                "You haven't shared any charts. Use `/reflect share` to make a link for someone outside Slack.",
+	"home.shares.expires":              "Expires %s",
+	"home.shares.views":                "%d views",
+	"home.shares.views_limited":        "%d of %d views",
+	"home.shares.revoke":               "Revoke",
+	"home.shares.revoke_confirm.title": "Revoke link?",
+	"home.shares.revoke_confirm.text":  "*%s* will stop working for anyone you've shared it with.",
+	"home.shares.revoke_failed":        "Sorry, I couldn't revoke your link. Please try again.",
+
+	"dashboard.title":       "Your Good Day dashboard",
+	"dashboard.from":        "From",
+	"dashboard.to":          "To",
+	"dashboard.show":        "Show",
+	"dashboard.report":      "Meetings and interruptions",
+	"dashboard.trends":      "Trends",
+	"dashboard.history":     "Your reflections",
+	"dashboard.date":        "Date",
+	"dashboard.empty":       "You have no reflections in this range.",
+	"home.button.dashboard": "Open dashboard",
+
+	"webform.required":        "Please pick an answer.",
+	"webform.invalid":         "That isn't one of the answers. Please pick another.",
+	"webform.stale":           "This page was open for too long. Please submit your answers again.",
+	"webform.save_failed":     "Sorry, I hit a snag and couldn't save your reflection. Please try again.",
+	"webform.saved":           "Well done! I saved your reflection. You can close this page.",
+	"home.button.reflect_web": "Reflect in a browser",
+
+	"token.default_name":               "API token",
+	"token.created":                    "Here's your API token *%s* with %s access. Copy it now, because I won't show it again:\n`%s`\nSend it as a bearer token to %s. The API is described at %s",
+	"token.access.read":                "read-only",
+	"token.access.write":               "read and write",
+	"token.too_many":                   "You already have %d API tokens. Revoke one from the settings section of the home tab first.",
+	"token.name_too_long":              "Token names can be at most %d characters.",
+	"token.failed":                     "Sorry, I hit a snag and couldn't create your API token. Please try again.",
+	"home.tokens":                      "*API tokens*\nTokens for the API you've made with `/reflect token [read|write] [name]`.",
+	"home.tokens.empty":                "You don't have any API tokens. Use `/reflect token` to make one for your notebooks and dashboards.",
+	"home.tokens.created":              "Created %s",
+	"home.tokens.last_used":            "last used %s",
+	"home.tokens.never_used":           "never used",
+	"home.tokens.revoke":               "Revoke",
+	"home.tokens.revoke_confirm.title": "Revoke token?",
+	"home.tokens.revoke_confirm.text":  "Anything using *%s* will stop working.",
+	"home.tokens.revoke_failed":        "Sorry, I couldn't revoke your API token. Please try again.",
+
+	"webhook.usage":                      "Use `/reflect webhook add <https url>` to get your reflections posted to a URL as you save them. Workspace admins can add `team` to get everyone's reflections in the team.",
+	"webhook.scope.user":                 "your reflections",
+	"webhook.scope.team":                 "everyone's reflections in the team",
+	"webhook.created":                    "I'll post %[2]s to %[1]s as they're saved, edited or deleted. Each delivery is signed with this secret in the `%[4]s` header. Copy it now, because I won't show it again:\n`%[3]s`",
+	"webhook.invalid_url":                "Webhooks need a full `https://` URL, without a username or password.",
+	"webhook.too_many":                   "You already have %d webhooks. Remove one from the settings section of the home tab first.",
+	"webhook.team_admin_only":            "Only workspace admins can add webhooks for the whole team.",
+	"webhook.failed":                     "Sorry, I hit a snag and couldn't add your webhook. Please try again.",
+	"home.webhooks":                      "*Webhooks*\nURLs your reflections are posted to, added with `/reflect webhook add <url>`.",
+	"home.webhooks.empty":                "You don't have any webhooks. Use `/reflect webhook add <url>` to get your reflections posted as you save them.",
+	"home.webhooks.created":              "Added %s",
+	"home.webhooks.no_deliveries":        "Nothing delivered yet.",
+	"home.webhooks.delivered":            "✓ `%s` %s delivered",
+	"home.webhooks.queued":               "• `%s` %s waiting to be delivered",
+	"home.webhooks.retrying":             "↻ `%s` %s failed %d times (%s), trying again %s",
+	"home.webhooks.failed":               "✗ `%s` %s gave up after %d attempts (%s)",
+	"home.webhooks.remove":               "Remove",
+	"home.webhooks.remove_confirm.title": "Remove webhook?",
+	"home.webhooks.remove_confirm.text":  "Nothing more will be posted to %s.",
+	"home.webhooks.remove_failed":        "Sorry, I couldn't remove your webhook. Please try again.",
+
+	"insights.empty":                "There are no reflections from the last %d days to learn from yet.",
+	"insights.count":                "You reflected on *%d* days in the last %d days.",
+	"insights.longest_streak":       ":trophy: Your longest streak in the last year was %d days.",
+	"insights.typical":              "*Your most common answers:*",
+	"insights.average":              "(average %.1f of %d)",
+	"insights.weekdays":             "*Your days by weekday:*",
+	"insights.weekday":              "%s: average day %.1f of %d, over %d days",
+	"insights.correlations":         "*What goes with a good day:*",
+	"insights.correlation.more":     "more on better days (%+.2f)",
+	"insights.correlation.less":     "less on better days (%+.2f)",
+	"insights.correlations.none":    "Nothing stands out yet as going with better or worse days.",
+	"insights.correlations.too_few": "Rate at least %d days to see what goes with a good day.",
+	"stats.usage":                   "Use `/reflect stats [days]` to see your stats over the last 1 to %d days.",
+	"stats.failed":                  "Sorry, I hit a snag and couldn't work out your stats. Please try again.",
+	"weekday.monday":                "Monday",
+	"weekday.tuesday":               "Tuesday",
+	"weekday.wednesday":             "Wednesday",
+	"weekday.thursday":              "Thursday",
+	"weekday.friday":                "Friday",
+	"weekday.saturday":              "Saturday",
+	"weekday.sunday":                "Sunday",
+}
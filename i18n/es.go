@@ -0,0 +1,288 @@
+package i18n
+
+var es = map[string]string{
+	"slash.reflect":          "¡Genial! ¡Hora de reflexionar!",
+	"modal.title":            "Registro Buen Día",
+	"modal.close":            "Cerrar",
+	"modal.submit":           "Enviar",
+	"modal.header":           "Es momento de pensar en cómo fue el día. Elige las respuestas que más se acerquen a cómo sentiste que fue hoy, y buscaremos patrones al final de la semana.",
+	"reflection.date":        "Fecha (UTC)",
+	"reflection.save_failed": "Lo siento, tuve un problema y no pude guardar tu reflexión. Para que sea más fácil guardarla, aquí están tus respuestas: %s",
+	"home.greeting":          "¡hola *%s*!",
+	"home.heatmap":           "Cómo te sentiste cada día, de un vistazo",
+	"home.report":            "Interrupciones y reuniones, con los buenos días resaltados",
+	"home.button.reflect":    "Reflexionar sobre hoy",
+	"home.button.download":   "Descargar mis reflexiones",
+	"download.failed":        "Lo siento, hubo un error al subir tus datos a Slack - inténtalo de nuevo en unos minutos.",
+	"download.explanation":   "Todas tus reflexiones hasta la fecha están en este archivo. Las fechas y horas están en tu zona horaria, %s.",
+	"download.empty":         "No se encontraron reflexiones.",
+
+	"reflection.saved":       "¡Bien hecho! Guardé tu reflexión.",
+	"reflection.updated":     "¡Entendido! Actualicé tu reflexión.",
+	"reflection.edit_failed": "Lo siento, no encontré la reflexión para editar.",
+	"modal.edit_header":      "Actualiza tus respuestas del %s.",
+	"confirm.compared":       "Comparado con tu promedio de las últimas 4 semanas",
+	"confirm.streak":         ":fire: Has reflexionado %d días seguidos - ¡sigue así!",
+	"confirm.streak_start":   ":seedling: ¡Empezaste una nueva racha de reflexiones!",
+	"confirm.button.edit":    "Editar reflexión",
+	"confirm.button.report":  "Ver informe detallado",
+
+	"compare.work_day_quality.more":         "un día mejor que de costumbre",
+	"compare.work_day_quality.less":         "un día más difícil que de costumbre",
+	"compare.work_other_people_amount.more": "más tiempo con otras personas que de costumbre",
+	"compare.work_other_people_amount.less": "menos tiempo con otras personas que de costumbre",
+	"compare.help_other_people_amount.more": "más ayuda a otros que de costumbre",
+	"compare.help_other_people_amount.less": "menos ayuda a otros que de costumbre",
+	"compare.interrupted_amount.more":       "más interrupciones que de costumbre",
+	"compare.interrupted_amount.less":       "menos interrupciones que de costumbre",
+	"compare.progress_goals_amount.more":    "más progreso que de costumbre",
+	"compare.progress_goals_amount.less":    "menos progreso que de costumbre",
+	"compare.quality_work_amount.more":      "más trabajo de calidad que de costumbre",
+	"compare.quality_work_amount.less":      "menos trabajo de calidad que de costumbre",
+	"compare.lot_of_work_amount.more":       "más trabajo que de costumbre",
+	"compare.lot_of_work_amount.less":       "menos trabajo que de costumbre",
+	"compare.stressful_amount.more":         "más estresante que de costumbre",
+	"compare.stressful_amount.less":         "menos estresante que de costumbre",
+	"compare.breaks_amount.more":            "más descansos que de costumbre",
+	"compare.breaks_amount.less":            "menos descansos que de costumbre",
+	"compare.meeting_number.more":           "más reuniones que de costumbre",
+	"compare.meeting_number.less":           "menos reuniones que de costumbre",
+
+	"question.work_day_quality":         "¿Cómo fue tu día de trabajo?",
+	"question.work_other_people_amount": "Trabajé con otras personas",
+	"question.help_other_people_amount": "Ayudé a otras personas",
+	"question.interrupted_amount":       "Mi trabajo fue interrumpido",
+	"question.progress_goals_amount":    "Avancé hacia mis objetivos",
+	"question.quality_work_amount":      "Hice un trabajo de alta calidad",
+	"question.lot_of_work_amount":       "Hice mucho trabajo",
+	"question.work_day_feeling":         "¿Qué describe mejor cómo te sientes sobre tu día de trabajo?",
+	"question.stressful_amount":         "Mi día fue estresante",
+	"question.breaks_amount":            "Hoy tomé descansos",
+	"question.meeting_number":           "¿Cuántas reuniones tuviste hoy?",
+	"question.most_productive_time":     "Hoy me sentí más productivo",
+	"question.least_productive_time":    "Hoy me sentí menos productivo",
+
+	"placeholder.quality":       "Elige la más cercana",
+	"option.quality.0-terrible": "Terrible",
+	"option.quality.1-bad":      "Malo",
+	"option.quality.2-ok":       "Normal",
+	"option.quality.3-good":     "Bueno",
+	"option.quality.4-awesome":  "Genial",
+
+	"placeholder.amount":     "Cuánto del día",
+	"option.amount.0-none":   "Nada del día",
+	"option.amount.1-little": "Un poco del día",
+	"option.amount.2-some":   "Parte del día",
+	"option.amount.3-much":   "Gran parte del día",
+	"option.amount.4-most":   "Casi todo o todo el día",
+
+	"placeholder.feeling":      "Elige la más cercana",
+	"option.feeling.0-tense":   "Tenso o nervioso",
+	"option.feeling.1-stress":  "Estresado o molesto",
+	"option.feeling.2-sad":     "Triste o deprimido",
+	"option.feeling.3-bored":   "Aburrido",
+	"option.feeling.4-calm":    "Tranquilo o relajado",
+	"option.feeling.5-serene":  "Sereno o satisfecho",
+	"option.feeling.6-happy":   "Feliz o eufórico",
+	"option.feeling.7-excited": "Entusiasmado o alerta",
+
+	"placeholder.number":   "Cuántas",
+	"option.number.0-none": "0",
+	"option.number.1-one":  "1",
+	"option.number.2-two":  "2",
+	"option.number.3-few":  "3-4",
+	"option.number.4-many": "5 o más",
+
+	"placeholder.time":       "Qué parte del día",
+	"option.time.0-morning":  "Por la mañana (9:00 – 11:00)",
+	"option.time.1-midday":   "A mediodía (11:00 – 13:00)",
+	"option.time.2-earlyAft": "A primera hora de la tarde (13:00 – 15:00)",
+	"option.time.3-lateAft":  "A última hora de la tarde (15:00 – 17:00)",
+	"option.time.4-nonwork":  "Fuera del horario laboral habitual",
+	"option.time.5-equally":  "Por igual durante todo el día",
+
+	"button.cancel":                     "Cancelar",
+	"home.section.overview":             "Resumen",
+	"home.section.history":              "Historial",
+	"home.section.insights":             "Tendencias",
+	"home.section.settings":             "Ajustes",
+	"home.history.title":                "Tus últimas %d reflexiones:",
+	"home.history.empty":                "Todavía no has guardado ninguna reflexión.",
+	"home.history.delete":               "Eliminar",
+	"home.history.delete_confirm.title": "¿Eliminar la reflexión?",
+	"home.history.delete_confirm.text":  "Tu reflexión del %s se eliminará permanentemente.",
+	"home.history.delete_failed":        "Lo siento, no pude eliminar esa reflexión - inténtalo de nuevo en unos minutos.",
+	"home.settings.language":            "*Idioma*\nEl idioma que uso para las preguntas y los mensajes.",
+	"home.settings.language_auto":       "Igual que Slack",
+	"home.settings.timezone":            "Las fechas usan la zona horaria de tu perfil de Slack: %s",
+
+	"home.settings.data":         "*Tus datos*\nDescarga una copia de tus reflexiones, o elimina algunas o todas.",
+	"home.button.delete":         "Eliminar mis datos",
+	"delete.title":               "Eliminar mis datos",
+	"delete.warning":             "Las reflexiones eliminadas no se pueden recuperar, así que quizá quieras descargar tus datos primero.",
+	"delete.scope":               "¿Qué debo eliminar?",
+	"delete.scope.day":           "Un día",
+	"delete.scope.range":         "Un rango de días",
+	"delete.scope.all":           "Todo, incluidos mis ajustes",
+	"delete.from":                "Día, o primer día del rango",
+	"delete.to":                  "Último día del rango",
+	"delete.submit":              "Eliminar",
+	"delete.error.from_required": "Elige un día.",
+	"delete.error.to_required":   "Elige el último día del rango.",
+	"delete.error.range_order":   "El último día no puede ser anterior al primero.",
+	"delete.error.scope":         "Elige qué quieres eliminar.",
+	"delete.done.day":            "¡Listo! Reflexiones eliminadas del %s: %d.",
+	"delete.done.range":          "¡Listo! Reflexiones eliminadas del %s al %s: %d.",
+	"delete.done.all":            "¡Listo! Eliminé tus %d reflexiones y tus ajustes, y tus enlaces anteriores a los gráficos ya no funcionan.",
+	"delete.failed":              "Lo siento, no pude eliminar tus datos - inténtalo de nuevo en unos minutos.",
+
+	"export.date":     "Fecha",
+	"export.time":     "Hora",
+	"export.timezone": "Zona horaria",
+	"export.score":    "%s (puntuación)",
+
+	"download.started":        "Estoy preparando tus datos - te enviaré el archivo en un momento.",
+	"download.unknown_format": "Lo siento, no puedo exportar a %s. Prueba csv, json, ndjson o zip.",
+	"download.format.csv":     "Hoja de cálculo (CSV)",
+	"download.format.json":    "JSON",
+	"download.format.ndjson":  "NDJSON (una reflexión por línea)",
+	"download.format.zip":     "ZIP con gráficos",
+
+	"import.started":            "Estoy leyendo el archivo - te enviaré una vista previa antes de importar nada.",
+	"import.usage":              "Para importar reflexiones, comparte un archivo CSV en un mensaje directo conmigo, o usa `/reflect import <enlace del archivo>`. Los administradores pueden añadir `@usuario` para importar para otra persona.",
+	"import.admin_only":         "Lo siento, solo los administradores del espacio de trabajo pueden importar reflexiones para otra persona.",
+	"import.failed":             "Lo siento, hubo un error al importar tus reflexiones - inténtalo de nuevo en unos minutos.",
+	"import.error.not_csv":      "Lo siento, solo puedo importar archivos CSV.",
+	"import.error.too_large":    "Lo siento, ese archivo es demasiado grande para importarlo.",
+	"import.error.no_date":      "Lo siento, no encontré una columna de fecha en ese archivo.",
+	"import.error.no_questions": "Lo siento, no encontré ninguna pregunta de reflexión en ese archivo.",
+	"import.preview.title":      "Vista previa de la importación",
+	"import.preview.summary":    "Encontré *%d* reflexiones: *%d* nuevas, *%d* en días que ya tienen una reflexión, que se omitirán, y *%d* líneas que no pude leer.",
+	"import.preview.for":        "Estas reflexiones se importarán para <@%s>.",
+	"import.preview.conflicts":  "*Días con reflexión:*",
+	"import.preview.errors":     "*No se pudo leer:*",
+	"import.preview.line":       "• Línea %d: %v",
+	"import.preview.more":       "• …y %d más",
+	"import.preview.ignored":    "Columnas ignoradas: %s",
+	"import.button.confirm":     "Importar %d reflexiones",
+	"import.done":               "Se importaron %d reflexiones. Se omitieron %d filas.",
+	"import.cancelled":          "Importación cancelada - no se guardó nada.",
+
+	"home.settings.links":                     "*Enlaces a tus gráficos*\nLos enlaces a tus gráficos funcionan durante 30 días, incluso fuera de Slack. Si compartiste uno por error, restablece tus enlaces para desactivarlos todos, incluidos tus enlaces compartidos.",
+	"home.button.reset_links":                 "Restablecer mis enlaces",
+	"home.settings.reset_links_confirm.title": "¿Restablecer tus enlaces?",
+	"home.settings.reset_links_confirm.text":  "Todos los enlaces a tus gráficos que te envié dejarán de funcionar, incluidos los de mensajes anteriores, y tus enlaces compartidos se revocarán. Tu pestaña de inicio recibirá otros nuevos.",
+	"links.reset_done":                        "¡Listo! Tus enlaces anteriores a los gráficos y tus enlaces compartidos ya no funcionan.",
+	"links.reset_failed":                      "Lo siento, no pude restablecer tus enlaces. Inténtalo de nuevo.",
+
+	"share.title":                      "Compartir un gráfico",
+	"share.submit":                     "Crear enlace",
+	"share.intro":                      "Crea un enlace a uno de tus gráficos para alguien fuera de Slack, como tu responsable o un coach. Cualquiera con el enlace puede ver el gráfico hasta que caduque.",
+	"share.name":                       "Nombre",
+	"share.name.placeholder":           "Para quién es, como \"Mi coach\"",
+	"share.chart":                      "Gráfico",
+	"share.chart.heatmap":              "Mapa de calor",
+	"share.chart.report":               "Informe detallado",
+	"share.expiry":                     "Caduca después de",
+	"share.expiry.1":                   "1 día",
+	"share.expiry.7":                   "7 días",
+	"share.expiry.30":                  "30 días",
+	"share.expiry.90":                  "90 días",
+	"share.max_views":                  "Número máximo de vistas",
+	"share.max_views.placeholder":      "Ilimitado",
+	"share.error.name":                 "Ponle un nombre al enlace.",
+	"share.error.chart":                "Elige un gráfico.",
+	"share.error.expiry":               "Elige cuándo caduca el enlace.",
+	"share.error.max_views":            "Escribe un número del 1 al %d, o déjalo vacío.",
+	"share.error.too_many":             "Ya tienes %d enlaces compartidos. Revoca algunos desde la pestaña de inicio primero.",
+	"share.failed":                     "Lo siento, no pude crear tu enlace. Inténtalo de nuevo.",
+	"share.created":                    "Aquí tienes tu enlace para *%s*. Funciona hasta el %s:\n%s",
+	"share.created_limited":            "Aquí tienes tu enlace para *%s*. Funciona hasta el %s, para %d vistas:\n%s",
+	"home.shares":                      "*Enlaces compartidos*\nLos enlaces a tus gráficos creados con `/reflect share`.",
+	"home.shares.empty":                "No has compartido ningún gráfico. Usa `/reflect share` para crear un enlace para alguien fuera de Slack.",
+	"home.shares.expires":              "Caduca el %s",
+	"home.shares.views":                "%d vistas",
+	"home.shares.views_limited":        "%d de %d vistas",
+	"home.shares.revoke":               "Revocar",
+	"home.shares.revoke_confirm.title": "¿Revocar el enlace?",
+	"home.shares.revoke_confirm.text":  "*%s* dejará de funcionar para todas las personas con quienes lo compartiste.",
+	"home.shares.revoke_failed":        "Lo siento, no pude revocar tu enlace. Inténtalo de nuevo.",
+
+	"dashboard.title":       "Tu panel de Good Day",
+	"dashboard.from":        "Desde",
+	"dashboard.to":          "Hasta",
+	"dashboard.show":        "Mostrar",
+	"dashboard.report":      "Reuniones e interrupciones",
+	"dashboard.trends":      "Tendencias",
+	"dashboard.history":     "Tus reflexiones",
+	"dashboard.date":        "Fecha",
+	"dashboard.empty":       "No tienes reflexiones en este periodo.",
+	"home.button.dashboard": "Abrir el panel",
+
+	"webform.required":        "Elige una respuesta.",
+	"webform.invalid":         "Esa no es una de las respuestas. Elige otra.",
+	"webform.stale":           "Esta página estuvo abierta demasiado tiempo. Envía tus respuestas de nuevo.",
+	"webform.save_failed":     "Lo siento, tuve un problema y no pude guardar tu reflexión. Inténtalo de nuevo.",
+	"webform.saved":           "¡Bien hecho! Guardé tu reflexión. Puedes cerrar esta página.",
+	"home.button.reflect_web": "Reflexionar en el navegador",
+
+	"token.default_name":               "Token de API",
+	"token.created":                    "Aquí está tu token de API *%s* con acceso %s. Cópialo ahora, porque no lo volveré a mostrar:\n`%s`\nEnvíalo como token bearer a %s. La API se describe en %s",
+	"token.access.read":                "de solo lectura",
+	"token.access.write":               "de lectura y escritura",
+	"token.too_many":                   "Ya tienes %d tokens de API. Revoca uno en la sección de ajustes de la pestaña de inicio primero.",
+	"token.name_too_long":              "Los nombres de token pueden tener como máximo %d caracteres.",
+	"token.failed":                     "Lo siento, tuve un problema y no pude crear tu token de API. Inténtalo de nuevo.",
+	"home.tokens":                      "*Tokens de API*\nLos tokens para la API que creaste con `/reflect token [read|write] [nombre]`.",
+	"home.tokens.empty":                "No tienes tokens de API. Usa `/reflect token` para crear uno para tus notebooks y paneles.",
+	"home.tokens.created":              "Creado %s",
+	"home.tokens.last_used":            "usado por última vez %s",
+	"home.tokens.never_used":           "nunca usado",
+	"home.tokens.revoke":               "Revocar",
+	"home.tokens.revoke_confirm.title": "¿Revocar el token?",
+	"home.tokens.revoke_confirm.text":  "Todo lo que use *%s* dejará de funcionar.",
+	"home.tokens.revoke_failed":        "Lo siento, no pude revocar tu token de API. Inténtalo de nuevo.",
+
+	"webhook.usage":                      "Usa `/reflect webhook add <url https>` para que tus reflexiones se envíen a una URL en cuanto las guardes. Los administradores del espacio de trabajo pueden añadir `team` para recibir las reflexiones de todo el equipo.",
+	"webhook.scope.user":                 "tus reflexiones",
+	"webhook.scope.team":                 "las reflexiones de todo el equipo",
+	"webhook.created":                    "Enviaré %[2]s a %[1]s en cuanto se guarden, editen o borren. Cada envío se firma con este secreto en la cabecera `%[4]s`. Cópialo ahora, porque no lo volveré a mostrar:\n`%[3]s`",
+	"webhook.invalid_url":                "Los webhooks necesitan una URL `https://` completa, sin usuario ni contraseña.",
+	"webhook.too_many":                   "Ya tienes %d webhooks. Quita uno en la sección de ajustes de la pestaña de inicio primero.",
+	"webhook.team_admin_only":            "Solo los administradores del espacio de trabajo pueden añadir webhooks para todo el equipo.",
+	"webhook.failed":                     "Lo siento, tuve un problema y no pude añadir tu webhook. Inténtalo de nuevo.",
+	"home.webhooks":                      "*Webhooks*\nLas URL a las que se envían tus reflexiones, añadidas con `/reflect webhook add <url>`.",
+	"home.webhooks.empty":                "No tienes webhooks. Usa `/reflect webhook add <url>` para que tus reflexiones se envíen en cuanto las guardes.",
+	"home.webhooks.created":              "Añadido %s",
+	"home.webhooks.no_deliveries":        "Todavía no se ha enviado nada.",
+	"home.webhooks.delivered":            "✓ `%s` %s enviado",
+	"home.webhooks.queued":               "• `%s` %s esperando para enviarse",
+	"home.webhooks.retrying":             "↻ `%s` %s falló %d veces (%s), se reintentará %s",
+	"home.webhooks.failed":               "✗ `%s` %s abandonado tras %d intentos (%s)",
+	"home.webhooks.remove":               "Quitar",
+	"home.webhooks.remove_confirm.title": "¿Quitar el webhook?",
+	"home.webhooks.remove_confirm.text":  "No se enviará nada más a %s.",
+	"home.webhooks.remove_failed":        "Lo siento, no pude quitar tu webhook. Inténtalo de nuevo.",
+
+	"insights.empty":                "Todavía no hay reflexiones de los últimos %d días de las que aprender.",
+	"insights.count":                "Reflexionaste *%d* días en los últimos %d días.",
+	"insights.longest_streak":       ":trophy: Tu racha más larga del último año fue de %d días.",
+	"insights.typical":              "*Tus respuestas más frecuentes:*",
+	"insights.average":              "(promedio %.1f de %d)",
+	"insights.weekdays":             "*Tus días según el día de la semana:*",
+	"insights.weekday":              "%s: día promedio %.1f de %d, en %d días",
+	"insights.correlations":         "*Lo que acompaña a un buen día:*",
+	"insights.correlation.more":     "más en los mejores días (%+.2f)",
+	"insights.correlation.less":     "menos en los mejores días (%+.2f)",
+	"insights.correlations.none":    "Todavía nada destaca como asociado a días mejores o peores.",
+	"insights.correlations.too_few": "Valora al menos %d días para ver lo que acompaña a un buen día.",
+	"stats.usage":                   "Usa `/reflect stats [días]` para ver tus estadísticas de los últimos 1 a %d días.",
+	"stats.failed":                  "Lo siento, tuve un problema y no pude calcular tus estadísticas. Inténtalo de nuevo.",
+	"weekday.monday":                "Lunes",
+	"weekday.tuesday":               "Martes",
+	"weekday.wednesday":             "Miércoles",
+	"weekday.thursday":              "Jueves",
+	"weekday.friday":                "Viernes",
+	"weekday.saturday":              "Sábado",
+	"weekday.sunday":                "Domingo",
+}
@@ -0,0 +1,288 @@
+package i18n
+
+var fr = map[string]string{
+	"slash.reflect":          "Youpi ! C'est l'heure de la réflexion !",
+	"modal.title":            "Suivi Bonne Journée",
+	"modal.close":            "Fermer",
+	"modal.submit":           "Envoyer",
+	"modal.header":           "C'est le moment de repenser à votre journée. Choisissez les réponses les plus proches de ce que vous avez ressenti aujourd'hui, et nous chercherons des tendances en fin de semaine.",
+	"reflection.date":        "Date (UTC)",
+	"reflection.save_failed": "Désolé, j'ai rencontré un problème et n'ai pas pu enregistrer votre réflexion. Pour vous faciliter la tâche, voici vos réponses : %s",
+	"home.greeting":          "bonjour *%s* !",
+	"home.heatmap":           "Votre ressenti quotidien en un coup d'œil",
+	"home.report":            "Interruptions et réunions, bonnes journées en surbrillance",
+	"home.button.reflect":    "Réfléchir à aujourd'hui",
+	"home.button.download":   "Télécharger mes réflexions",
+	"download.failed":        "Désolé, une erreur s'est produite lors de l'envoi de vos données à Slack - veuillez réessayer dans quelques minutes.",
+	"download.explanation":   "Toutes vos réflexions à ce jour sont dans ce fichier. Les dates et heures sont dans votre fuseau horaire, %s.",
+	"download.empty":         "Aucune réflexion trouvée.",
+
+	"reflection.saved":       "Bravo ! J'ai enregistré votre réflexion.",
+	"reflection.updated":     "C'est noté ! J'ai mis à jour votre réflexion.",
+	"reflection.edit_failed": "Désolé, je n'ai pas trouvé la réflexion à modifier.",
+	"modal.edit_header":      "Modifiez vos réponses pour le %s.",
+	"confirm.compared":       "Comparé à votre moyenne des 4 dernières semaines",
+	"confirm.streak":         ":fire: Vous avez fait votre réflexion %d jours d'affilée - continuez comme ça !",
+	"confirm.streak_start":   ":seedling: Vous avez commencé une nouvelle série de réflexions !",
+	"confirm.button.edit":    "Modifier la réflexion",
+	"confirm.button.report":  "Voir le rapport détaillé",
+
+	"compare.work_day_quality.more":         "une meilleure journée que d'habitude",
+	"compare.work_day_quality.less":         "une journée plus difficile que d'habitude",
+	"compare.work_other_people_amount.more": "plus de temps avec les autres que d'habitude",
+	"compare.work_other_people_amount.less": "moins de temps avec les autres que d'habitude",
+	"compare.help_other_people_amount.more": "plus d'aide aux autres que d'habitude",
+	"compare.help_other_people_amount.less": "moins d'aide aux autres que d'habitude",
+	"compare.interrupted_amount.more":       "plus d'interruptions que d'habitude",
+	"compare.interrupted_amount.less":       "moins d'interruptions que d'habitude",
+	"compare.progress_goals_amount.more":    "plus de progrès que d'habitude",
+	"compare.progress_goals_amount.less":    "moins de progrès que d'habitude",
+	"compare.quality_work_amount.more":      "plus de travail de qualité que d'habitude",
+	"compare.quality_work_amount.less":      "moins de travail de qualité que d'habitude",
+	"compare.lot_of_work_amount.more":       "plus de travail que d'habitude",
+	"compare.lot_of_work_amount.less":       "moins de travail que d'habitude",
+	"compare.stressful_amount.more":         "plus stressante que d'habitude",
+	"compare.stressful_amount.less":         "moins stressante que d'habitude",
+	"compare.breaks_amount.more":            "plus de pauses que d'habitude",
+	"compare.breaks_amount.less":            "moins de pauses que d'habitude",
+	"compare.meeting_number.more":           "plus de réunions que d'habitude",
+	"compare.meeting_number.less":           "moins de réunions que d'habitude",
+
+	"question.work_day_quality":         "Comment s'est passée votre journée de travail ?",
+	"question.work_other_people_amount": "J'ai travaillé avec d'autres personnes",
+	"question.help_other_people_amount": "J'ai aidé d'autres personnes",
+	"question.interrupted_amount":       "Mon travail a été interrompu",
+	"question.progress_goals_amount":    "J'ai progressé vers mes objectifs",
+	"question.quality_work_amount":      "J'ai fait du travail de qualité",
+	"question.lot_of_work_amount":       "J'ai beaucoup travaillé",
+	"question.work_day_feeling":         "Qu'est-ce qui décrit le mieux votre ressenti sur votre journée de travail ?",
+	"question.stressful_amount":         "Ma journée a été stressante",
+	"question.breaks_amount":            "J'ai pris des pauses aujourd'hui",
+	"question.meeting_number":           "Combien de réunions avez-vous eues aujourd'hui ?",
+	"question.most_productive_time":     "Aujourd'hui, je me suis senti le plus productif",
+	"question.least_productive_time":    "Aujourd'hui, je me suis senti le moins productif",
+
+	"placeholder.quality":       "Choisissez la plus proche",
+	"option.quality.0-terrible": "Terrible",
+	"option.quality.1-bad":      "Mauvaise",
+	"option.quality.2-ok":       "Correcte",
+	"option.quality.3-good":     "Bonne",
+	"option.quality.4-awesome":  "Excellente",
+
+	"placeholder.amount":     "Quelle part de la journée",
+	"option.amount.0-none":   "Pas du tout",
+	"option.amount.1-little": "Un peu de la journée",
+	"option.amount.2-some":   "Une partie de la journée",
+	"option.amount.3-much":   "Une bonne partie de la journée",
+	"option.amount.4-most":   "Presque toute ou toute la journée",
+
+	"placeholder.feeling":      "Choisissez la plus proche",
+	"option.feeling.0-tense":   "Tendu ou nerveux",
+	"option.feeling.1-stress":  "Stressé ou contrarié",
+	"option.feeling.2-sad":     "Triste ou déprimé",
+	"option.feeling.3-bored":   "Ennuyé",
+	"option.feeling.4-calm":    "Calme ou détendu",
+	"option.feeling.5-serene":  "Serein ou satisfait",
+	"option.feeling.6-happy":   "Heureux ou ravi",
+	"option.feeling.7-excited": "Enthousiaste ou alerte",
+
+	"placeholder.number":   "Combien",
+	"option.number.0-none": "0",
+	"option.number.1-one":  "1",
+	"option.number.2-two":  "2",
+	"option.number.3-few":  "3-4",
+	"option.number.4-many": "5 ou plus",
+
+	"placeholder.time":       "Quel moment de la journée",
+	"option.time.0-morning":  "Le matin (9:00 – 11:00)",
+	"option.time.1-midday":   "En milieu de journée (11:00 – 13:00)",
+	"option.time.2-earlyAft": "En début d'après-midi (13:00 – 15:00)",
+	"option.time.3-lateAft":  "En fin d'après-midi (15:00 – 17:00)",
+	"option.time.4-nonwork":  "En dehors des heures de travail habituelles",
+	"option.time.5-equally":  "De façon égale tout au long de la journée",
+
+	"button.cancel":                     "Annuler",
+	"home.section.overview":             "Aperçu",
+	"home.section.history":              "Historique",
+	"home.section.insights":             "Tendances",
+	"home.section.settings":             "Paramètres",
+	"home.history.title":                "Vos %d dernières réflexions :",
+	"home.history.empty":                "Vous n'avez encore enregistré aucune réflexion.",
+	"home.history.delete":               "Supprimer",
+	"home.history.delete_confirm.title": "Supprimer la réflexion ?",
+	"home.history.delete_confirm.text":  "Votre réflexion du %s sera définitivement supprimée.",
+	"home.history.delete_failed":        "Désolé, je n'ai pas pu supprimer cette réflexion - veuillez réessayer dans quelques minutes.",
+	"home.settings.language":            "*Langue*\nLa langue que j'utilise pour les questions et les messages.",
+	"home.settings.language_auto":       "Comme Slack",
+	"home.settings.timezone":            "Les dates utilisent le fuseau horaire de votre profil Slack : %s",
+
+	"home.settings.data":         "*Vos données*\nTéléchargez une copie de vos réflexions, ou supprimez-en une partie ou la totalité.",
+	"home.button.delete":         "Supprimer mes données",
+	"delete.title":               "Supprimer mes données",
+	"delete.warning":             "Les réflexions supprimées ne peuvent pas être récupérées, vous voudrez peut-être d'abord télécharger vos données.",
+	"delete.scope":               "Que dois-je supprimer ?",
+	"delete.scope.day":           "Un jour",
+	"delete.scope.range":         "Une période",
+	"delete.scope.all":           "Tout, y compris mes paramètres",
+	"delete.from":                "Jour, ou premier jour de la période",
+	"delete.to":                  "Dernier jour de la période",
+	"delete.submit":              "Supprimer",
+	"delete.error.from_required": "Choisissez un jour.",
+	"delete.error.to_required":   "Choisissez le dernier jour de la période.",
+	"delete.error.range_order":   "Le dernier jour ne peut pas précéder le premier jour.",
+	"delete.error.scope":         "Choisissez ce que vous voulez supprimer.",
+	"delete.done.day":            "C'est fait ! Réflexions supprimées du %s : %d.",
+	"delete.done.range":          "C'est fait ! Réflexions supprimées du %s au %s : %d.",
+	"delete.done.all":            "C'est fait ! J'ai supprimé vos %d réflexions et vos paramètres, et vos anciens liens vers vos graphiques ne fonctionnent plus.",
+	"delete.failed":              "Désolé, je n'ai pas pu supprimer vos données - veuillez réessayer dans quelques minutes.",
+
+	"export.date":     "Date",
+	"export.time":     "Heure",
+	"export.timezone": "Fuseau horaire",
+	"export.score":    "%s (score)",
+
+	"download.started":        "Je prépare vos données - je vous envoie le fichier dans un instant.",
+	"download.unknown_format": "Désolé, je ne peux pas exporter en %s. Essayez csv, json, ndjson ou zip.",
+	"download.format.csv":     "Tableur (CSV)",
+	"download.format.json":    "JSON",
+	"download.format.ndjson":  "NDJSON (une réflexion par ligne)",
+	"download.format.zip":     "ZIP avec graphiques",
+
+	"import.started":            "Je lis le fichier - je vous enverrai un aperçu avant d'importer quoi que ce soit.",
+	"import.usage":              "Pour importer des réflexions, partagez un fichier CSV dans un message direct avec moi, ou utilisez `/reflect import <lien du fichier>`. Les administrateurs peuvent ajouter `@utilisateur` pour importer pour quelqu'un d'autre.",
+	"import.admin_only":         "Désolé, seuls les administrateurs de l'espace de travail peuvent importer des réflexions pour quelqu'un d'autre.",
+	"import.failed":             "Désolé, une erreur s'est produite lors de l'import de vos réflexions - veuillez réessayer dans quelques minutes.",
+	"import.error.not_csv":      "Désolé, je ne peux importer que des fichiers CSV.",
+	"import.error.too_large":    "Désolé, ce fichier est trop volumineux pour être importé.",
+	"import.error.no_date":      "Désolé, je n'ai pas trouvé de colonne de date dans ce fichier.",
+	"import.error.no_questions": "Désolé, je n'ai trouvé aucune question de réflexion dans ce fichier.",
+	"import.preview.title":      "Aperçu de l'import",
+	"import.preview.summary":    "J'ai trouvé *%d* réflexions : *%d* nouvelles, *%d* sur des jours qui ont déjà une réflexion, qui seront ignorées, et *%d* lignes que je n'ai pas pu lire.",
+	"import.preview.for":        "Ces réflexions seront importées pour <@%s>.",
+	"import.preview.conflicts":  "*Réflexion déjà enregistrée :*",
+	"import.preview.errors":     "*Illisible :*",
+	"import.preview.line":       "• Ligne %d : %v",
+	"import.preview.more":       "• …et %d de plus",
+	"import.preview.ignored":    "Colonnes ignorées : %s",
+	"import.button.confirm":     "Importer %d réflexions",
+	"import.done":               "%d réflexions importées. %d lignes ont été ignorées.",
+	"import.cancelled":          "Import annulé - rien n'a été enregistré.",
+
+	"home.settings.links":                     "*Liens vers vos graphiques*\nLes liens vers vos graphiques fonctionnent pendant 30 jours, même en dehors de Slack. Si l'un d'eux a été partagé par erreur, réinitialisez vos liens pour tous les désactiver, y compris vos liens partagés.",
+	"home.button.reset_links":                 "Réinitialiser mes liens",
+	"home.settings.reset_links_confirm.title": "Réinitialiser vos liens ?",
+	"home.settings.reset_links_confirm.text":  "Tous les liens vers vos graphiques que je vous ai envoyés cesseront de fonctionner, y compris ceux des anciens messages, et vos liens partagés seront révoqués. Votre onglet d'accueil en recevra de nouveaux.",
+	"links.reset_done":                        "C'est fait ! Vos anciens liens vers vos graphiques et vos liens partagés ne fonctionnent plus.",
+	"links.reset_failed":                      "Désolé, je n'ai pas pu réinitialiser vos liens. Veuillez réessayer.",
+
+	"share.title":                      "Partager un graphique",
+	"share.submit":                     "Créer le lien",
+	"share.intro":                      "Créez un lien vers l'un de vos graphiques pour quelqu'un en dehors de Slack, comme votre responsable ou un coach. Toute personne disposant du lien peut voir le graphique jusqu'à son expiration.",
+	"share.name":                       "Nom",
+	"share.name.placeholder":           "Pour qui, par exemple « Mon coach »",
+	"share.chart":                      "Graphique",
+	"share.chart.heatmap":              "Carte de chaleur",
+	"share.chart.report":               "Rapport détaillé",
+	"share.expiry":                     "Expire après",
+	"share.expiry.1":                   "1 jour",
+	"share.expiry.7":                   "7 jours",
+	"share.expiry.30":                  "30 jours",
+	"share.expiry.90":                  "90 jours",
+	"share.max_views":                  "Nombre maximal de vues",
+	"share.max_views.placeholder":      "Illimité",
+	"share.error.name":                 "Veuillez donner un nom au lien.",
+	"share.error.chart":                "Veuillez choisir un graphique.",
+	"share.error.expiry":               "Veuillez choisir quand le lien expire.",
+	"share.error.max_views":            "Veuillez saisir un nombre de 1 à %d, ou laisser vide.",
+	"share.error.too_many":             "Vous avez déjà %d liens partagés. Révoquez-en d'abord depuis l'onglet d'accueil.",
+	"share.failed":                     "Désolé, je n'ai pas pu créer votre lien. Veuillez réessayer.",
+	"share.created":                    "Voici votre lien pour *%s*. Il fonctionne jusqu'au %s :\n%s",
+	"share.created_limited":            "Voici votre lien pour *%s*. Il fonctionne jusqu'au %s, pour %d vues :\n%s",
+	"home.shares":                      "*Liens partagés*\nLes liens vers vos graphiques créés avec `/reflect share`.",
+	"home.shares.empty":                "Vous n'avez partagé aucun graphique. Utilisez `/reflect share` pour créer un lien pour quelqu'un en dehors de Slack.",
+	"home.shares.expires":              "Expire le %s",
+	"home.shares.views":                "%d vues",
+	"home.shares.views_limited":        "%d vues sur %d",
+	"home.shares.revoke":               "Révoquer",
+	"home.shares.revoke_confirm.title": "Révoquer le lien ?",
+	"home.shares.revoke_confirm.text":  "*%s* cessera de fonctionner pour toutes les personnes avec qui vous l'avez partagé.",
+	"home.shares.revoke_failed":        "Désolé, je n'ai pas pu révoquer votre lien. Veuillez réessayer.",
+
+	"dashboard.title":       "Votre tableau de bord Good Day",
+	"dashboard.from":        "Du",
+	"dashboard.to":          "Au",
+	"dashboard.show":        "Afficher",
+	"dashboard.report":      "Réunions et interruptions",
+	"dashboard.trends":      "Tendances",
+	"dashboard.history":     "Vos réflexions",
+	"dashboard.date":        "Date",
+	"dashboard.empty":       "Vous n'avez aucune réflexion sur cette période.",
+	"home.button.dashboard": "Ouvrir le tableau de bord",
+
+	"webform.required":        "Veuillez choisir une réponse.",
+	"webform.invalid":         "Ce n'est pas l'une des réponses. Veuillez en choisir une autre.",
+	"webform.stale":           "Cette page est restée ouverte trop longtemps. Veuillez envoyer vos réponses à nouveau.",
+	"webform.save_failed":     "Désolé, un problème m'a empêché d'enregistrer votre réflexion. Veuillez réessayer.",
+	"webform.saved":           "Bravo ! J'ai enregistré votre réflexion. Vous pouvez fermer cette page.",
+	"home.button.reflect_web": "Réfléchir dans un navigateur",
+
+	"token.default_name":               "Jeton d'API",
+	"token.created":                    "Voici votre jeton d'API *%s* avec un accès %s. Copiez-le maintenant, car je ne le montrerai plus :\n`%s`\nEnvoyez-le comme jeton bearer à %s. L'API est décrite à %s",
+	"token.access.read":                "en lecture seule",
+	"token.access.write":               "en lecture et écriture",
+	"token.too_many":                   "Vous avez déjà %d jetons d'API. Révoquez-en un dans la section des paramètres de l'onglet d'accueil d'abord.",
+	"token.name_too_long":              "Les noms de jeton peuvent contenir au plus %d caractères.",
+	"token.failed":                     "Désolé, un problème m'a empêché de créer votre jeton d'API. Veuillez réessayer.",
+	"home.tokens":                      "*Jetons d'API*\nLes jetons pour l'API que vous avez créés avec `/reflect token [read|write] [nom]`.",
+	"home.tokens.empty":                "Vous n'avez aucun jeton d'API. Utilisez `/reflect token` pour en créer un pour vos notebooks et tableaux de bord.",
+	"home.tokens.created":              "Créé %s",
+	"home.tokens.last_used":            "utilisé pour la dernière fois %s",
+	"home.tokens.never_used":           "jamais utilisé",
+	"home.tokens.revoke":               "Révoquer",
+	"home.tokens.revoke_confirm.title": "Révoquer le jeton ?",
+	"home.tokens.revoke_confirm.text":  "Tout ce qui utilise *%s* cessera de fonctionner.",
+	"home.tokens.revoke_failed":        "Désolé, je n'ai pas pu révoquer votre jeton d'API. Veuillez réessayer.",
+
+	"webhook.usage":                      "Utilisez `/reflect webhook add <url https>` pour que vos réflexions soient envoyées à une URL dès que vous les enregistrez. Les administrateurs de l'espace de travail peuvent ajouter `team` pour recevoir les réflexions de toute l'équipe.",
+	"webhook.scope.user":                 "vos réflexions",
+	"webhook.scope.team":                 "les réflexions de toute l'équipe",
+	"webhook.created":                    "J'enverrai %[2]s à %[1]s dès qu'elles sont enregistrées, modifiées ou supprimées. Chaque envoi est signé avec ce secret dans l'en-tête `%[4]s`. Copiez-le maintenant, car je ne le montrerai plus :\n`%[3]s`",
+	"webhook.invalid_url":                "Les webhooks ont besoin d'une URL `https://` complète, sans nom d'utilisateur ni mot de passe.",
+	"webhook.too_many":                   "Vous avez déjà %d webhooks. Supprimez-en un dans la section des paramètres de l'onglet d'accueil d'abord.",
+	"webhook.team_admin_only":            "Seuls les administrateurs de l'espace de travail peuvent ajouter des webhooks pour toute l'équipe.",
+	"webhook.failed":                     "Désolé, un problème m'a empêché d'ajouter votre webhook. Veuillez réessayer.",
+	"home.webhooks":                      "*Webhooks*\nLes URL auxquelles vos réflexions sont envoyées, ajoutées avec `/reflect webhook add <url>`.",
+	"home.webhooks.empty":                "Vous n'avez aucun webhook. Utilisez `/reflect webhook add <url>` pour que vos réflexions soient envoyées dès que vous les enregistrez.",
+	"home.webhooks.created":              "Ajouté %s",
+	"home.webhooks.no_deliveries":        "Rien n'a encore été envoyé.",
+	"home.webhooks.delivered":            "✓ `%s` %s envoyé",
+	"home.webhooks.queued":               "• `%s` %s en attente d'envoi",
+	"home.webhooks.retrying":             "↻ `%s` %s a échoué %d fois (%s), nouvel essai %s",
+	"home.webhooks.failed":               "✗ `%s` %s abandonné après %d essais (%s)",
+	"home.webhooks.remove":               "Supprimer",
+	"home.webhooks.remove_confirm.title": "Supprimer le webhook ?",
+	"home.webhooks.remove_confirm.text":  "Plus rien ne sera envoyé à %s.",
+	"home.webhooks.remove_failed":        "Désolé, je n'ai pas pu supprimer votre webhook. Veuillez réessayer.",
+
+	"insights.empty":                "Il n'y a pas encore de réflexions des %d derniers jours dont tirer des enseignements.",
+	"insights.count":                "Vous avez fait votre réflexion *%d* jours au cours des %d derniers jours.",
+	"insights.longest_streak":       ":trophy: Votre plus longue série de l'année écoulée est de %d jours.",
+	"insights.typical":              "*Vos réponses les plus fréquentes :*",
+	"insights.average":              "(moyenne %.1f sur %d)",
+	"insights.weekdays":             "*Vos journées selon le jour de la semaine :*",
+	"insights.weekday":              "%s : journée moyenne %.1f sur %d, sur %d jours",
+	"insights.correlations":         "*Ce qui va avec une bonne journée :*",
+	"insights.correlation.more":     "plus lors des meilleures journées (%+.2f)",
+	"insights.correlation.less":     "moins lors des meilleures journées (%+.2f)",
+	"insights.correlations.none":    "Rien ne ressort encore comme allant avec les meilleures ou les pires journées.",
+	"insights.correlations.too_few": "Notez au moins %d journées pour voir ce qui va avec une bonne journée.",
+	"stats.usage":                   "Utilisez `/reflect stats [jours]` pour voir vos statistiques des 1 à %d derniers jours.",
+	"stats.failed":                  "Désolé, un problème m'a empêché de calculer vos statistiques. Veuillez réessayer.",
+	"weekday.monday":                "Lundi",
+	"weekday.tuesday":               "Mardi",
+	"weekday.wednesday":             "Mercredi",
+	"weekday.thursday":              "Jeudi",
+	"weekday.friday":                "Vendredi",
+	"weekday.saturday":              "Samedi",
+	"weekday.sunday":                "Dimanche",
+}
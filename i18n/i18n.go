@@ -0,0 +1,68 @@
+// Package i18n holds the translations of user-facing text, selected by the
+// language of the Slack user's locale.
+package i18n
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	English = "en"
+	French  = "fr"
+	Spanish = "es"
+
+	// Default is the language used when a locale is unknown or unsupported.
+	Default = English
+)
+
+// Languages lists the supported languages, default first.
+var Languages = []string{English, French, Spanish}
+
+// Names are the languages' names, in their own language.
+var Names = map[string]string{
+	English: "English",
+	French:  "Français",
+	Spanish: "Español",
+}
+
+var catalog = map[string]map[string]string{
+	English: en,
+	French:  fr,
+	Spanish: es,
+}
+
+// FromLocale returns the supported language for a Slack locale such as
+// "fr-FR", or Default if the locale's language is not supported.
+func FromLocale(locale string) string {
+	lang := strings.ToLower(locale)
+	if i := strings.IndexAny(lang, "-_"); i >= 0 {
+		lang = lang[:i]
+	}
+	if _, ok := catalog[lang]; ok {
+		return lang
+	}
+	return Default
+}
+
+// Lookup returns the translation of key in lang, if there is one.
+func Lookup(lang, key string) (string, bool) {
+	s, ok := catalog[lang][key]
+	return s, ok
+}
+
+// T returns the translation of key in lang formatted with args, falling back
+// to the default language and then to the key itself.
+func T(lang, key string, args ...interface{}) string {
+	s, ok := Lookup(lang, key)
+	if !ok {
+		s, ok = Lookup(Default, key)
+	}
+	if !ok {
+		s = key
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(s, args...)
+	}
+	return s
+}
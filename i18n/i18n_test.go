@@ -0,0 +1,43 @@
+package i18n
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromLocale(t *testing.T) {
+	tcs := map[string]string{
+		"":      English,
+		"en-US": English,
+		"fr-FR": French,
+		"fr-CA": French,
+		"es-LA": Spanish,
+		"es_ES": Spanish,
+		"ja-JP": English,
+		"FR":    French,
+	}
+
+	for in, ex := range tcs {
+		t.Run(in, func(t *testing.T) {
+			require.Equal(t, ex, FromLocale(in), "language mismatch")
+		})
+	}
+}
+
+func TestT(t *testing.T) {
+	require.Equal(t, "Fermer", T(French, "modal.close"), "translation should be used")
+	require.Equal(t, "hello *bob*!", T("xx", "home.greeting", "bob"), "unknown language should fall back to default")
+	require.Equal(t, "no.such.key", T(French, "no.such.key"), "missing key should fall back to key")
+}
+
+func TestCatalogsComplete(t *testing.T) {
+	for _, lang := range Languages {
+		t.Run(lang, func(t *testing.T) {
+			for k := range en {
+				_, ok := Lookup(lang, k)
+				require.True(t, ok, "missing translation for %s", k)
+			}
+		})
+	}
+}
@@ -0,0 +1,393 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jharlap/good-day-app/i18n"
+	"github.com/jharlap/good-day-app/importer"
+	"github.com/jharlap/good-day-app/metrics"
+	"github.com/jharlap/good-day-app/reflection"
+	"github.com/jharlap/good-day-app/webhook"
+	"github.com/rs/zerolog/log"
+	"github.com/slack-go/slack"
+)
+
+const (
+	// maxImportFileSize is the largest CSV file we download, several times
+	// bigger than years of daily reflections.
+	maxImportFileSize = 5 << 20
+	// importPreviewLength is how many conflicts and errors the preview lists.
+	importPreviewLength = 10
+	// importBatchSize is how many reflections are inserted per statement.
+	importBatchSize = 500
+)
+
+// importRequest identifies a CSV file and whose reflections it holds. It is
+// the value of the preview's confirm button, so the file is downloaded and
+// checked again when the import is confirmed.
+type importRequest struct {
+	FileID string `json:"f"`
+	UserID string `json:"u"`
+}
+
+// fileSharedEvent is the file_shared event, which slackevents doesn't parse.
+type fileSharedEvent struct {
+	TeamID         string `json:"team_id"`
+	Authorizations []struct {
+		UserID string `json:"user_id"`
+		IsBot  bool   `json:"is_bot"`
+	} `json:"authorizations"`
+	Event struct {
+		FileID    string `json:"file_id"`
+		UserID    string `json:"user_id"`
+		ChannelID string `json:"channel_id"`
+	} `json:"event"`
+}
+
+// handleFileShared previews importing a file a user shared in their DM with us.
+func (a *App) handleFileShared(ctx context.Context, body []byte) {
+	var ev fileSharedEvent
+	if err := json.Unmarshal(body, &ev); err != nil {
+		log.Debug().Err(err).Str("body", string(body)).Msg("error unmarshaling file_shared event")
+		return
+	}
+
+	// only files shared in a DM are meant for us, and we share our own exports there
+	if !strings.HasPrefix(ev.Event.ChannelID, "D") {
+		return
+	}
+	for _, a := range ev.Authorizations {
+		if a.IsBot && a.UserID == ev.Event.UserID {
+			return
+		}
+	}
+
+	a.jobs.Go(ctx, "import-preview", func(ctx context.Context) {
+		a.previewImport(ctx, ev.TeamID, ev.Event.UserID, importRequest{FileID: ev.Event.FileID, UserID: ev.Event.UserID})
+	})
+}
+
+var (
+	slackFileIDPattern = regexp.MustCompile(`\bF[A-Z0-9]{6,}\b`)
+	slackUserIDPattern = regexp.MustCompile(`<@([UW][A-Z0-9]+)(\|[^>]*)?>`)
+)
+
+// parseImportCommand reads the file and optional user for an import from the
+// text of `/reflect import <file link> [@user]`.
+func parseImportCommand(text, uid string) (importRequest, bool) {
+	req := importRequest{
+		FileID: slackFileIDPattern.FindString(text),
+		UserID: uid,
+	}
+	if m := slackUserIDPattern.FindStringSubmatch(text); m != nil {
+		req.UserID = m[1]
+	}
+	return req, len(req.FileID) > 0
+}
+
+// canImportFor returns whether the requesting user may import reflections for
+// req.UserID: their own, or anyone's for workspace admins.
+func (a *App) canImportFor(ctx context.Context, tid, requester string, req importRequest) bool {
+	if req.UserID == requester {
+		return true
+	}
+	su, err := a.fetchUser(ctx, tid, requester)
+	if err != nil {
+		log.Error().Err(err).Str("tid", tid).Str("uid", requester).Msg("error checking if user is an admin")
+		return false
+	}
+	return su.IsAdmin
+}
+
+// importPlan is what an import would do.
+type importPlan struct {
+	Result importer.Result
+	// New are the reflections to insert, on days without a reflection.
+	New []reflection.Reflection
+	// Conflicts are rows on days that already have a reflection, which are skipped.
+	Conflicts []importer.Row
+}
+
+// planImport downloads and parses the file, and finds which of its rows
+// conflict with the user's existing reflections.
+func (a *App) planImport(ctx context.Context, tid string, req importRequest, su slackUser) (importPlan, error) {
+	var p importPlan
+
+	f, _, _, err := a.slack.GetFileInfoContext(ctx, req.FileID, 0, 0)
+	if err != nil {
+		return p, fmt.Errorf("error getting import file info: %w", err)
+	}
+	if f.Filetype != "csv" && !strings.HasSuffix(strings.ToLower(f.Name), ".csv") {
+		return p, errImportNotCSV
+	}
+	if f.Size > maxImportFileSize {
+		return p, errImportTooLarge
+	}
+
+	buf := new(bytes.Buffer)
+	if err := a.slack.GetFile(f.URLPrivateDownload, buf); err != nil {
+		return p, fmt.Errorf("error downloading import file: %w", err)
+	}
+
+	p.Result, err = importer.ParseCSV(io.LimitReader(buf, maxImportFileSize), su.Location)
+	if err != nil {
+		return p, err
+	}
+
+	existing := make(map[string]bool)
+	_, err = a.forEachUserReflection(ctx, tid, req.UserID, func(r reflection.Reflection) error {
+		existing[r.Date.In(su.Location).Format(dateFormat)] = true
+		return nil
+	})
+	if err != nil {
+		return p, err
+	}
+
+	for _, row := range p.Result.Rows {
+		r := row.Reflection
+		day := r.Date.In(su.Location).Format(dateFormat)
+		if existing[day] {
+			p.Conflicts = append(p.Conflicts, row)
+			continue
+		}
+		existing[day] = true
+
+		r.TeamID = tid
+		r.UserID = req.UserID
+		p.New = append(p.New, r)
+	}
+	return p, nil
+}
+
+var (
+	errImportNotCSV   = errors.New("import file is not a csv file")
+	errImportTooLarge = errors.New("import file is too large")
+)
+
+// importErrorText explains to the user why their file can't be imported.
+func importErrorText(lang string, err error) string {
+	switch {
+	case errors.Is(err, errImportNotCSV):
+		return i18n.T(lang, "import.error.not_csv")
+	case errors.Is(err, errImportTooLarge):
+		return i18n.T(lang, "import.error.too_large")
+	case errors.Is(err, importer.ErrNoDateColumn):
+		return i18n.T(lang, "import.error.no_date")
+	case errors.Is(err, importer.ErrNoQuestionColumn):
+		return i18n.T(lang, "import.error.no_questions")
+	default:
+		return i18n.T(lang, "import.failed")
+	}
+}
+
+// previewImport DMs the requesting user what importing the file would do, with
+// a button to go ahead. Nothing is saved until they confirm.
+func (a *App) previewImport(ctx context.Context, tid, requester string, req importRequest) {
+	lang := a.lookupUser(ctx, tid, requester).Lang
+	if !a.canImportFor(ctx, tid, requester, req) {
+		a.messageUser(ctx, tid, requester, i18n.T(lang, "import.admin_only"))
+		return
+	}
+
+	p, err := a.planImport(ctx, tid, req, a.lookupUser(ctx, tid, req.UserID))
+	if err != nil {
+		a.reportErrorToUser(ctx, err, tid, requester, importErrorText(lang, err))
+		return
+	}
+
+	title := i18n.T(lang, "import.preview.title")
+	_, _, err = a.slack.PostMessageContext(
+		ctx,
+		requester,
+		slack.MsgOptionText(title, false),
+		slack.MsgOptionBlocks(importPreviewBlocks(lang, title, requester, req, p)...),
+	)
+	if err != nil {
+		log.Error().Err(err).Msgf("error posting import preview to %s", requester)
+	}
+}
+
+func importPreviewBlocks(lang, title, requester string, req importRequest, p importPlan) []slack.Block {
+	summary := i18n.T(lang, "import.preview.summary", len(p.Result.Rows), len(p.New), len(p.Conflicts), len(p.Result.Errors))
+	if req.UserID != requester {
+		summary = fmt.Sprintf("%s\n%s", summary, i18n.T(lang, "import.preview.for", req.UserID))
+	}
+	bb := []slack.Block{
+		slack.NewHeaderBlock(slack.NewTextBlockObject(slack.PlainTextType, title, false, false)),
+		markdownSection(summary),
+	}
+
+	if len(p.Conflicts) > 0 {
+		lines := []string{i18n.T(lang, "import.preview.conflicts")}
+		for i, row := range p.Conflicts {
+			if i == importPreviewLength {
+				lines = append(lines, i18n.T(lang, "import.preview.more", len(p.Conflicts)-i))
+				break
+			}
+			lines = append(lines, i18n.T(lang, "import.preview.line", row.Line, row.Reflection.Date.Format(dateFormat)))
+		}
+		bb = append(bb, markdownSection(strings.Join(lines, "\n")))
+	}
+
+	if len(p.Result.Errors) > 0 {
+		lines := []string{i18n.T(lang, "import.preview.errors")}
+		for i, e := range p.Result.Errors {
+			if i == importPreviewLength {
+				lines = append(lines, i18n.T(lang, "import.preview.more", len(p.Result.Errors)-i))
+				break
+			}
+			lines = append(lines, i18n.T(lang, "import.preview.line", e.Line, e.Err))
+		}
+		bb = append(bb, markdownSection(strings.Join(lines, "\n")))
+	}
+
+	if len(p.Result.Ignored) > 0 {
+		bb = append(bb, slack.NewContextBlock("",
+			slack.NewTextBlockObject(slack.MarkdownType, i18n.T(lang, "import.preview.ignored", strings.Join(p.Result.Ignored, ", ")), false, false),
+		))
+	}
+
+	cancelBtn := slack.NewButtonBlockElement(importButtonCancel, "", slack.NewTextBlockObject(slack.PlainTextType, i18n.T(lang, "button.cancel"), false, false))
+	if len(p.New) == 0 {
+		return append(bb, slack.NewActionBlock("import-action-block", cancelBtn))
+	}
+
+	v, err := json.Marshal(req)
+	if err != nil {
+		log.Error().Err(err).Msg("error encoding import request")
+		return bb
+	}
+	confirmBtn := slack.NewButtonBlockElement(importButtonConfirm, string(v), slack.NewTextBlockObject(slack.PlainTextType, i18n.T(lang, "import.button.confirm", len(p.New)), false, false))
+	confirmBtn.Style = slack.StylePrimary
+	return append(bb, slack.NewActionBlock("import-action-block", confirmBtn, cancelBtn))
+}
+
+// handleImportConfirmed imports the file previewed to the user, checking it
+// again since it may have changed since the preview. It runs in the
+// background, since downloading and inserting can take longer than Slack
+// waits for a response. The preview is only deleted once the import has
+// succeeded, so the user can try again if it fails.
+func (a *App) handleImportConfirmed(ctx context.Context, ic slack.InteractionCallback, value string) {
+	tid, requester := ic.Team.ID, ic.User.ID
+	lang := a.lookupUser(ctx, tid, requester).Lang
+
+	var req importRequest
+	if err := json.Unmarshal([]byte(value), &req); err != nil {
+		a.reportErrorToUser(ctx, fmt.Errorf("error decoding import request: %w", err), tid, requester, i18n.T(lang, "import.failed"))
+		return
+	}
+	if !a.canImportFor(ctx, tid, requester, req) {
+		a.messageUser(ctx, tid, requester, i18n.T(lang, "import.admin_only"))
+		return
+	}
+
+	p, err := a.planImport(ctx, tid, req, a.lookupUser(ctx, tid, req.UserID))
+	if err != nil {
+		a.reportErrorToUser(ctx, err, tid, requester, importErrorText(lang, err))
+		return
+	}
+
+	n, err := a.insertReflections(ctx, p.New)
+	if err != nil {
+		a.reportErrorToUser(ctx, err, tid, requester, i18n.T(lang, "import.failed"))
+		return
+	}
+
+	log.Info().Str("tid", tid).Str("uid", req.UserID).Str("by", requester).Int64("count", n).Msg("imported reflections")
+	if n > 0 {
+		first, last := importedRange(p.New)
+		a.notifyWebhooks(ctx, webhook.ReflectionsImported(tid, req.UserID, first, last, n))
+	}
+	a.removeImportPreview(ctx, ic)
+	a.messageUser(ctx, tid, requester, i18n.T(lang, "import.done", n, len(p.Conflicts)+len(p.Result.Errors)))
+	a.refreshHomeView(ctx, tid, req.UserID)
+}
+
+// importedRange returns when the first and last of the reflections were made.
+func importedRange(rr []reflection.Reflection) (first, last time.Time) {
+	for i, r := range rr {
+		if i == 0 || r.Date.Before(first) {
+			first = r.Date
+		}
+		if r.Date.After(last) {
+			last = r.Date
+		}
+	}
+	return first, last
+}
+
+func (a *App) handleImportCancelled(ctx context.Context, ic slack.InteractionCallback) {
+	a.removeImportPreview(ctx, ic)
+	a.messageUser(ctx, ic.Team.ID, ic.User.ID, i18n.T(a.lookupUser(ctx, ic.Team.ID, ic.User.ID).Lang, "import.cancelled"))
+}
+
+// removeImportPreview deletes the preview message, so its buttons can't be
+// used twice.
+func (a *App) removeImportPreview(ctx context.Context, ic slack.InteractionCallback) {
+	if _, _, err := a.slack.DeleteMessageContext(ctx, ic.Channel.ID, ic.Message.Timestamp); err != nil {
+		log.Debug().Err(err).Str("uid", ic.User.ID).Msg("error deleting import preview")
+	}
+}
+
+// insertReflections saves the reflections in one transaction, so a failed
+// import leaves nothing behind to conflict with trying again.
+func (a *App) insertReflections(ctx context.Context, rr []reflection.Reflection) (_ int64, err error) {
+	defer func(t time.Time) { a.metrics.ObserveQuery("import_reflections", t, err) }(time.Now())
+
+	tx, err := a.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("error starting import transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var n int64
+	for start := 0; start < len(rr); start += importBatchSize {
+		end := start + importBatchSize
+		if end > len(rr) {
+			end = len(rr)
+		}
+
+		// rows are maps so unanswered questions are inserted as NULL
+		batch := make([]map[string]interface{}, end-start)
+		for i, r := range rr[start:end] {
+			row := map[string]interface{}{
+				"team_id": r.TeamID,
+				"user_id": r.UserID,
+				// the date column has no fractional seconds, see store.Save
+				"date": r.Date.Truncate(time.Second),
+			}
+			for _, q := range reflection.Questions {
+				var v interface{}
+				if a := r.ValueForQuestion(q.Field); len(a) > 0 {
+					v = a
+				}
+				row[q.Field] = v
+			}
+			batch[i] = row
+		}
+
+		res, err := tx.NamedExecContext(ctx, "INSERT INTO reflections (team_id, user_id, date, work_day_quality, work_other_people_amount, help_other_people_amount, interrupted_amount, progress_goals_amount, quality_work_amount, lot_of_work_amount, work_day_feeling, stressful_amount, breaks_amount, meeting_number, most_productive_time, least_productive_time) VALUES (:team_id, :user_id, :date, :work_day_quality, :work_other_people_amount, :help_other_people_amount, :interrupted_amount, :progress_goals_amount, :quality_work_amount, :lot_of_work_amount, :work_day_feeling, :stressful_amount, :breaks_amount, :meeting_number, :most_productive_time, :least_productive_time)", batch)
+		if err != nil {
+			return 0, fmt.Errorf("error importing reflections: %w", err)
+		}
+		c, err := res.RowsAffected()
+		if err != nil {
+			return 0, fmt.Errorf("error counting imported reflections: %w", err)
+		}
+		n += c
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("error committing import: %w", err)
+	}
+	a.metrics.ReflectionsSaved(metrics.SourceImported, int(n))
+	return n, nil
+}
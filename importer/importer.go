@@ -0,0 +1,293 @@
+// Package importer reads reflections from CSV files, such as our own exports,
+// exports from before they were localized, or the original Good Day
+// spreadsheet.
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jharlap/good-day-app/i18n"
+	"github.com/jharlap/good-day-app/reflection"
+)
+
+var (
+	ErrNoDateColumn     = errors.New("no date column")
+	ErrNoQuestionColumn = errors.New("no question columns")
+)
+
+// Row is a reflection read from a CSV file. TeamID and UserID are left for
+// the caller to set.
+type Row struct {
+	// Line counts the header as line 1 and each row as one line.
+	Line       int
+	Reflection reflection.Reflection
+}
+
+// RowError is why a line of a CSV file could not be read.
+type RowError struct {
+	Line int
+	Err  error
+}
+
+func (e RowError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Err)
+}
+
+// Result is what was read from a CSV file.
+type Result struct {
+	Rows   []Row
+	Errors []RowError
+
+	// Ignored are the headers of columns that don't match a field.
+	Ignored []string
+}
+
+type columnKind int
+
+const (
+	columnIgnored columnKind = iota
+	columnDate
+	columnTime
+	columnTimezone
+	columnQuestion
+	// columnScore is the score next to an answer in our exports, which is
+	// skipped in favour of the answer.
+	columnScore
+)
+
+type column struct {
+	kind     columnKind
+	question reflection.Question
+}
+
+// ParseCSV reads reflections from a CSV file with a header row. Columns are
+// matched by field name or by question text in any language, and answers by
+// option code, option text in any language, or score. Dates without a
+// timezone column are read in loc.
+func ParseCSV(r io.Reader, loc *time.Location) (Result, error) {
+	var res Result
+
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return res, ErrNoDateColumn
+	} else if err != nil {
+		return res, fmt.Errorf("error reading csv header: %w", err)
+	}
+
+	cols, hasDate, hasQuestion := matchColumns(header)
+	if !hasDate {
+		return res, ErrNoDateColumn
+	}
+	if !hasQuestion {
+		return res, ErrNoQuestionColumn
+	}
+	for i, c := range cols {
+		if c.kind == columnIgnored && len(strings.TrimSpace(header[i])) > 0 {
+			res.Ignored = append(res.Ignored, header[i])
+		}
+	}
+
+	seen := make(map[time.Time]int)
+	line := 1
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			var pe *csv.ParseError
+			if errors.As(err, &pe) {
+				// a malformed line doesn't stop the rest from being read
+				res.Errors = append(res.Errors, RowError{Line: pe.Line, Err: pe.Err})
+				continue
+			}
+			return res, fmt.Errorf("error reading csv: %w", err)
+		}
+		if blank(rec) {
+			continue
+		}
+
+		rf, err := parseRow(cols, rec, loc)
+		if err != nil {
+			res.Errors = append(res.Errors, RowError{Line: line, Err: err})
+			continue
+		}
+
+		// reflections are keyed by date, so only the first of duplicates can be kept
+		if first, ok := seen[rf.Date]; ok {
+			res.Errors = append(res.Errors, RowError{Line: line, Err: fmt.Errorf("same date as line %d", first)})
+			continue
+		}
+		seen[rf.Date] = line
+
+		res.Rows = append(res.Rows, Row{Line: line, Reflection: rf})
+	}
+
+	return res, nil
+}
+
+func matchColumns(header []string) (cols []column, hasDate, hasQuestion bool) {
+	names := map[string]column{
+		"date":      {kind: columnDate},
+		"timestamp": {kind: columnDate},
+		"time":      {kind: columnTime},
+		"timezone":  {kind: columnTimezone},
+	}
+	for _, lang := range i18n.Languages {
+		names[normalize(i18n.T(lang, "export.date"))] = column{kind: columnDate}
+		names[normalize(i18n.T(lang, "export.time"))] = column{kind: columnTime}
+		names[normalize(i18n.T(lang, "export.timezone"))] = column{kind: columnTimezone}
+		for _, q := range reflection.LocalizedQuestions(lang) {
+			names[normalize(q.Text)] = column{kind: columnQuestion, question: q}
+			names[normalize(i18n.T(lang, "export.score", q.Text))] = column{kind: columnScore}
+		}
+	}
+	for _, q := range reflection.Questions {
+		names[normalize(q.Field)] = column{kind: columnQuestion, question: q}
+	}
+
+	cols = make([]column, len(header))
+	for i, h := range header {
+		c := names[normalize(h)]
+		if c.kind == columnDate && hasDate {
+			// keep the first date column, e.g. ignore created_at next to date
+			c = column{}
+		}
+		hasDate = hasDate || c.kind == columnDate
+		hasQuestion = hasQuestion || c.kind == columnQuestion
+		cols[i] = c
+	}
+	return cols, hasDate, hasQuestion
+}
+
+func parseRow(cols []column, rec []string, loc *time.Location) (reflection.Reflection, error) {
+	var rf reflection.Reflection
+	var date, clock string
+	for i, c := range cols {
+		if i >= len(rec) {
+			break
+		}
+		v := strings.TrimSpace(rec[i])
+
+		switch c.kind {
+		case columnDate:
+			date = v
+		case columnTime:
+			clock = v
+		case columnTimezone:
+			if l, err := time.LoadLocation(v); err == nil && len(v) > 0 {
+				loc = l
+			}
+		case columnQuestion:
+			if len(v) == 0 {
+				continue
+			}
+			code, ok := matchOption(c.question.Options, v)
+			if !ok {
+				return rf, fmt.Errorf("%q is not an answer to %q", v, c.question.Text)
+			}
+			rf.SetValueForQuestion(c.question.Field, code)
+		}
+	}
+
+	d, err := parseDate(date, clock, loc)
+	if err != nil {
+		return rf, err
+	}
+	rf.Date = d
+	return rf, nil
+}
+
+var (
+	// zonedLayouts include their own timezone.
+	zonedLayouts = []string{time.RFC3339, "2006-01-02 15:04:05 -0700 MST"}
+	localLayouts = []string{
+		"2006-01-02 15:04:05",
+		"2006-01-02 15:04",
+		"1/2/2006 15:04:05",
+		"1/2/2006 15:04",
+	}
+	dateLayouts = []string{"2006-01-02", "1/2/2006"}
+)
+
+// parseDate reads a date, with the time of day either in the date or in clock.
+// Dates without a time of day are at noon, so they stay on the same day in
+// nearby timezones.
+func parseDate(date, clock string, loc *time.Location) (time.Time, error) {
+	if len(date) == 0 {
+		return time.Time{}, errors.New("missing date")
+	}
+
+	for _, l := range zonedLayouts {
+		if t, err := time.Parse(l, date); err == nil {
+			return t, nil
+		}
+	}
+
+	v := date
+	if len(clock) > 0 {
+		v = date + " " + clock
+	}
+	for _, l := range localLayouts {
+		if t, err := time.ParseInLocation(l, v, loc); err == nil {
+			return t, nil
+		}
+	}
+	for _, l := range dateLayouts {
+		if t, err := time.ParseInLocation(l, date, loc); err == nil {
+			return t.Add(12 * time.Hour), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is not a date", date)
+}
+
+// matchOption finds the option for an answer given as its code, its text in
+// any language, or its score.
+func matchOption(o reflection.OptionSet, v string) (reflection.NumberPrefixedEnum, bool) {
+	n := normalize(v)
+	for _, opt := range o.Options {
+		if normalize(opt.Code) == n {
+			return reflection.NumberPrefixedEnum(opt.Code), true
+		}
+	}
+	for _, lang := range i18n.Languages {
+		for _, opt := range o.Localize(lang).Options {
+			if normalize(opt.Text) == n {
+				return reflection.NumberPrefixedEnum(opt.Code), true
+			}
+		}
+	}
+	if score, err := strconv.Atoi(n); err == nil {
+		for _, opt := range o.Options {
+			code := reflection.NumberPrefixedEnum(opt.Code)
+			if code.IntVal() == score {
+				return code, true
+			}
+		}
+	}
+	return "", false
+}
+
+func normalize(s string) string {
+	return strings.ToLower(strings.TrimSpace(s))
+}
+
+func blank(rec []string) bool {
+	for _, v := range rec {
+		if len(strings.TrimSpace(v)) > 0 {
+			return false
+		}
+	}
+	return true
+}
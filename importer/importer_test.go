@@ -0,0 +1,121 @@
+package importer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jharlap/good-day-app/export"
+	"github.com/jharlap/good-day-app/i18n"
+	"github.com/jharlap/good-day-app/reflection"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCSVRoundTrip(t *testing.T) {
+	loc, err := time.LoadLocation("America/Toronto")
+	require.NoError(t, err, "programmer error: unknown test timezone")
+
+	rr := []reflection.Reflection{
+		{Date: time.Date(2021, 7, 2, 17, 30, 0, 0, loc), WorkDayQuality: "3-good", WorkDayFeeling: "6-happy", MeetingNumber: "3-few"},
+		{Date: time.Date(2021, 7, 5, 9, 0, 0, 0, loc), LeastProductiveTime: "4-nonwork"},
+	}
+
+	for _, lang := range i18n.Languages {
+		t.Run(lang, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			w := export.NewCSV(buf, lang, loc)
+			for _, r := range rr {
+				require.NoError(t, w.Write(r))
+			}
+			require.NoError(t, w.Close())
+
+			res, err := ParseCSV(buf, time.UTC)
+			require.NoError(t, err)
+			require.Empty(t, res.Errors)
+			require.Empty(t, res.Ignored, "score columns should be skipped quietly")
+			require.Len(t, res.Rows, len(rr))
+			for i, r := range rr {
+				got := res.Rows[i].Reflection
+				require.True(t, r.Date.Equal(got.Date), "date mismatch: %s != %s", r.Date, got.Date)
+				for _, q := range reflection.Questions {
+					require.Equal(t, r.ValueForQuestion(q.Field), got.ValueForQuestion(q.Field), "answer mismatch for %s", q.Field)
+				}
+			}
+			require.Equal(t, 2, res.Rows[0].Line)
+		})
+	}
+}
+
+func TestParseCSVLegacyExport(t *testing.T) {
+	in := `team_id,user_id,date,work_day_quality,work_day_feeling,meeting_number,created_at
+T1,U1,2021-06-01T21:04:05Z,2-ok,4-calm,0-none,2021-06-01T21:04:06Z
+T1,U1,2021-06-02T20:00:00Z,,,,2021-06-02T20:00:01Z
+`
+	res, err := ParseCSV(strings.NewReader(in), time.UTC)
+	require.NoError(t, err)
+	require.Empty(t, res.Errors)
+	require.Equal(t, []string{"team_id", "user_id", "created_at"}, res.Ignored)
+	require.Len(t, res.Rows, 2)
+
+	r := res.Rows[0].Reflection
+	require.Equal(t, time.Date(2021, 6, 1, 21, 4, 5, 0, time.UTC), r.Date.UTC())
+	require.EqualValues(t, "2-ok", r.WorkDayQuality)
+	require.EqualValues(t, "4-calm", r.WorkDayFeeling)
+	require.EqualValues(t, "0-none", r.MeetingNumber)
+	require.Empty(t, r.TeamID, "the importing user's ids should be used")
+	require.Empty(t, res.Rows[1].Reflection.WorkDayQuality)
+}
+
+func TestParseCSVSpreadsheet(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Paris")
+	require.NoError(t, err, "programmer error: unknown test timezone")
+
+	in := `Timestamp,How was your work day?,How many meetings did you have today?,My day was stressful
+6/1/2021 17:32:10,good,3-4,2
+6/2/2021,Awful,1,
+6/3/2021 17:00:00,Awesome,1,
+6/3/2021 17:00:00,OK,1,
+not a date,OK,1,
+`
+	res, err := ParseCSV(strings.NewReader(in), loc)
+	require.NoError(t, err)
+	require.Len(t, res.Rows, 2)
+	require.Equal(t, time.Date(2021, 6, 1, 17, 32, 10, 0, loc), res.Rows[0].Reflection.Date)
+	require.EqualValues(t, "3-good", res.Rows[0].Reflection.WorkDayQuality, "labels should match regardless of case")
+	require.EqualValues(t, "3-few", res.Rows[0].Reflection.MeetingNumber)
+	require.EqualValues(t, "2-some", res.Rows[0].Reflection.StressfulAmount, "scores should match option codes")
+	require.Equal(t, 4, res.Rows[1].Line)
+
+	require.Len(t, res.Errors, 3)
+	require.Equal(t, 3, res.Errors[0].Line, "unknown answers should be reported")
+	require.Contains(t, res.Errors[0].Error(), "Awful")
+	require.Equal(t, 5, res.Errors[1].Line, "duplicate dates should be reported")
+	require.Equal(t, 6, res.Errors[2].Line, "bad dates should be reported")
+}
+
+func TestParseCSVDateOnly(t *testing.T) {
+	res, err := ParseCSV(strings.NewReader("date,work_day_quality\n2021-06-01,3\n"), time.UTC)
+	require.NoError(t, err)
+	require.Len(t, res.Rows, 1)
+	require.Equal(t, time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC), res.Rows[0].Reflection.Date)
+}
+
+func TestParseCSVMissingColumns(t *testing.T) {
+	tcs := []struct {
+		name string
+		in   string
+		err  error
+	}{
+		{"empty", "", ErrNoDateColumn},
+		{"no date", "work_day_quality\n2-ok\n", ErrNoDateColumn},
+		{"no questions", "date,notes\n2021-06-01,hi\n", ErrNoQuestionColumn},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseCSV(strings.NewReader(tc.in), time.UTC)
+			require.ErrorIs(t, err, tc.err)
+		})
+	}
+}
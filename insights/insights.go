@@ -0,0 +1,356 @@
+// Package insights computes statistics of a user's reflections: streaks,
+// how they usually answer each question, how their days vary by weekday,
+// and which answers go with better or worse days.
+package insights
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/jharlap/good-day-app/reflection"
+)
+
+const (
+	// QualityField is the question the other questions are correlated with.
+	QualityField = "work_day_quality"
+
+	// MinCorrelationSamples is how many reflections answering both questions
+	// are needed before their correlation means anything.
+	MinCorrelationSamples = 10
+
+	dateFormat = "2006-01-02"
+)
+
+// Summary is the statistics of some reflections.
+type Summary struct {
+	Count int
+	// First and Last are the dates of the oldest and newest reflections,
+	// zero if there are none.
+	First, Last time.Time
+
+	// CurrentStreak and LongestStreak are in days, as counted by
+	// CurrentStreak and LongestStreak.
+	CurrentStreak int
+	LongestStreak int
+
+	// Questions has the stats of every question, in the order they're asked.
+	Questions []Question
+	// Weekdays has the stats of each day of the week, from Monday.
+	Weekdays []Weekday
+	// Correlations has the correlation of each ordered question with
+	// QualityField, for questions with at least MinCorrelationSamples
+	// answers alongside it, strongest first.
+	Correlations []Correlation
+}
+
+// Question is the stats of one question's answers.
+type Question struct {
+	Field    string
+	Answered int
+	// Ordered is true if the question's answers can be averaged.
+	Ordered bool
+	// Average is the mean of the answers' number prefixes, for ordered
+	// questions that were answered.
+	Average float64
+	// Distribution counts each answer's code.
+	Distribution map[string]int
+	// MostCommon is the most common answer's code, or empty if unanswered.
+	MostCommon string
+}
+
+// Weekday is the stats of the reflections on one day of the week.
+type Weekday struct {
+	Weekday time.Weekday
+	Count   int
+	// QualityAnswered and AverageQuality are how many of the reflections
+	// answered QualityField and their average answer.
+	QualityAnswered int
+	AverageQuality  float64
+}
+
+// Correlation is the Spearman rank correlation of a question's answers with
+// QualityField's over N reflections answering both. Rho is from -1, when
+// more of one always goes with less of the other, to 1, when they always
+// rise together.
+type Correlation struct {
+	Field string
+	Rho   float64
+	N     int
+}
+
+// Summarize computes the stats of rr, counting the current streak up to now
+// and days in loc.
+func Summarize(rr []reflection.Reflection, now time.Time, loc *time.Location) Summary {
+	s := Summary{Count: len(rr)}
+
+	dates := make([]time.Time, len(rr))
+	for i, r := range rr {
+		dates[i] = r.Date
+		if s.First.IsZero() || r.Date.Before(s.First) {
+			s.First = r.Date
+		}
+		if r.Date.After(s.Last) {
+			s.Last = r.Date
+		}
+	}
+	s.CurrentStreak = CurrentStreak(dates, now, loc)
+	s.LongestStreak = LongestStreak(dates, loc)
+
+	averages, counts := Averages(rr)
+	for _, q := range reflection.Questions {
+		s.Questions = append(s.Questions, Question{
+			Field:        q.Field,
+			Answered:     counts[q.Field],
+			Ordered:      q.Options.Ordered,
+			Average:      averages[q.Field],
+			Distribution: Distribution(rr, q.Field),
+			MostCommon:   MostCommon(rr, q.Field),
+		})
+	}
+
+	s.Weekdays = Weekdays(rr, loc)
+	s.Correlations = Correlations(rr)
+	return s
+}
+
+// Averages returns the mean answer value and number of answers per question
+// field, ignoring unanswered questions.
+func Averages(rr []reflection.Reflection) (map[string]float64, map[string]int) {
+	sums := make(map[string]int)
+	counts := make(map[string]int)
+	for _, r := range rr {
+		for _, q := range reflection.Questions {
+			v := reflection.NumberPrefixedEnum(r.ValueForQuestion(q.Field))
+			if iv := v.IntVal(); iv >= 0 {
+				sums[q.Field] += iv
+				counts[q.Field]++
+			}
+		}
+	}
+
+	averages := make(map[string]float64)
+	for f, n := range counts {
+		averages[f] = float64(sums[f]) / float64(n)
+	}
+	return averages, counts
+}
+
+// Distribution counts how often each answer was given to field.
+func Distribution(rr []reflection.Reflection, field string) map[string]int {
+	counts := make(map[string]int)
+	for _, r := range rr {
+		if v := r.ValueForQuestion(field); len(v) > 0 {
+			counts[v]++
+		}
+	}
+	return counts
+}
+
+// MostCommon returns the option code most often chosen for field,
+// preferring the lowest code on ties so the result is stable.
+func MostCommon(rr []reflection.Reflection, field string) string {
+	counts := Distribution(rr, field)
+
+	var best string
+	for code, n := range counts {
+		if n > counts[best] || (n == counts[best] && code < best) {
+			best = code
+		}
+	}
+	return best
+}
+
+// CurrentStreak counts consecutive days with a reflection, ending today in
+// loc. Weekends without a reflection don't break the streak, and neither does
+// today if the user hasn't reflected yet.
+func CurrentStreak(dates []time.Time, now time.Time, loc *time.Location) int {
+	days := make(map[string]bool)
+	for _, d := range dates {
+		days[d.In(loc).Format(dateFormat)] = true
+	}
+
+	n := now.In(loc)
+	day := time.Date(n.Year(), n.Month(), n.Day(), 0, 0, 0, 0, loc)
+	if !days[day.Format(dateFormat)] {
+		day = day.AddDate(0, 0, -1)
+	}
+
+	streak := 0
+	for {
+		switch {
+		case days[day.Format(dateFormat)]:
+			streak++
+		case isWeekend(day):
+		default:
+			return streak
+		}
+		day = day.AddDate(0, 0, -1)
+	}
+}
+
+// LongestStreak counts the most consecutive days with a reflection in loc,
+// with weekends not breaking streaks like in CurrentStreak.
+func LongestStreak(dates []time.Time, loc *time.Location) int {
+	if len(dates) == 0 {
+		return 0
+	}
+
+	seen := make(map[string]bool)
+	var days []time.Time
+	for _, d := range dates {
+		l := d.In(loc)
+		day := time.Date(l.Year(), l.Month(), l.Day(), 0, 0, 0, 0, loc)
+		if !seen[day.Format(dateFormat)] {
+			seen[day.Format(dateFormat)] = true
+			days = append(days, day)
+		}
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+
+	longest, streak := 1, 1
+	for i := 1; i < len(days); i++ {
+		if onlyWeekendsBetween(days[i-1], days[i]) {
+			streak++
+		} else {
+			streak = 1
+		}
+		if streak > longest {
+			longest = streak
+		}
+	}
+	return longest
+}
+
+// onlyWeekendsBetween reports whether every day after from and before to is
+// a weekend day.
+func onlyWeekendsBetween(from, to time.Time) bool {
+	for d := from.AddDate(0, 0, 1); d.Before(to); d = d.AddDate(0, 0, 1) {
+		if !isWeekend(d) {
+			return false
+		}
+	}
+	return true
+}
+
+func isWeekend(d time.Time) bool {
+	return d.Weekday() == time.Saturday || d.Weekday() == time.Sunday
+}
+
+// Weekdays returns the stats of the reflections on each day of the week in
+// loc, from Monday.
+func Weekdays(rr []reflection.Reflection, loc *time.Location) []Weekday {
+	ww := make([]Weekday, 7)
+	sums := make([]int, 7)
+	for i := range ww {
+		ww[i].Weekday = time.Weekday((i + 1) % 7)
+	}
+	for _, r := range rr {
+		i := (int(r.Date.In(loc).Weekday()) + 6) % 7
+		ww[i].Count++
+		if v := r.WorkDayQuality.IntVal(); v >= 0 {
+			sums[i] += v
+			ww[i].QualityAnswered++
+		}
+	}
+	for i := range ww {
+		if ww[i].QualityAnswered > 0 {
+			ww[i].AverageQuality = float64(sums[i]) / float64(ww[i].QualityAnswered)
+		}
+	}
+	return ww
+}
+
+// Correlations returns the correlation of each ordered question with
+// QualityField, strongest first. Questions with fewer than
+// MinCorrelationSamples answers alongside QualityField, or whose answers
+// never vary, are left out.
+func Correlations(rr []reflection.Reflection) []Correlation {
+	var cc []Correlation
+	for _, q := range reflection.Questions {
+		if !q.Options.Ordered || q.Field == QualityField {
+			continue
+		}
+
+		var x, y []float64
+		for _, r := range rr {
+			quality := r.WorkDayQuality.IntVal()
+			v := reflection.NumberPrefixedEnum(r.ValueForQuestion(q.Field))
+			if quality < 0 || v.IntVal() < 0 {
+				continue
+			}
+			x = append(x, float64(quality))
+			y = append(y, float64(v.IntVal()))
+		}
+		if len(x) < MinCorrelationSamples {
+			continue
+		}
+		if rho, ok := Spearman(x, y); ok {
+			cc = append(cc, Correlation{Field: q.Field, Rho: rho, N: len(x)})
+		}
+	}
+
+	sort.SliceStable(cc, func(i, j int) bool { return math.Abs(cc[i].Rho) > math.Abs(cc[j].Rho) })
+	return cc
+}
+
+// Spearman returns the Spearman rank correlation of x and y, which must be
+// the same length. It's false if either never varies, since their
+// correlation is then undefined.
+func Spearman(x, y []float64) (float64, bool) {
+	return pearson(ranks(x), ranks(y))
+}
+
+// ranks returns the rank of each value of v from 1, giving tied values the
+// average of the ranks they span.
+func ranks(v []float64) []float64 {
+	idx := make([]int, len(v))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(i, j int) bool { return v[idx[i]] < v[idx[j]] })
+
+	r := make([]float64, len(v))
+	for i := 0; i < len(idx); {
+		j := i
+		for j+1 < len(idx) && v[idx[j+1]] == v[idx[i]] {
+			j++
+		}
+		// positions i..j are tied, so share the mean of ranks i+1..j+1
+		rank := float64(i+j)/2 + 1
+		for k := i; k <= j; k++ {
+			r[idx[k]] = rank
+		}
+		i = j + 1
+	}
+	return r
+}
+
+// pearson returns the Pearson correlation of x and y, or false if either
+// has no variance.
+func pearson(x, y []float64) (float64, bool) {
+	n := float64(len(x))
+	if len(x) == 0 || len(x) != len(y) {
+		return 0, false
+	}
+
+	var mx, my float64
+	for i := range x {
+		mx += x[i]
+		my += y[i]
+	}
+	mx /= n
+	my /= n
+
+	var cov, vx, vy float64
+	for i := range x {
+		dx, dy := x[i]-mx, y[i]-my
+		cov += dx * dy
+		vx += dx * dx
+		vy += dy * dy
+	}
+	if vx == 0 || vy == 0 {
+		return 0, false
+	}
+	return cov / math.Sqrt(vx*vy), true
+}
@@ -0,0 +1,207 @@
+package insights
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/jharlap/good-day-app/reflection"
+	"github.com/stretchr/testify/require"
+)
+
+func day(t *testing.T, s string) time.Time {
+	d, err := time.Parse("2006-01-02 15:04", s)
+	require.NoError(t, err)
+	return d
+}
+
+func TestCurrentStreak(t *testing.T) {
+	// Friday July 2nd 2021
+	now := day(t, "2021-07-02 20:00")
+
+	tcs := map[string]struct {
+		dates []string
+		ex    int
+	}{
+		"none":                     {ex: 0},
+		"today":                    {dates: []string{"2021-07-02 09:00"}, ex: 1},
+		"yesterday, not yet today": {dates: []string{"2021-07-01 09:00"}, ex: 1},
+		"week":                     {dates: []string{"2021-06-28 09:00", "2021-06-29 09:00", "2021-06-30 09:00", "2021-07-01 09:00", "2021-07-02 09:00"}, ex: 5},
+		"over a weekend":           {dates: []string{"2021-06-25 09:00", "2021-06-28 09:00", "2021-06-29 09:00", "2021-06-30 09:00", "2021-07-01 09:00", "2021-07-02 09:00"}, ex: 6},
+		"gap":                      {dates: []string{"2021-06-29 09:00", "2021-07-01 09:00", "2021-07-02 09:00"}, ex: 2},
+		"twice in a day":           {dates: []string{"2021-07-02 09:00", "2021-07-02 17:00"}, ex: 1},
+		"ended two days ago":       {dates: []string{"2021-06-30 09:00"}, ex: 0},
+	}
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			var dd []time.Time
+			for _, s := range tc.dates {
+				dd = append(dd, day(t, s))
+			}
+			require.Equal(t, tc.ex, CurrentStreak(dd, now, time.UTC))
+		})
+	}
+}
+
+func TestCurrentStreakUsesLocation(t *testing.T) {
+	toronto, err := time.LoadLocation("America/Toronto")
+	require.NoError(t, err)
+	// 1am UTC on Saturday is still Friday evening in Toronto
+	now := day(t, "2021-07-03 01:00")
+	dd := []time.Time{day(t, "2021-07-01 15:00"), day(t, "2021-07-03 00:30")}
+	require.Equal(t, 2, CurrentStreak(dd, now, toronto))
+	require.Equal(t, 1, CurrentStreak(dd, now, time.UTC))
+}
+
+func TestLongestStreak(t *testing.T) {
+	tcs := map[string]struct {
+		dates []string
+		ex    int
+	}{
+		"none":           {ex: 0},
+		"one":            {dates: []string{"2021-07-02 09:00"}, ex: 1},
+		"unsorted":       {dates: []string{"2021-07-01 09:00", "2021-06-29 09:00", "2021-06-30 09:00"}, ex: 3},
+		"over a weekend": {dates: []string{"2021-07-01 09:00", "2021-07-02 09:00", "2021-07-05 09:00"}, ex: 3},
+		"on a weekend":   {dates: []string{"2021-07-02 09:00", "2021-07-03 09:00", "2021-07-05 09:00"}, ex: 3},
+		"longest first":  {dates: []string{"2021-06-01 09:00", "2021-06-02 09:00", "2021-06-03 09:00", "2021-06-07 09:00", "2021-06-09 09:00", "2021-06-10 09:00"}, ex: 3},
+		"longest last":   {dates: []string{"2021-06-01 09:00", "2021-06-03 09:00", "2021-06-04 09:00", "2021-06-07 09:00", "2021-06-08 09:00"}, ex: 4},
+		"same day twice": {dates: []string{"2021-06-01 09:00", "2021-06-01 17:00", "2021-06-02 09:00"}, ex: 2},
+	}
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			var dd []time.Time
+			for _, s := range tc.dates {
+				dd = append(dd, day(t, s))
+			}
+			require.Equal(t, tc.ex, LongestStreak(dd, time.UTC))
+		})
+	}
+}
+
+func TestAveragesAndDistribution(t *testing.T) {
+	rr := []reflection.Reflection{
+		{WorkDayQuality: "3-good", MeetingNumber: "1-one"},
+		{WorkDayQuality: "1-bad", MeetingNumber: "1-one"},
+		{WorkDayQuality: "3-good"},
+	}
+	averages, counts := Averages(rr)
+	require.InDelta(t, 7.0/3, averages["work_day_quality"], 1e-9)
+	require.Equal(t, 3, counts["work_day_quality"])
+	require.Equal(t, 2, counts["meeting_number"])
+	require.Zero(t, counts["interrupted_amount"])
+
+	require.Equal(t, map[string]int{"3-good": 2, "1-bad": 1}, Distribution(rr, "work_day_quality"))
+	require.Equal(t, "3-good", MostCommon(rr, "work_day_quality"))
+	require.Equal(t, "", MostCommon(rr, "interrupted_amount"))
+	require.Equal(t, "0-none", MostCommon([]reflection.Reflection{{StressfulAmount: "2-some"}, {StressfulAmount: "0-none"}}, "stressful_amount"), "ties go to the lowest code")
+}
+
+func TestWeekdays(t *testing.T) {
+	rr := []reflection.Reflection{
+		{Date: day(t, "2021-06-28 09:00"), WorkDayQuality: "4-awesome"},
+		{Date: day(t, "2021-07-05 09:00"), WorkDayQuality: "2-ok"},
+		{Date: day(t, "2021-07-02 09:00"), WorkDayQuality: "0-terrible"},
+		{Date: day(t, "2021-07-04 09:00")},
+	}
+	ww := Weekdays(rr, time.UTC)
+	require.Len(t, ww, 7)
+	require.Equal(t, Weekday{Weekday: time.Monday, Count: 2, QualityAnswered: 2, AverageQuality: 3}, ww[0])
+	require.Equal(t, Weekday{Weekday: time.Tuesday}, ww[1])
+	require.Equal(t, Weekday{Weekday: time.Friday, Count: 1, QualityAnswered: 1}, ww[4])
+	require.Equal(t, Weekday{Weekday: time.Sunday, Count: 1}, ww[6])
+}
+
+func TestSpearman(t *testing.T) {
+	tcs := map[string]struct {
+		x, y []float64
+		rho  float64
+		ok   bool
+	}{
+		"perfect":          {x: []float64{1, 2, 3, 4}, y: []float64{10, 20, 30, 40}, rho: 1, ok: true},
+		"monotonic":        {x: []float64{1, 2, 3, 4}, y: []float64{1, 4, 9, 100}, rho: 1, ok: true},
+		"inverse":          {x: []float64{1, 2, 3, 4}, y: []float64{4, 3, 2, 1}, rho: -1, ok: true},
+		"ties":             {x: []float64{1, 2, 2, 3}, y: []float64{1, 2, 3, 4}, rho: 0.9486832980505138, ok: true},
+		"textbook":         {x: []float64{106, 100, 86, 101, 99, 103, 97, 113, 112, 110}, y: []float64{7, 27, 2, 50, 28, 29, 20, 12, 6, 17}, rho: -0.17575757575757575, ok: true},
+		"constant":         {x: []float64{2, 2, 2}, y: []float64{1, 2, 3}},
+		"empty":            {},
+		"different length": {x: []float64{1, 2}, y: []float64{1}},
+	}
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			rho, ok := Spearman(tc.x, tc.y)
+			require.Equal(t, tc.ok, ok)
+			require.InDelta(t, tc.rho, rho, 1e-9)
+		})
+	}
+}
+
+func TestRanks(t *testing.T) {
+	require.Equal(t, []float64{1, 2.5, 2.5, 4}, ranks([]float64{1, 2, 2, 3}))
+	require.Equal(t, []float64{3, 1, 2}, ranks([]float64{9, 1, 5}))
+	require.Equal(t, []float64{2, 2, 2}, ranks([]float64{0, 0, 0}))
+}
+
+func TestCorrelations(t *testing.T) {
+	var rr []reflection.Reflection
+	for i := 0; i < MinCorrelationSamples; i++ {
+		q := reflection.NumberPrefixedEnum([]string{"0-terrible", "1-bad", "2-ok", "3-good", "4-awesome"}[i%5])
+		rr = append(rr, reflection.Reflection{
+			WorkDayQuality: q,
+			// more progress on better days, fewer interruptions
+			ProgressGoalsAmount: reflection.NumberPrefixedEnum([]string{"0-none", "1-little", "2-some", "3-much", "4-most"}[i%5]),
+			InterruptedAmount:   reflection.NumberPrefixedEnum([]string{"4-most", "3-much", "2-some", "1-little", "0-none"}[i%5]),
+			// always the same, so uncorrelated
+			BreaksAmount: "2-some",
+			// unordered, so never correlated
+			WorkDayFeeling: "6-happy",
+		})
+	}
+	// too few answers alongside quality to count
+	rr[0].MeetingNumber = "1-one"
+
+	cc := Correlations(rr)
+	fields := make(map[string]Correlation)
+	for _, c := range cc {
+		fields[c.Field] = c
+	}
+	require.InDelta(t, 1, fields["progress_goals_amount"].Rho, 1e-9)
+	require.InDelta(t, -1, fields["interrupted_amount"].Rho, 1e-9)
+	require.Equal(t, MinCorrelationSamples, fields["interrupted_amount"].N)
+	for _, f := range []string{QualityField, "breaks_amount", "work_day_feeling", "meeting_number"} {
+		require.NotContains(t, fields, f)
+	}
+	for i := 1; i < len(cc); i++ {
+		require.GreaterOrEqual(t, math.Abs(cc[i-1].Rho), math.Abs(cc[i].Rho), "strongest first")
+	}
+
+	require.Empty(t, Correlations(rr[:MinCorrelationSamples-1]))
+}
+
+func TestSummarize(t *testing.T) {
+	now := day(t, "2021-07-02 20:00")
+	rr := []reflection.Reflection{
+		{Date: day(t, "2021-07-01 21:00"), WorkDayQuality: "3-good", WorkDayFeeling: "6-happy"},
+		{Date: day(t, "2021-07-02 21:00"), WorkDayQuality: "0-terrible", MeetingNumber: "4-many"},
+	}
+	s := Summarize(rr, now, time.UTC)
+	require.Equal(t, 2, s.Count)
+	require.Equal(t, rr[0].Date, s.First)
+	require.Equal(t, rr[1].Date, s.Last)
+	require.Equal(t, 2, s.CurrentStreak)
+	require.Equal(t, 2, s.LongestStreak)
+	require.Len(t, s.Questions, len(reflection.Questions))
+	require.Equal(t, Question{Field: QualityField, Answered: 2, Ordered: true, Average: 1.5, Distribution: map[string]int{"3-good": 1, "0-terrible": 1}, MostCommon: "0-terrible"}, s.Questions[0])
+	for _, q := range s.Questions {
+		if q.Field == "work_day_feeling" {
+			require.False(t, q.Ordered)
+			require.Equal(t, "6-happy", q.MostCommon)
+		}
+	}
+	require.Len(t, s.Weekdays, 7)
+	require.Empty(t, s.Correlations)
+
+	empty := Summarize(nil, now, time.UTC)
+	require.Zero(t, empty.Count)
+	require.True(t, empty.First.IsZero())
+	require.Zero(t, empty.LongestStreak)
+}
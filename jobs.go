@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"sync"
+
+	"github.com/jharlap/good-day-app/tracing"
+	"github.com/rs/zerolog/log"
+)
+
+// jobRunner runs work that carries on after a request has been answered,
+// like opening a modal or sending a download, so shutdown can wait for it
+// instead of killing it.
+type jobRunner struct {
+	// ctx is the parent of every job's context, cancelled if shutdown gives
+	// up waiting.
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func newJobRunner() *jobRunner {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &jobRunner{ctx: ctx, cancel: cancel}
+}
+
+// Go runs fn in the background. Its context keeps ctx's trace span, but not
+// its cancellation, since a request's context is done once it's answered.
+func (j *jobRunner) Go(ctx context.Context, name string, fn func(ctx context.Context)) {
+	ctx = tracing.Detach(ctx, j.ctx)
+	j.wg.Add(1)
+	go func() {
+		defer j.wg.Done()
+		defer func() {
+			if p := recover(); p != nil {
+				log.Error().Str("job", name).Interface("panic", p).Msg("background job panicked")
+			}
+		}()
+		fn(ctx)
+	}()
+}
+
+// Wait waits for every job to finish. If ctx is done first, it cancels the
+// jobs that are still running and returns ctx's error.
+func (j *jobRunner) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		j.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		j.cancel()
+		return ctx.Err()
+	}
+}
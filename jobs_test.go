@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobRunnerWaitsForJobs(t *testing.T) {
+	j := newJobRunner()
+
+	// the request's context is done as soon as it's answered
+	reqCtx, cancel := context.WithCancel(context.Background())
+	var done int32
+	j.Go(reqCtx, "slow", func(ctx context.Context) {
+		time.Sleep(50 * time.Millisecond)
+		if ctx.Err() == nil {
+			atomic.StoreInt32(&done, 1)
+		}
+	})
+	cancel()
+
+	require.NoError(t, j.Wait(context.Background()))
+	require.Equal(t, int32(1), atomic.LoadInt32(&done), "the job should finish without seeing the request's cancellation")
+}
+
+func TestJobRunnerCancelsJobsAfterTimeout(t *testing.T) {
+	j := newJobRunner()
+
+	cancelled := make(chan struct{})
+	j.Go(context.Background(), "stuck", func(ctx context.Context) {
+		<-ctx.Done()
+		close(cancelled)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, j.Wait(ctx), context.DeadlineExceeded)
+
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("the job's context wasn't cancelled")
+	}
+}
+
+func TestJobRunnerRecoversPanics(t *testing.T) {
+	j := newJobRunner()
+	j.Go(context.Background(), "panics", func(ctx context.Context) {
+		panic("boom")
+	})
+	require.NoError(t, j.Wait(context.Background()))
+}
@@ -3,99 +3,93 @@ package main
 import (
 	"bytes"
 	"context"
-	"encoding/base64"
-	"encoding/csv"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
 	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
 	_ "embed"
 
 	_ "github.com/go-sql-driver/mysql"
-	"github.com/jharlap/good-day-app/heatmap"
+	"github.com/jharlap/good-day-app/config"
+	"github.com/jharlap/good-day-app/export"
+	"github.com/jharlap/good-day-app/i18n"
+	"github.com/jharlap/good-day-app/metrics"
 	"github.com/jharlap/good-day-app/reflection"
-	"github.com/jharlap/good-day-app/report"
-	"github.com/jharlap/good-day-app/urlsigner"
-	"github.com/jmoiron/sqlx"
+	"github.com/jharlap/good-day-app/store"
+	"github.com/jharlap/good-day-app/tracing"
+	"github.com/jharlap/good-day-app/webhook"
 	"github.com/rs/zerolog/log"
 	"github.com/slack-go/slack"
 	"github.com/slack-go/slack/slackevents"
 )
 
-var (
-	sapi             *slack.Client
-	signingSecret    string
-	db               *sqlx.DB
-	heatmapper       *heatmap.Heatmap
-	detailedReporter *report.InterruptionsMeetingsReport
-)
-
 //go:embed assets/fonts/Sunflower-Medium.ttf
 var defaultFontFaceBytes []byte
 
 func main() {
-	urlSigningKeyB64 := os.Getenv("URL_SIGNING_KEY_BASE64")
-	baseURL := os.Getenv("BASE_URL")
-	chartRendererURL := os.Getenv("RENDER_URL")
-	chartRendererCredsFile := os.Getenv("RENDER_CREDS_FILE")
-	signingSecret = os.Getenv("SLACK_SIGNING_SECRET")
-	sapi = slack.New(os.Getenv("SLACK_BOT_TOKEN"))
-	port := os.Getenv("PORT")
-	if len(port) > 0 {
-		port = fmt.Sprintf(":%s", port)
-	} else {
-		port = ":3000"
+	configFile := flag.String("config", os.Getenv("CONFIG_FILE"), "path to an optional JSON config file, overridden by environment variables")
+	flag.Parse()
+
+	if err := run(*configFile); err != nil {
+		log.Fatal().Err(err).Msg("exiting")
 	}
+	log.Info().Msg("shut down")
+}
 
-	var signer *urlsigner.Engine
-	{
-		key, err := base64.StdEncoding.DecodeString(urlSigningKeyB64)
-		if err != nil {
-			log.Fatal().Err(err).Msg("error decoding url signing key")
-		}
-		signer = urlsigner.New(key)
+// run serves the app until it's sent SIGTERM or interrupted, returning an
+// error if it couldn't start or didn't shut down cleanly.
+func run(configFile string) error {
+	cfg, err := config.Load(configFile)
+	if err != nil {
+		return fmt.Errorf("error loading config: %w", err)
 	}
+	log.Info().Str("config", cfg.Summary()).Msg("loaded config")
 
-	{
-		dsn := os.Getenv("DATABASE_DSN")
-		dsn = dsn + "?parseTime=true"
-		fmt.Println("connecting to db with dsn:", dsn)
-		c, err := sqlx.Open("mysql", dsn)
-		if err != nil {
-			log.Fatal().Err(err).Msg("error connecting to database")
-		}
-		db = c
-		db.SetConnMaxLifetime(time.Minute * 3)
-		db.SetMaxOpenConns(10)
-		db.SetMaxIdleConns(10)
-	}
-
-	heatmapper = heatmap.New(baseURL+"/heatmap/", signer, db, defaultFontFaceBytes)
-	detailedReporter = report.New(baseURL+"/report/", signer, db, chartRendererURL, chartRendererCredsFile)
-
-	http.HandleFunc("/", printBody)
-	http.Handle("/event", verifySecret(http.HandlerFunc(handleEvent)))
-	http.Handle("/interactive", verifySecret(http.HandlerFunc(handleInteractive)))
-	http.Handle("/slash", verifySecret(http.HandlerFunc(handleSlash)))
-	http.Handle("/heatmap/", heatmapper)      // no verifySecret because this is a signed URL
-	http.Handle("/report/", detailedReporter) // no verifySecret because this is a signed URL
-	http.ListenAndServe(port, nil)
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.TracingExporter, cfg.OTLPEndpoint, version)
+	if err != nil {
+		return fmt.Errorf("error setting up tracing: %w", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	app, err := NewApp(cfg)
+	if err != nil {
+		return fmt.Errorf("error creating app: %w", err)
+	}
+	defer app.Close()
+
+	ln, err := net.Listen("tcp", cfg.Addr())
+	if err != nil {
+		return fmt.Errorf("error listening on %s: %w", cfg.Addr(), err)
+	}
+	log.Info().Str("addr", ln.Addr().String()).Msg("listening")
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	return app.Serve(ctx, ln)
 }
 
-func verifySecret(next http.HandlerFunc) http.Handler {
+func (a *App) verifySecret(next http.HandlerFunc) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		body, err := ioutil.ReadAll(r.Body)
-		if err != nil {
+		if isBodyTooLarge(err) {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		} else if err != nil {
 			w.WriteHeader(http.StatusBadRequest)
 			return
 		}
 		defer r.Body.Close()
 
-		sv, err := slack.NewSecretsVerifier(r.Header, signingSecret)
+		sv, err := slack.NewSecretsVerifier(r.Header, a.signingSecret)
 		if err != nil {
 			w.WriteHeader(http.StatusBadRequest)
 			return
@@ -114,32 +108,105 @@ func verifySecret(next http.HandlerFunc) http.Handler {
 	})
 }
 
-func handleSlash(w http.ResponseWriter, r *http.Request) {
+func (a *App) handleSlash(w http.ResponseWriter, r *http.Request) {
 	s, err := slack.SlashCommandParse(r)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
+	ctx := r.Context()
 	switch s.Command {
 	case "/reflect":
-		params := &slack.Msg{Text: "Yay! Reflection time!"}
-		b, err := json.Marshal(params)
-		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			return
-		}
-		w.Header().Set("Content-Type", "application/json")
-		w.Write(b)
+		su := a.lookupUser(ctx, s.TeamID, s.UserID)
+		lang := su.Lang
+
+		switch subcommand(s.Text) {
+		case "delete":
+			a.jobs.Go(ctx, "delete-dialog", func(ctx context.Context) {
+				if err := a.startDeleteDataDialog(ctx, s.TriggerID, lang); err != nil {
+					log.Error().Err(err).Str("uid", s.UserID).Msg("error opening delete data modal")
+				}
+			})
+
+		case "share":
+			a.jobs.Go(ctx, "share-dialog", func(ctx context.Context) {
+				if err := a.startShareDialog(ctx, s.TriggerID, lang); err != nil {
+					log.Error().Err(err).Str("uid", s.UserID).Msg("error opening share modal")
+				}
+			})
+
+		case "token":
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(&slack.Msg{Text: a.handleTokenCommand(ctx, s.TeamID, s.UserID, s.Text, lang)})
+
+		case "stats":
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(&slack.Msg{Text: a.handleStatsCommand(ctx, s.TeamID, s.UserID, s.Text, su)})
+
+		case "webhook":
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(&slack.Msg{Text: a.handleWebhookCommand(ctx, s.TeamID, s.UserID, s.Text, lang)})
+
+		case "export":
+			format := export.FormatCSV
+			if ff := strings.Fields(s.Text); len(ff) > 1 {
+				format = strings.ToLower(ff[1])
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(&slack.Msg{Text: i18n.T(lang, "download.started")})
+
+			a.jobs.Go(ctx, "data-download", func(ctx context.Context) {
+				a.sendDataDownload(ctx, s.TeamID, s.UserID, format)
+			})
+
+		case "import":
+			msg := i18n.T(lang, "import.started")
+			req, ok := parseImportCommand(s.Text, s.UserID)
+			if !ok {
+				msg = i18n.T(lang, "import.usage")
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(&slack.Msg{Text: msg})
+
+			if ok {
+				a.jobs.Go(ctx, "import-preview", func(ctx context.Context) {
+					a.previewImport(ctx, s.TeamID, s.UserID, req)
+				})
+			}
 
-		go startReflectionDialog(s.TriggerID)
+		default:
+			params := &slack.Msg{Text: i18n.T(lang, "slash.reflect")}
+			b, err := json.Marshal(params)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(b)
+
+			a.jobs.Go(ctx, "reflection-dialog", func(ctx context.Context) {
+				if err := a.startReflectionDialog(ctx, s.TriggerID, lang); err != nil {
+					log.Error().Err(err).Str("uid", s.UserID).Msg("error opening reflection modal")
+				}
+			})
+		}
 	default:
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
 }
 
-func handleInteractive(w http.ResponseWriter, r *http.Request) {
+// subcommand returns the first word of a slash command's text, lowercased.
+func subcommand(text string) string {
+	ff := strings.Fields(text)
+	if len(ff) == 0 {
+		return ""
+	}
+	return strings.ToLower(ff[0])
+}
+
+func (a *App) handleInteractive(w http.ResponseWriter, r *http.Request) {
 	err := r.ParseForm()
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
@@ -156,28 +223,78 @@ func handleInteractive(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if ic.Type == slack.InteractionTypeBlockActions && len(ic.ActionCallback.BlockActions) > 0 && ic.ActionCallback.BlockActions[0].ActionID == homeButtonStartReflection {
-		startReflectionDialog(ic.TriggerID)
-	} else if ic.Type == slack.InteractionTypeBlockActions && len(ic.ActionCallback.BlockActions) > 0 && ic.ActionCallback.BlockActions[0].ActionID == homeButtonDownloadData {
-		sendDataDownload(ic.Team.ID, ic.User.ID)
+	if ic.Type == slack.InteractionTypeBlockActions && len(ic.ActionCallback.BlockActions) > 0 {
+		a.handleBlockAction(r.Context(), ic, ic.ActionCallback.BlockActions[0])
 	} else if ic.Type == slack.InteractionTypeViewSubmission && ic.View.CallbackID == reflectionModalCallbackID {
-		handleReflectionModalCallback(ic)
+		a.handleReflectionModalCallback(r.Context(), ic)
+	} else if ic.Type == slack.InteractionTypeViewSubmission && ic.View.CallbackID == deleteDataModalCallbackID {
+		a.handleDeleteDataModalCallback(r.Context(), w, ic)
+	} else if ic.Type == slack.InteractionTypeViewSubmission && ic.View.CallbackID == shareModalCallbackID {
+		a.handleShareModalCallback(r.Context(), w, ic)
 	}
 }
 
-func handleReflectionModalCallback(ic slack.InteractionCallback) {
-	/*
-		for k, v := range ic.View.State.Values {
-			for ik := range v {
-				val := ic.View.State.Values[k][ik].SelectedOption.Value
-				fmt.Println("k:", k, "ik:", ik, "iv.Value:", val)
-			}
+func (a *App) handleBlockAction(ctx context.Context, ic slack.InteractionCallback, ba *slack.BlockAction) {
+	tid, uid := ic.Team.ID, ic.User.ID
+
+	switch {
+	case ba.ActionID == homeButtonStartReflection:
+		a.startReflectionDialog(ctx, ic.TriggerID, a.lookupUser(ctx, tid, uid).Lang)
+	case ba.ActionID == homeButtonDownloadData:
+		a.jobs.Go(ctx, "data-download", func(ctx context.Context) {
+			a.sendDataDownload(ctx, tid, uid, export.FormatCSV)
+		})
+	case ba.ActionID == homeSelectExportFormat:
+		format := ba.SelectedOption.Value
+		a.jobs.Go(ctx, "data-download", func(ctx context.Context) {
+			a.sendDataDownload(ctx, tid, uid, format)
+		})
+	case ba.ActionID == confirmationButtonEditReflection:
+		a.startEditReflectionDialog(ctx, ic.TriggerID, tid, uid, ba.Value)
+	case ba.ActionID == homeButtonDeleteData:
+		a.startDeleteDataDialog(ctx, ic.TriggerID, a.lookupUser(ctx, tid, uid).Lang)
+	case ba.ActionID == homeButtonDeleteReflection:
+		a.handleDeleteReflection(ctx, tid, uid, ba.Value)
+	case ba.ActionID == homeButtonResetLinks:
+		a.handleResetLinks(ctx, tid, uid)
+	case ba.ActionID == homeButtonRevokeShare:
+		a.handleRevokeShare(ctx, tid, uid, ba.Value)
+	case ba.ActionID == homeButtonRevokeToken:
+		a.handleRevokeToken(ctx, tid, uid, ba.Value)
+	case ba.ActionID == homeButtonRemoveWebhook:
+		a.handleRemoveWebhook(ctx, tid, uid, ba.Value)
+	case ba.ActionID == importButtonConfirm:
+		a.jobs.Go(ctx, "import", func(ctx context.Context) {
+			a.handleImportConfirmed(ctx, ic, ba.Value)
+		})
+	case ba.ActionID == importButtonCancel:
+		a.handleImportCancelled(ctx, ic)
+	case ba.ActionID == homeSelectLanguage:
+		a.handleLanguageSelected(ctx, tid, uid, ba.SelectedOption.Value)
+	case strings.HasPrefix(ba.ActionID, homeButtonSectionPrefix):
+		a.handleHomeSectionSelected(ctx, tid, uid, strings.TrimPrefix(ba.ActionID, homeButtonSectionPrefix))
+	}
+}
+
+func (a *App) handleReflectionModalCallback(ctx context.Context, ic slack.InteractionCallback) {
+	su := a.lookupUser(ctx, ic.Team.ID, ic.User.ID)
+
+	// an edit of an existing reflection carries that reflection's date
+	date := time.Now()
+	isEdit := len(ic.View.PrivateMetadata) > 0
+	if isEdit {
+		ts, err := strconv.ParseInt(ic.View.PrivateMetadata, 10, 64)
+		if err != nil {
+			log.Error().Err(err).Str("metadata", ic.View.PrivateMetadata).Msg("error parsing edited reflection date")
+			return
 		}
-	*/
+		date = time.Unix(ts, 0)
+	}
+
 	r := reflection.Reflection{
 		TeamID:                ic.Team.ID,
 		UserID:                ic.User.ID,
-		Date:                  time.Now(),
+		Date:                  date,
 		WorkDayQuality:        selectedOptionValue(ic, "work_day_quality"),
 		WorkOtherPeopleAmount: selectedOptionValue(ic, "work_other_people_amount"),
 		HelpOtherPeopleAmount: selectedOptionValue(ic, "help_other_people_amount"),
@@ -192,41 +309,108 @@ func handleReflectionModalCallback(ic slack.InteractionCallback) {
 		MostProductiveTime:    selectedOptionValue(ic, "most_productive_time"),
 		LeastProductiveTime:   selectedOptionValue(ic, "least_productive_time"),
 	}
-	err := saveReflection(r)
+	source := metrics.SourceSubmitted
+	if isEdit {
+		source = metrics.SourceEdited
+	}
+	err := a.submitReflection(ctx, r, su, source)
 	if err != nil {
-		reportErrorToUser(err, ic.Team.ID, ic.User.ID, fmt.Sprintf("Sorry, I hit a snag and couldn't save your reflection. To make it easier to save, here's your answers: %s", r))
+		a.reportErrorToUser(ctx, err, ic.Team.ID, ic.User.ID, i18n.T(su.Lang, "reflection.save_failed", r.Text(su.Lang)))
 		log.Error().Err(err).Msg("error saving reflection")
-		return
 	}
+}
 
-	messageUser(ic.Team.ID, ic.User.ID, fmt.Sprintf("Well done! I saved your reflection - here is what you said:\n%s", r))
+// submitReflection saves a reflection submitted from source, updating the
+// existing one if it was edited, then confirms it to the user and refreshes
+// their home tab.
+func (a *App) submitReflection(ctx context.Context, r reflection.Reflection, su slackUser, source string) error {
+	isEdit := source == metrics.SourceEdited
+	var err error
+	if isEdit {
+		err = a.reflections.Update(ctx, r)
+	} else {
+		err = a.reflections.Save(ctx, r)
+	}
+	if err != nil {
+		return err
+	}
+	a.metrics.ReflectionsSaved(source, 1)
+
+	a.notifyWebhooks(ctx, webhook.ReflectionSaved(r, isEdit))
+	a.sendReflectionConfirmation(ctx, r, su, isEdit)
+	a.refreshHomeView(ctx, r.TeamID, r.UserID)
+	return nil
 }
 
 func selectedOptionValue(ic slack.InteractionCallback, field string) reflection.NumberPrefixedEnum {
 	return reflection.NumberPrefixedEnum(ic.View.State.Values[field]["select"].SelectedOption.Value)
 }
 
-func saveReflection(r reflection.Reflection) error {
-	_, err := db.Exec("INSERT INTO reflections SET team_id=?, user_id=?, date=?, work_day_quality=?, work_other_people_amount=?, help_other_people_amount=?, interrupted_amount=?, progress_goals_amount=?, quality_work_amount=?, lot_of_work_amount=?, work_day_feeling=?, stressful_amount=?, breaks_amount=?, meeting_number=?, most_productive_time=?, least_productive_time=?", r.TeamID, r.UserID, r.Date, r.WorkDayQuality, r.WorkOtherPeopleAmount, r.HelpOtherPeopleAmount, r.InterruptedAmount, r.ProgressGoalsAmount, r.QualityWorkAmount, r.LotOfWorkAmount, r.WorkDayFeeling, r.StressfulAmount, r.BreaksAmount, r.MeetingNumber, r.MostProductiveTime, r.LeastProductiveTime)
+// countUserReflections returns how many reflections the user has.
+func (a *App) countUserReflections(ctx context.Context, tid, uid string) (int, error) {
+	var n int
+	start := time.Now()
+	err := a.db.GetContext(ctx, &n, "SELECT COUNT(*) FROM reflections WHERE team_id = ? AND user_id = ?", tid, uid)
+	a.metrics.ObserveQuery("count_reflections", start, err)
 	if err != nil {
-		return fmt.Errorf("error saving reflection: %w", err)
+		return 0, fmt.Errorf("error counting reflections: %w", err)
 	}
 
-	return nil
+	return n, nil
+}
+
+// userRecentReflections returns the user's latest n reflections, newest first.
+func (a *App) userRecentReflections(ctx context.Context, tid, uid string, n int) ([]reflection.Reflection, error) {
+	var rr []reflection.Reflection
+	start := time.Now()
+	err := a.db.SelectContext(ctx, &rr, "SELECT * FROM reflections WHERE team_id = ? AND user_id = ? ORDER BY date DESC LIMIT ?", tid, uid, n)
+	a.metrics.ObserveQuery("recent_reflections", start, err)
+	if err != nil {
+		return nil, fmt.Errorf("error querying recent reflections: %w", err)
+	}
+
+	return rr, nil
+}
+
+// userReflectionsSince returns the user's reflections dated at or after since,
+// oldest first.
+func (a *App) userReflectionsSince(ctx context.Context, tid, uid string, since time.Time) ([]reflection.Reflection, error) {
+	var rr []reflection.Reflection
+	start := time.Now()
+	err := a.db.SelectContext(ctx, &rr, "SELECT * FROM reflections WHERE team_id = ? AND user_id = ? AND date >= ? ORDER BY date", tid, uid, since)
+	a.metrics.ObserveQuery("reflections_since", start, err)
+	if err != nil {
+		return nil, fmt.Errorf("error querying reflections: %w", err)
+	}
+
+	return rr, nil
 }
 
-func generateReflectionModal() slack.ModalViewRequest {
-	// Create a ModalViewRequest with a header and two inputs
-	titleText := slack.NewTextBlockObject(slack.PlainTextType, "Good Day Tracker", false, false)
-	closeText := slack.NewTextBlockObject(slack.PlainTextType, "Close", false, false)
-	submitText := slack.NewTextBlockObject(slack.PlainTextType, "Submit", false, false)
+func generateReflectionModal(lang string) slack.ModalViewRequest {
+	return generateReflectionModalWithAnswers(lang, nil, nil)
+}
 
-	headerText := slack.NewTextBlockObject(slack.MarkdownType, "Time to think about how the day went. Pick the answers that are closest to how you felt today went, and we'll review for patterns at the end of the week.", false, false)
+// generateReflectionModalWithAnswers builds the reflection modal, prefilled
+// with an existing reflection's answers if existing is not nil.
+func generateReflectionModalWithAnswers(lang string, existing *reflection.Reflection, loc *time.Location) slack.ModalViewRequest {
+	// Create a ModalViewRequest with a header and one input per question
+	titleText := slack.NewTextBlockObject(slack.PlainTextType, i18n.T(lang, "modal.title"), false, false)
+	closeText := slack.NewTextBlockObject(slack.PlainTextType, i18n.T(lang, "modal.close"), false, false)
+	submitText := slack.NewTextBlockObject(slack.PlainTextType, i18n.T(lang, "modal.submit"), false, false)
+
+	headerText := slack.NewTextBlockObject(slack.MarkdownType, i18n.T(lang, "modal.header"), false, false)
+	if existing != nil {
+		headerText = slack.NewTextBlockObject(slack.MarkdownType, i18n.T(lang, "modal.edit_header", slackDate(existing.Date, loc)), false, false)
+	}
 	headerSection := slack.NewSectionBlock(headerText, nil, nil)
 
 	bb := []slack.Block{headerSection}
-	for _, q := range reflection.Questions {
-		bb = append(bb, q.SlackBlock())
+	for _, q := range reflection.LocalizedQuestions(lang) {
+		if existing != nil {
+			bb = append(bb, q.SlackBlockWithAnswer(existing.ValueForQuestion(q.Field)))
+		} else {
+			bb = append(bb, q.SlackBlock())
+		}
 	}
 
 	blocks := slack.Blocks{
@@ -240,19 +424,44 @@ func generateReflectionModal() slack.ModalViewRequest {
 	modalRequest.Submit = submitText
 	modalRequest.Blocks = blocks
 	modalRequest.CallbackID = reflectionModalCallbackID
+	if existing != nil {
+		modalRequest.PrivateMetadata = strconv.FormatInt(existing.Date.Unix(), 10)
+	}
 	return modalRequest
 }
 
-func startReflectionDialog(triggerID string) error {
-	v := generateReflectionModal()
-	_, err := sapi.OpenView(triggerID, v)
+func (a *App) startReflectionDialog(ctx context.Context, triggerID, lang string) error {
+	v := generateReflectionModal(lang)
+	_, err := a.slack.OpenViewContext(ctx, triggerID, v)
 	if err != nil {
 		return fmt.Errorf("error opening reflection modal: %w", err)
 	}
 	return nil
 }
 
-func handleEvent(w http.ResponseWriter, r *http.Request) {
+// startEditReflectionDialog opens the reflection modal prefilled with the
+// answers of the user's reflection identified by the unix timestamp ts.
+func (a *App) startEditReflectionDialog(ctx context.Context, triggerID, tid, uid, ts string) {
+	su := a.lookupUser(ctx, tid, uid)
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		log.Error().Err(err).Str("ts", ts).Msg("error parsing reflection date to edit")
+		return
+	}
+
+	r, err := a.reflections.Get(ctx, tid, uid, time.Unix(sec, 0))
+	if err != nil {
+		a.reportErrorToUser(ctx, err, tid, uid, i18n.T(su.Lang, "reflection.edit_failed"))
+		return
+	}
+
+	_, err = a.slack.OpenViewContext(ctx, triggerID, generateReflectionModalWithAnswers(su.Lang, &r, su.Location))
+	if err != nil {
+		log.Error().Err(err).Str("uid", uid).Msg("error opening edit reflection modal")
+	}
+}
+
+func (a *App) handleEvent(w http.ResponseWriter, r *http.Request) {
 	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
@@ -260,7 +469,11 @@ func handleEvent(w http.ResponseWriter, r *http.Request) {
 	}
 
 	ev, err := slackevents.ParseEvent(json.RawMessage(body), slackevents.OptionNoVerifyToken())
-	if err != nil {
+	if err != nil && innerEventType(body) == "file_shared" {
+		// slackevents can't parse file_shared, so we parse it ourselves
+		a.handleFileShared(r.Context(), body)
+		return
+	} else if err != nil {
 		log.Debug().Err(err).Str("body", string(body)).Msg("unable to parse event")
 	}
 
@@ -275,7 +488,7 @@ func handleEvent(w http.ResponseWriter, r *http.Request) {
 		}
 
 	case slackevents.CallbackEvent:
-		handleInnerEvent(r.Context(), w, ev.InnerEvent)
+		a.handleInnerEvent(r.Context(), w, ev.InnerEvent)
 
 	default:
 		fmt.Printf("ev: %+v\n", ev)
@@ -284,160 +497,118 @@ func handleEvent(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func handleInnerEvent(ctx context.Context, w http.ResponseWriter, iev slackevents.EventsAPIInnerEvent) {
+// innerEventType returns the type of a callback event's inner event.
+func innerEventType(body []byte) string {
+	var ev struct {
+		Event struct {
+			Type string `json:"type"`
+		} `json:"event"`
+	}
+	if err := json.Unmarshal(body, &ev); err != nil {
+		return ""
+	}
+	return ev.Event.Type
+}
+
+func (a *App) handleInnerEvent(ctx context.Context, w http.ResponseWriter, iev slackevents.EventsAPIInnerEvent) {
 	switch ev := iev.Data.(type) {
 	case *slackevents.AppHomeOpenedEvent:
-		bb, err := renderHomeView(ctx, ev.View.TeamID, ev.User)
+		err := a.publishHomeView(ctx, ev.View.TeamID, ev.User)
 		if err != nil {
-			log.Debug().Err(err).Str("user", ev.User).Msg("error rendering home view")
+			log.Debug().Err(err).Str("user", ev.User).Msg("error publishing home view")
 			w.WriteHeader(http.StatusInternalServerError)
 		}
 
-		v := slack.HomeTabViewRequest{
-			Type:   slack.VTHomeTab,
-			Blocks: bb,
-		}
-		r, err := sapi.PublishViewContext(ctx, ev.User, v, "")
-		if err != nil {
-			log.Debug().Err(err).Str("user", ev.User).Msgf("error publishing home view: %+v", r.ResponseMetadata.Messages)
-		}
-
 	default:
 		fmt.Printf("unknown inner event type: %+v", ev)
 	}
 }
 
-func renderHomeView(ctx context.Context, tid, uid string) (slack.Blocks, error) {
-	var bb slack.Blocks
-
-	u, err := sapi.GetUserInfoContext(ctx, uid)
+// forEachUserReflection calls fn with each of the user's reflections, oldest
+// first, without loading them all into memory. It returns how many there were.
+func (a *App) forEachUserReflection(ctx context.Context, tid, uid string, fn func(reflection.Reflection) error) (int, error) {
+	start := time.Now()
+	rows, err := a.db.QueryxContext(ctx, "SELECT "+store.Columns+" FROM reflections WHERE team_id = ? AND user_id = ? ORDER BY date", tid, uid)
+	a.metrics.ObserveQuery("user_reflections", start, err)
 	if err != nil {
-		return bb, fmt.Errorf("error getting user info for uid %s: %w", uid, err)
-	}
-
-	bb.BlockSet = append(bb.BlockSet, slack.NewSectionBlock(nil, []*slack.TextBlockObject{slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf("hello *%s*!", u.Name), false, false)}, nil))
-
-	hmURL := heatmapper.URLForTeamAndUser(tid, uid, u.TZOffset/3600)
-	if len(hmURL) == 0 {
-		return bb, fmt.Errorf("error getting heatmap URL for tid %s uid %s: %w", tid, uid, err)
+		return 0, fmt.Errorf("error querying reflections: %w", err)
 	}
+	defer rows.Close()
 
-	bb.BlockSet = append(bb.BlockSet, slack.NewImageBlock(hmURL, "Daily feeling at a glance", "", slack.NewTextBlockObject(slack.PlainTextType, "Daily feeling at a glance", false, false)))
-
-	bb.BlockSet = append(bb.BlockSet, slack.NewActionBlock(
-		"home-start-reflection-action-block",
-		slack.NewButtonBlockElement(homeButtonStartReflection, "start-today-btn", slack.NewTextBlockObject(slack.PlainTextType, "Reflect on Today", false, false)),
-		slack.NewButtonBlockElement(homeButtonDownloadData, "download-data-btn", slack.NewTextBlockObject(slack.PlainTextType, "Download Reflections Data", false, false)),
-	))
-
-	repURL := detailedReporter.URLForTeamAndUser(tid, uid, u.TZOffset/3600)
-	if len(repURL) == 0 {
-		return bb, fmt.Errorf("error getting detailed report URL for tid %s uid %s: %w", tid, uid, err)
-	}
-
-	bb.BlockSet = append(bb.BlockSet, slack.NewImageBlock(repURL, "Interruptions and meetings with good days overlay", "", slack.NewTextBlockObject(slack.PlainTextType, "Interruptions and meetings with good days overlay", false, false)))
-
-	return bb, nil
-}
-
-func userReflectionsCSV(tid, uid string) (string, error) {
-	rows, err := db.Queryx("SELECT * FROM reflections WHERE team_id = ? AND user_id = ?", tid, uid)
-	if err != nil {
-		return "", err
-	}
-
-	isFirstRow := true
-	buf := new(bytes.Buffer)
-	cw := csv.NewWriter(buf)
+	n := 0
 	for rows.Next() {
-		if isFirstRow {
-			nn, err := rows.Columns()
-			if err != nil {
-				return "", fmt.Errorf("error reading column metadata: %w", err)
-			}
-			cw.Write(nn)
-			isFirstRow = false
-		}
-
-		r, err := rows.SliceScan()
-		if err != nil {
-			return "", fmt.Errorf("error scanning db row: %w", err)
+		var r reflection.Reflection
+		if err := rows.StructScan(&r); err != nil {
+			return n, fmt.Errorf("error scanning reflection: %w", err)
 		}
-
-		ss, err := stringSlice(r)
-		if err != nil {
-			return "", fmt.Errorf("error converting db row: %w", err)
-		}
-
-		err = cw.Write(ss)
-		if err != nil {
-			return "", fmt.Errorf("error writing csv row: %w", err)
+		if err := fn(r); err != nil {
+			return n, err
 		}
+		n++
 	}
 	if err := rows.Err(); err != nil {
-		return "", fmt.Errorf("error reading csv rows: %w", err)
+		return n, fmt.Errorf("error reading reflections: %w", err)
 	}
 
-	// if no rows, insert data to indicate an empty file
-	if isFirstRow {
-		return "No reflections found.", nil
-	}
+	return n, nil
+}
 
-	cw.Flush()
-	return buf.String(), nil
+// slackUser is what we need to know about a Slack user to talk to them.
+type slackUser struct {
+	Name     string
+	Lang     string
+	Location *time.Location
+	TZOffset int
+	IsAdmin  bool
+	Settings userSettings
 }
 
-func sendDataDownload(tid, uid string) {
-	log.Info().Str("tid", tid).Str("uid", uid).Msg("sendDataDownload")
-	content, err := userReflectionsCSV(tid, uid)
+// fetchUser returns the user's name, language and timezone based on their
+// Slack profile and settings. On error, the returned user has defaults for
+// whatever couldn't be fetched.
+func (a *App) fetchUser(ctx context.Context, tid, uid string) (slackUser, error) {
+	su := slackUser{
+		Lang:     i18n.Default,
+		Location: time.UTC,
+		Settings: userSettings{TeamID: tid, UserID: uid, HomeSection: homeSectionOverview},
+	}
+	u, err := a.slack.GetUserInfoContext(ctx, uid)
 	if err != nil {
-		reportErrorToUser(err, tid, uid, "Sorry, there was an error uploading your data to Slack - please try again in a few minutes.")
-		return
+		return su, fmt.Errorf("error getting slack user info: %w", err)
 	}
 
-	fn := fmt.Sprintf("reflections_%s_%d.csv", uid, time.Now().Unix())
-	_, err = sapi.UploadFile(slack.FileUploadParameters{
-		Title:    fn,
-		Filename: fn,
-		Filetype: "csv",
-		Content:  content,
-		Channels: []string{uid},
-	})
-	if err != nil {
-		reportErrorToUser(err, tid, uid, "Sorry, there was an error uploading your data to Slack - please try again in a few minutes.")
-		return
+	su.Name = u.Name
+	su.Lang = i18n.FromLocale(u.Locale)
+	su.TZOffset = u.TZOffset
+	su.IsAdmin = u.IsAdmin || u.IsOwner
+	su.Location = time.FixedZone(u.TZLabel, u.TZOffset)
+	if loc, err := time.LoadLocation(u.TZ); err == nil && len(u.TZ) > 0 {
+		su.Location = loc
 	}
 
-	_, _, err = sapi.PostMessage(
-		uid,
-		slack.MsgOptionText("All your reflections to date are in this file. Please note that date columns are in UTC timezone.", false),
-	)
+	settings, err := a.getUserSettings(ctx, tid, uid)
 	if err != nil {
-		log.Error().Err(err).Msgf("error posting file explanation error message to %s", uid)
+		return su, err
 	}
+	su.Settings = settings
+	if settings.Language.Valid {
+		su.Lang = i18n.FromLocale(settings.Language.String)
+	}
+	return su, nil
 }
 
-func stringSlice(ii []interface{}) ([]string, error) {
-	var ss []string
-	for _, i := range ii {
-		switch v := i.(type) {
-		case string:
-			ss = append(ss, v)
-		case uint8:
-			ss = append(ss, strconv.FormatUint(uint64(v), 10))
-		case []uint8:
-			ss = append(ss, string([]byte(v)))
-		case time.Time:
-			ss = append(ss, v.Format(time.RFC3339))
-		default:
-			return nil, fmt.Errorf("error converting row value of type %T to string: %+v\n", v, v)
-		}
+// lookupUser is fetchUser for when defaults are good enough.
+func (a *App) lookupUser(ctx context.Context, tid, uid string) slackUser {
+	su, err := a.fetchUser(ctx, tid, uid)
+	if err != nil {
+		log.Debug().Err(err).Str("tid", tid).Str("uid", uid).Msg("error looking up user")
 	}
-	return ss, nil
+	return su
 }
 
-func messageUser(tid, uid, msg string) {
-	_, _, err := sapi.PostMessage(
+func (a *App) messageUser(ctx context.Context, tid, uid, msg string) {
+	_, _, err := a.slack.PostMessageContext(
+		ctx,
 		uid,
 		slack.MsgOptionText(msg, false),
 	)
@@ -446,18 +617,32 @@ func messageUser(tid, uid, msg string) {
 	}
 }
 
-func reportErrorToUser(err error, tid, uid, msg string) {
+func (a *App) reportErrorToUser(ctx context.Context, err error, tid, uid, msg string) {
 	log.Error().Err(err).Str("tid", tid).Str("uid", uid).Msg("")
-	messageUser(tid, uid, msg)
-}
-
-func printBody(w http.ResponseWriter, r *http.Request) {
-	b, err := ioutil.ReadAll(r.Body)
-	fmt.Println(string(b), err)
+	a.messageUser(ctx, tid, uid, msg)
 }
 
 const (
 	homeButtonStartReflection = "start-reflection-action"
 	homeButtonDownloadData    = "download-data-action"
 	reflectionModalCallbackID = "reflection-modal-callback-id"
+	deleteDataModalCallbackID = "delete-data-modal-callback-id"
+	shareModalCallbackID      = "share-modal-callback-id"
+
+	homeButtonSectionPrefix    = "home-section-action-"
+	homeButtonDeleteReflection = "delete-reflection-action"
+	homeButtonDeleteData       = "delete-data-action"
+	homeButtonResetLinks       = "reset-links-action"
+	homeButtonRevokeShare      = "revoke-share-action"
+	homeButtonOpenDashboard    = "open-dashboard-action"
+	homeButtonReflectInBrowser = "reflect-in-browser-action"
+	homeButtonRevokeToken      = "revoke-token-action"
+	homeButtonRemoveWebhook    = "remove-webhook-action"
+	homeSelectLanguage         = "select-language-action"
+	homeSelectExportFormat     = "select-export-format-action"
+	importButtonConfirm        = "import-confirm-action"
+	importButtonCancel         = "import-cancel-action"
+
+	confirmationButtonEditReflection = "edit-reflection-action"
+	confirmationButtonOpenReport     = "open-report-action"
 )
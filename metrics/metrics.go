@@ -0,0 +1,260 @@
+// Package metrics collects Prometheus metrics about the app: its HTTP
+// handlers, Slack API calls, database queries and chart rendering, plus
+// counters of what people do with it.
+package metrics
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "goodday"
+
+// Metrics holds the app's metrics in their own registry, so several apps can
+// run in one process, as they do in tests.
+//
+// A nil *Metrics is valid and records nothing.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+
+	slackCalls    *prometheus.CounterVec
+	slackDuration *prometheus.HistogramVec
+
+	queries       *prometheus.CounterVec
+	queryDuration *prometheus.HistogramVec
+
+	renders        *prometheus.CounterVec
+	renderDuration *prometheus.HistogramVec
+
+	reflectionsSaved   *prometheus.CounterVec
+	reflectionsDeleted prometheus.Counter
+	exportsSent        *prometheus.CounterVec
+	webhookDeliveries  *prometheus.CounterVec
+}
+
+// New creates the metrics, along with the Go runtime and process collectors.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "HTTP requests by handler and status code.",
+		}, []string{"handler", "code"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "How long HTTP requests took, by handler.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"handler"}),
+
+		slackCalls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "slack",
+			Name:      "calls_total",
+			Help:      "Slack API calls by method and result.",
+		}, []string{"method", "result"}),
+		slackDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "slack",
+			Name:      "call_duration_seconds",
+			Help:      "How long Slack API calls took, by method.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method"}),
+
+		queries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "db",
+			Name:      "queries_total",
+			Help:      "Database queries by name and result.",
+		}, []string{"query", "result"}),
+		queryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "db",
+			Name:      "query_duration_seconds",
+			Help:      "How long database queries took, by name.",
+			Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"query"}),
+
+		renders: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "render",
+			Name:      "renders_total",
+			Help:      "Chart renders by renderer and result.",
+		}, []string{"renderer", "result"}),
+		renderDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "render",
+			Name:      "duration_seconds",
+			Help:      "How long chart renders took, by renderer.",
+			Buckets:   []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+		}, []string{"renderer"}),
+
+		reflectionsSaved: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "reflections_saved_total",
+			Help:      "Reflections saved, by how: submitted, edited, imported, or from the web form or API.",
+		}, []string{"source"}),
+		reflectionsDeleted: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "reflections_deleted_total",
+			Help:      "Reflections deleted.",
+		}),
+		exportsSent: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "exports_sent_total",
+			Help:      "Data downloads sent, by format.",
+		}, []string{"format"}),
+		webhookDeliveries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "webhook_deliveries_total",
+			Help:      "Webhook delivery attempts, by result: delivered, retrying or failed.",
+		}, []string{"result"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests, m.httpDuration,
+		m.slackCalls, m.slackDuration,
+		m.queries, m.queryDuration,
+		m.renders, m.renderDuration,
+		m.reflectionsSaved, m.reflectionsDeleted, m.exportsSent, m.webhookDeliveries,
+	)
+	return m
+}
+
+// Handler serves the metrics in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// InstrumentHandler counts and times the requests h serves, labelled with
+// name rather than the path, so signed URLs don't each get their own series.
+func (m *Metrics) InstrumentHandler(name string, h http.Handler) http.Handler {
+	if m == nil {
+		return h
+	}
+
+	requests := m.httpRequests.MustCurryWith(prometheus.Labels{"handler": name})
+	duration := m.httpDuration.MustCurryWith(prometheus.Labels{"handler": name})
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w, code: http.StatusOK}
+		h.ServeHTTP(sw, r)
+		requests.WithLabelValues(strconv.Itoa(sw.code)).Inc()
+		duration.WithLabelValues().Observe(time.Since(start).Seconds())
+	})
+}
+
+// statusWriter remembers the status code a handler responded with.
+type statusWriter struct {
+	http.ResponseWriter
+	code int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	w.code = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// ObserveSlackCall records a call to a Slack API method that started at
+// start and returned err.
+func (m *Metrics) ObserveSlackCall(method string, start time.Time, err error) {
+	if m == nil {
+		return
+	}
+	m.slackCalls.WithLabelValues(method, result(err)).Inc()
+	m.slackDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+}
+
+// ObserveQuery records a database query that started at start and returned
+// err. Finding no rows isn't counted as an error.
+func (m *Metrics) ObserveQuery(name string, start time.Time, err error) {
+	if m == nil {
+		return
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		err = nil
+	}
+	m.queries.WithLabelValues(name, result(err)).Inc()
+	m.queryDuration.WithLabelValues(name).Observe(time.Since(start).Seconds())
+}
+
+// ObserveRender records a chart render that started at start and returned
+// err.
+func (m *Metrics) ObserveRender(renderer string, start time.Time, err error) {
+	if m == nil {
+		return
+	}
+	m.renders.WithLabelValues(renderer, result(err)).Inc()
+	m.renderDuration.WithLabelValues(renderer).Observe(time.Since(start).Seconds())
+}
+
+// Sources of saved reflections.
+const (
+	SourceSubmitted = "submitted"
+	SourceEdited    = "edited"
+	SourceImported  = "imported"
+	SourceWeb       = "web"
+	SourceAPI       = "api"
+)
+
+// ReflectionsSaved counts n reflections saved from source.
+func (m *Metrics) ReflectionsSaved(source string, n int) {
+	if m == nil {
+		return
+	}
+	m.reflectionsSaved.WithLabelValues(source).Add(float64(n))
+}
+
+// ReflectionsDeleted counts n reflections deleted.
+func (m *Metrics) ReflectionsDeleted(n int) {
+	if m == nil {
+		return
+	}
+	m.reflectionsDeleted.Add(float64(n))
+}
+
+// ExportSent counts a data download sent in format.
+func (m *Metrics) ExportSent(format string) {
+	if m == nil {
+		return
+	}
+	m.exportsSent.WithLabelValues(format).Inc()
+}
+
+// Results of webhook delivery attempts.
+const (
+	DeliveryDelivered = "delivered"
+	DeliveryRetrying  = "retrying"
+	DeliveryFailed    = "failed"
+)
+
+// WebhookDelivery counts a webhook delivery attempt with result.
+func (m *Metrics) WebhookDelivery(result string) {
+	if m == nil {
+		return
+	}
+	m.webhookDeliveries.WithLabelValues(result).Inc()
+}
+
+func result(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "ok"
+}
@@ -0,0 +1,86 @@
+package metrics
+
+import (
+	"database/sql"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstrumentHandler(t *testing.T) {
+	m := New()
+	h := m.InstrumentHandler("test", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+
+	for _, path := range []string{"/", "/", "/missing"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	require.Equal(t, 2.0, testutil.ToFloat64(m.httpRequests.WithLabelValues("test", "200")))
+	require.Equal(t, 1.0, testutil.ToFloat64(m.httpRequests.WithLabelValues("test", "404")))
+	require.Equal(t, 1, testutil.CollectAndCount(m.httpDuration))
+}
+
+func TestObserve(t *testing.T) {
+	m := New()
+	start := time.Now()
+
+	m.ObserveQuery("get", start, nil)
+	m.ObserveQuery("get", start, sql.ErrNoRows)
+	m.ObserveQuery("get", start, errors.New("connection refused"))
+	require.Equal(t, 2.0, testutil.ToFloat64(m.queries.WithLabelValues("get", "ok")), "no rows should not count as an error")
+	require.Equal(t, 1.0, testutil.ToFloat64(m.queries.WithLabelValues("get", "error")))
+
+	m.ObserveSlackCall("chat.postMessage", start, errors.New("channel_not_found"))
+	require.Equal(t, 1.0, testutil.ToFloat64(m.slackCalls.WithLabelValues("chat.postMessage", "error")))
+
+	m.ObserveRender("heatmap", start, nil)
+	require.Equal(t, 1.0, testutil.ToFloat64(m.renders.WithLabelValues("heatmap", "ok")))
+
+	m.ReflectionsSaved(SourceImported, 12)
+	m.ReflectionsSaved(SourceSubmitted, 1)
+	require.Equal(t, 12.0, testutil.ToFloat64(m.reflectionsSaved.WithLabelValues(SourceImported)))
+	m.ExportSent("csv")
+	require.Equal(t, 1.0, testutil.ToFloat64(m.exportsSent.WithLabelValues("csv")))
+	m.WebhookDelivery(DeliveryRetrying)
+	require.Equal(t, 1.0, testutil.ToFloat64(m.webhookDeliveries.WithLabelValues(DeliveryRetrying)))
+}
+
+func TestHandler(t *testing.T) {
+	m := New()
+	m.ExportSent("zip")
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	b, err := ioutil.ReadAll(w.Body)
+	require.NoError(t, err)
+	require.Contains(t, string(b), `goodday_exports_sent_total{format="zip"} 1`)
+	require.Contains(t, string(b), "go_goroutines")
+}
+
+func TestNilMetricsRecordsNothing(t *testing.T) {
+	var m *Metrics
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	require.NotPanics(t, func() {
+		m.InstrumentHandler("test", h).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		m.ObserveQuery("get", time.Now(), nil)
+		m.ObserveSlackCall("auth.test", time.Now(), nil)
+		m.ObserveRender("report", time.Now(), nil)
+		m.ReflectionsSaved(SourceSubmitted, 1)
+		m.ReflectionsDeleted(1)
+		m.ExportSent("csv")
+	})
+}
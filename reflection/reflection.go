@@ -7,6 +7,7 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/jharlap/good-day-app/i18n"
 	"github.com/slack-go/slack"
 )
 
@@ -32,9 +33,14 @@ type Reflection struct {
 }
 
 func (r Reflection) String() string {
+	return r.Text(i18n.Default)
+}
+
+// Text formats the reflection's answers in the given language.
+func (r Reflection) Text(lang string) string {
 	buf := new(bytes.Buffer)
-	fmt.Fprintf(buf, "Date (UTC): %s\n", r.Date)
-	for _, q := range Questions {
+	fmt.Fprintf(buf, "%s: %s\n", i18n.T(lang, "reflection.date"), r.Date)
+	for _, q := range LocalizedQuestions(lang) {
 		fmt.Fprintf(buf, "%s: *%s*\n", q.Text, q.Options.ValueFor(r.ValueForQuestion(q.Field)))
 	}
 	return buf.String()
@@ -50,23 +56,80 @@ func (r Reflection) ValueForQuestion(field string) string {
 	return ""
 }
 
+// SetValueForQuestion sets the answer to the question for field, returning
+// false if there is no such question.
+func (r *Reflection) SetValueForQuestion(field string, v NumberPrefixedEnum) bool {
+	rv := reflect.ValueOf(r).Elem()
+	for i := 0; i < rv.NumField(); i++ {
+		if t, ok := rv.Type().Field(i).Tag.Lookup("db"); ok && t == field && rv.Field(i).Type() == reflect.TypeOf(v) {
+			rv.Field(i).Set(reflect.ValueOf(v))
+			return true
+		}
+	}
+	return false
+}
+
 type Question struct {
 	Text    string
 	Field   string
 	Options OptionSet
 }
 
+// Localize returns a copy of the question with its text and options
+// translated to lang. Untranslated text is left in English.
+func (q Question) Localize(lang string) Question {
+	if t, ok := i18n.Lookup(lang, "question."+q.Field); ok {
+		q.Text = t
+	}
+	q.Options = q.Options.Localize(lang)
+	return q
+}
+
 func (q Question) SlackBlock() *slack.InputBlock {
+	return q.SlackBlockWithAnswer("")
+}
+
+// SlackBlockWithAnswer is like SlackBlock, with the option for code
+// preselected so an existing answer can be edited.
+func (q Question) SlackBlockWithAnswer(code string) *slack.InputBlock {
+	el := q.Options.SlackElement()
+	for _, opt := range q.Options.Options {
+		if opt.Code == code {
+			el.InitialOption = opt.SlackOption()
+		}
+	}
 	return slack.NewInputBlock(
 		q.Field,
 		slack.NewTextBlockObject(slack.PlainTextType, q.Text, false, false),
-		q.Options.SlackElement(),
+		el,
 	)
 }
 
 type OptionSet struct {
+	Name        string
 	Placeholder string
 	Options     []Option
+
+	// Ordered is true if options go from least to most, so the number
+	// prefixes of their codes can be compared and averaged.
+	Ordered bool
+}
+
+// Localize returns a copy of the option set with its text translated to lang.
+// Option codes are stored in the database, so they are never translated.
+func (o OptionSet) Localize(lang string) OptionSet {
+	if t, ok := i18n.Lookup(lang, "placeholder."+o.Name); ok {
+		o.Placeholder = t
+	}
+	opts := make([]Option, len(o.Options))
+	for i, opt := range o.Options {
+		if t, ok := i18n.Lookup(lang, "option."+o.Name+"."+opt.Code); ok {
+			opt.Text = t
+		}
+		opts[i] = opt
+	}
+	o.Options = opts
+	return o
 }
 
 func (o OptionSet) SlackElement() *slack.SelectBlockElement {
@@ -106,6 +169,7 @@ func (o Option) SlackOption() *slack.OptionBlockObject {
 
 var (
 	QualityOptions = OptionSet{
+		Name:        "quality",
 		Placeholder: "Pick the closest",
 		Options: []Option{
 			{Text: "Terrible", Code: "0-terrible"},
@@ -114,9 +178,11 @@ var (
 			{Text: "Good", Code: "3-good"},
 			{Text: "Awesome", Code: "4-awesome"},
 		},
+		Ordered: true,
 	}
 
 	AmountOfDayOptions = OptionSet{
+		Name:        "amount",
 		Placeholder: "How much of the day",
 		Options: []Option{
 			{Text: "None of the day", Code: "0-none"},
@@ -125,9 +191,11 @@ var (
 			{Text: "Much of the day", Code: "3-much"},
 			{Text: "Most or all of the day", Code: "4-most"},
 		},
+		Ordered: true,
 	}
 
 	FeelingOptions = OptionSet{
+		Name:        "feeling",
 		Placeholder: "Pick the closest",
 		Options: []Option{
 			{Text: "Tense or nervous", Code: "0-tense"},
@@ -142,6 +210,7 @@ var (
 	}
 
 	NumberOptions = OptionSet{
+		Name:        "number",
 		Placeholder: "How many",
 		Options: []Option{
 			{Text: "0", Code: "0-none"},
@@ -150,9 +219,11 @@ var (
 			{Text: "3-4", Code: "3-few"},
 			{Text: "5 or more", Code: "4-many"},
 		},
+		Ordered: true,
 	}
 
 	TimeOptions = OptionSet{
+		Name:        "time",
 		Placeholder: "Which part of the day",
 		Options: []Option{
 			{Text: "In the morning (9:00 – 11:00)", Code: "0-morning"},
@@ -181,10 +252,23 @@ var (
 	}
 )
 
+// LocalizedQuestions returns Questions translated to lang.
+func LocalizedQuestions(lang string) []Question {
+	qq := make([]Question, len(Questions))
+	for i, q := range Questions {
+		qq[i] = q.Localize(lang)
+	}
+	return qq
+}
+
 type NumberPrefixedEnum string
 
 func (e *NumberPrefixedEnum) Scan(src interface{}) error {
 	switch v := src.(type) {
+	case nil:
+		// unanswered questions are NULL
+		*e = ""
+
 	case string:
 		*e = NumberPrefixedEnum(v)
 
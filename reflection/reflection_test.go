@@ -3,6 +3,7 @@ package reflection
 import (
 	"testing"
 
+	"github.com/jharlap/good-day-app/i18n"
 	"github.com/stretchr/testify/require"
 )
 
@@ -25,6 +26,15 @@ func TestReflectionValueForQuestion(t *testing.T) {
 	}
 }
 
+func TestReflectionSetValueForQuestion(t *testing.T) {
+	var r Reflection
+	require.True(t, r.SetValueForQuestion("work_day_feeling", "2-sad"))
+	require.EqualValues(t, "2-sad", r.WorkDayFeeling)
+	require.False(t, r.SetValueForQuestion("team_id", "T1"), "only answers should be settable")
+	require.False(t, r.SetValueForQuestion("other_field", "1-q"))
+	require.Empty(t, r.TeamID)
+}
+
 func TestNumberPrefixedEnumScan(t *testing.T) {
 	tcs := []string{"", "a", "2", "5-ahe", "10-sd"}
 
@@ -39,6 +49,14 @@ func TestNumberPrefixedEnumScan(t *testing.T) {
 	}
 }
 
+func TestNumberPrefixedEnumScanNull(t *testing.T) {
+	e := NumberPrefixedEnum("1-q")
+	err := e.Scan(nil)
+
+	require.NoError(t, err, "unexpected error")
+	require.EqualValues(t, "", e, "NULL should scan as empty")
+}
+
 func TestNumberPrefixedEnumIntVal(t *testing.T) {
 	tcs := []struct {
 		in NumberPrefixedEnum
@@ -57,3 +75,22 @@ func TestNumberPrefixedEnumIntVal(t *testing.T) {
 		})
 	}
 }
+
+func TestQuestionsTranslated(t *testing.T) {
+	for _, lang := range i18n.Languages {
+		if lang == i18n.Default {
+			continue
+		}
+		t.Run(lang, func(t *testing.T) {
+			for i, q := range LocalizedQuestions(lang) {
+				require.NotEqual(t, Questions[i].Text, q.Text, "question %s not translated", q.Field)
+				require.NotEqual(t, Questions[i].Options.Placeholder, q.Options.Placeholder, "placeholder for %s not translated", q.Field)
+				for j, o := range q.Options.Options {
+					require.Equal(t, Questions[i].Options.Options[j].Code, o.Code, "option codes must not be translated")
+					_, ok := i18n.Lookup(lang, "option."+q.Options.Name+"."+o.Code)
+					require.True(t, ok, "option %s of %s not translated", o.Code, q.Field)
+				}
+			}
+		})
+	}
+}
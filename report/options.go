@@ -0,0 +1,85 @@
+package report
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Resource is the kind of resource report URLs are signed for.
+const Resource = "report"
+
+const (
+	ThemeLight = "light"
+	ThemeDark  = "dark"
+
+	MinRangeDays = 7
+	MaxRangeDays = 90
+)
+
+// Options are how a report is drawn. They are signed into its URL, so whoever
+// it's shared with can't change them.
+type Options struct {
+	// RangeDays is how many days the report covers, ending with the current
+	// week.
+	RangeDays int
+	// Theme is ThemeLight or ThemeDark.
+	Theme string
+}
+
+var DefaultOptions = Options{RangeDays: 14, Theme: ThemeLight}
+
+// Validate checks the options can be drawn.
+func (o Options) Validate() error {
+	if o.RangeDays < MinRangeDays || o.RangeDays > MaxRangeDays {
+		return fmt.Errorf("range of %d days isn't between %d and %d", o.RangeDays, MinRangeDays, MaxRangeDays)
+	}
+	if _, ok := themes[o.Theme]; !ok {
+		return fmt.Errorf("unknown theme %q", o.Theme)
+	}
+	return nil
+}
+
+// signed returns the options to sign, leaving out defaults to keep URLs short.
+func (o Options) signed() map[string]string {
+	m := make(map[string]string)
+	if o.RangeDays != DefaultOptions.RangeDays {
+		m["range"] = strconv.Itoa(o.RangeDays)
+	}
+	if o.Theme != DefaultOptions.Theme {
+		m["theme"] = o.Theme
+	}
+	return m
+}
+
+// optionsFromSigned returns the options from a verified URL, with defaults for
+// any left out.
+func optionsFromSigned(m map[string]string) (Options, error) {
+	o := DefaultOptions
+	for k, v := range m {
+		switch k {
+		case "range":
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return Options{}, fmt.Errorf("error parsing range %q: %w", v, err)
+			}
+			o.RangeDays = n
+		case "theme":
+			o.Theme = v
+		default:
+			return Options{}, fmt.Errorf("unknown option %q", k)
+		}
+	}
+	return o, o.Validate()
+}
+
+// theme is the colours a report is drawn with.
+type theme struct {
+	background string
+	text       string
+	subtext    string
+}
+
+var themes = map[string]theme{
+	ThemeLight: {background: "#ffffff", text: "#333333", subtext: "#aaaaaa"},
+	ThemeDark:  {background: "#100c2a", text: "#eeeeee", subtext: "#aaaaaa"},
+}
@@ -0,0 +1,45 @@
+package report
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOptionsRoundTrip(t *testing.T) {
+	for _, o := range []Options{DefaultOptions, {RangeDays: 30, Theme: ThemeDark}, {RangeDays: MinRangeDays, Theme: ThemeLight}} {
+		got, err := optionsFromSigned(o.signed())
+		require.NoError(t, err)
+		require.Equal(t, o, got)
+	}
+	require.Empty(t, DefaultOptions.signed(), "defaults should be left out of URLs")
+}
+
+func TestOptionsFromSigned(t *testing.T) {
+	tcs := map[string]map[string]string{
+		"unknown option":     {"metric": "work_day_quality"},
+		"range too short":    {"range": "6"},
+		"range too long":     {"range": "91"},
+		"range not a number": {"range": "two weeks"},
+		"unknown theme":      {"theme": "neon"},
+	}
+	for name, in := range tcs {
+		t.Run(name, func(t *testing.T) {
+			_, err := optionsFromSigned(in)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestReportRange(t *testing.T) {
+	now, err := time.Parse("2006-01-02 15:04:05", "2021-07-02 14:25:01")
+	require.NoError(t, err)
+
+	start, end := reportRange(now, 0, DefaultOptions.RangeDays)
+	require.Equal(t, mondayOfWeekBeforeInUTC(now, 0), start, "the default range should be the last two weeks")
+	require.Equal(t, "2021-07-05", end.Format(dateFormat))
+
+	start, _ = reportRange(now, 0, 30)
+	require.Equal(t, "2021-06-05", start.Format(dateFormat))
+}
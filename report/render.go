@@ -1,6 +1,7 @@
 package report
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -9,16 +10,56 @@ import (
 	"github.com/jharlap/good-day-app/reflection"
 )
 
-func (imr *InterruptionsMeetingsReport) renderReflectionsEchart(rr []reflection.Reflection, startTime, endTime time.Time, w io.Writer) error {
-	c := map[string]interface{}{
-		"title": map[string]string{
+func (imr *InterruptionsMeetingsReport) renderReflectionsEchart(ctx context.Context, rr []reflection.Reflection, startTime, endTime time.Time, th theme, w io.Writer) error {
+	b, err := json.Marshal(chartOptions(rr, startTime, endTime, th))
+	if err != nil {
+		return fmt.Errorf("error rendering to json: %w", err)
+	}
+
+	renderStart := time.Now()
+	img, err := imr.renderer.Render(ctx, b)
+	imr.metrics.ObserveRender("render_service", renderStart, err)
+	if err != nil {
+		return fmt.Errorf("error rendering chart: %w", err)
+	}
+
+	_, err = w.Write(img)
+	return err
+}
+
+// ChartOptions returns the ECharts options the report is drawn from, for
+// reflections with dates in the user's timezone, in the named theme.
+func ChartOptions(rr []reflection.Reflection, startTime, endTime time.Time, themeName string) map[string]interface{} {
+	th, ok := themes[themeName]
+	if !ok {
+		th = themes[DefaultOptions.Theme]
+	}
+	return chartOptions(rr, startTime, endTime, th)
+}
+
+func chartOptions(rr []reflection.Reflection, startTime, endTime time.Time, th theme) map[string]interface{} {
+	return map[string]interface{}{
+		"backgroundColor": th.background,
+		"textStyle": map[string]string{
+			"color": th.text,
+		},
+		"title": map[string]interface{}{
 			"text":    "Meetings and interruptions",
 			"subtext": "Shaded days are good days",
+			"textStyle": map[string]string{
+				"color": th.text,
+			},
+			"subtextStyle": map[string]string{
+				"color": th.subtext,
+			},
 		},
-		"legend": map[string]string{
+		"legend": map[string]interface{}{
 			"type": "plain",
 			"top":  "bottom",
 			"left": "center",
+			"textStyle": map[string]string{
+				"color": th.text,
+			},
 		},
 		"xAxis": map[string]string{
 			"type": "time",
@@ -105,19 +146,6 @@ func (imr *InterruptionsMeetingsReport) renderReflectionsEchart(rr []reflection.
 			"#26c0c0",
 		},
 	}
-
-	b, err := json.Marshal(c)
-	if err != nil {
-		return fmt.Errorf("error rendering to json: %w", err)
-	}
-
-	img, err := imr.renderer.Render(b)
-	if err != nil {
-		return fmt.Errorf("error rendering chart: %w", err)
-	}
-
-	_, err = w.Write(img)
-	return err
 }
 
 func categoryDataForOptionSet(os reflection.OptionSet) []string {
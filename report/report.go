@@ -1,16 +1,22 @@
 package report
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"strings"
 	"time"
 
+	"github.com/jharlap/good-day-app/metrics"
 	"github.com/jharlap/good-day-app/reflection"
+	"github.com/jharlap/good-day-app/tracing"
 	"github.com/jharlap/good-day-app/urlsigner"
 	"github.com/jmoiron/sqlx"
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type InterruptionsMeetingsReport struct {
@@ -18,23 +24,34 @@ type InterruptionsMeetingsReport struct {
 	signer   *urlsigner.Engine
 	db       *sqlx.DB
 	renderer *RenderService
+	metrics  *metrics.Metrics
 }
 
-func New(baseURL string, signer *urlsigner.Engine, db *sqlx.DB, renderURL, renderCredsFile string) *InterruptionsMeetingsReport {
+func New(baseURL string, signer *urlsigner.Engine, db *sqlx.DB, renderURL, renderCredsFile string, m *metrics.Metrics) *InterruptionsMeetingsReport {
 
 	return &InterruptionsMeetingsReport{
 		baseURL:  baseURL,
 		signer:   signer,
 		db:       db,
 		renderer: &RenderService{URL: renderURL, CredentialsFile: renderCredsFile},
+		metrics:  m,
 	}
 }
 
-func (imr *InterruptionsMeetingsReport) URLForTeamAndUser(teamID, userID string, tz int) string {
+func (imr *InterruptionsMeetingsReport) URLForTeamAndUser(teamID, userID string, tz int, generation uint64) string {
+	return imr.URL(teamID, userID, tz, generation, DefaultOptions)
+}
+
+// URL returns a signed URL for the user's report drawn with o, in the
+// generation of the user's links.
+func (imr *InterruptionsMeetingsReport) URL(teamID, userID string, tz int, generation uint64, o Options) string {
 	sig := imr.signer.Sign(urlsigner.Params{
+		Resource:       Resource,
 		TeamID:         teamID,
 		UserID:         userID,
 		TZ:             tz,
+		Options:        o.signed(),
+		Generation:     generation,
 		ExpiryDuration: time.Hour * 24 * 30,
 	})
 	return fmt.Sprintf("%s/%s", imr.baseURL, sig)
@@ -49,11 +66,15 @@ func (imr *InterruptionsMeetingsReport) ServeHTTP(w http.ResponseWriter, r *http
 			return
 		}
 
-		p, err := imr.signer.Parse(r.URL.Path[i+1:])
+		p, err := imr.signer.ParseContext(r.Context(), r.URL.Path[i+1:], Resource)
 		if errors.Is(err, urlsigner.ErrInvalidSignature) {
 			w.WriteHeader(http.StatusUnauthorized)
 			log.Debug().Err(err).Str("path", r.URL.Path).Msg("invalid url signing")
 			return
+		} else if errors.Is(err, urlsigner.ErrGenerationUnavailable) {
+			w.WriteHeader(http.StatusInternalServerError)
+			log.Error().Err(err).Msg("error checking report url is current")
+			return
 		} else if err != nil {
 			w.WriteHeader(http.StatusBadRequest)
 			log.Debug().Err(err).Str("path", r.URL.Path).Msg("invalid url signing")
@@ -62,13 +83,51 @@ func (imr *InterruptionsMeetingsReport) ServeHTTP(w http.ResponseWriter, r *http
 		rp = p
 	}
 
-	start := mondayOfWeekBeforeInUTC(time.Now(), rp.TZ)
-	rows, err := imr.db.QueryxContext(r.Context(), "SELECT * FROM reflections WHERE `date` >= ? AND team_id = ? AND user_id = ?", start.Format(mysqlDatetimeFormat), rp.TeamID, rp.UserID)
+	o, err := optionsFromSigned(rp.Options)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		log.Debug().Err(err).Str("path", r.URL.Path).Msg("invalid report options")
+		return
+	}
+
+	buf := new(bytes.Buffer)
+	err = imr.Render(r.Context(), buf, rp.TeamID, rp.UserID, rp.TZ, o)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
-		log.Error().Err(err).Str("tid", rp.TeamID).Str("uid", rp.UserID).Msg("error querying for reflections")
+		log.Error().Err(err).Str("tid", rp.TeamID).Str("uid", rp.UserID).Msg("error rendering report")
 		return
 	}
+
+	w.Header().Set("Content-Type", "image/png")
+	_, err = w.Write(buf.Bytes())
+	if err != nil {
+		log.Error().Err(err).Msg("error writing report image")
+	}
+}
+
+// Render writes a PNG chart of the user's meetings and interruptions over the
+// range of days ending with this week, with days in the user's timezone.
+func (imr *InterruptionsMeetingsReport) Render(ctx context.Context, w io.Writer, teamID, userID string, tz int, o Options) (err error) {
+	ctx, span := tracing.Start(ctx, "report.Render", attribute.Int("range_days", o.RangeDays), attribute.String("theme", o.Theme))
+	defer func(t time.Time) {
+		imr.metrics.ObserveRender("report", t, err)
+		tracing.End(span, err)
+	}(time.Now())
+
+	if err := o.Validate(); err != nil {
+		return fmt.Errorf("error drawing report: %w", err)
+	}
+
+	start, end := reportRange(time.Now(), tz, o.RangeDays)
+	const query = "SELECT * FROM reflections WHERE `date` >= ? AND team_id = ? AND user_id = ?"
+	queryStart := time.Now()
+	qctx, qspan := tracing.StartQuery(ctx, "report_reflections", query)
+	rows, err := imr.db.QueryxContext(qctx, query, start.Format(mysqlDatetimeFormat), teamID, userID)
+	imr.metrics.ObserveQuery("report_reflections", queryStart, err)
+	tracing.End(qspan, err)
+	if err != nil {
+		return fmt.Errorf("error querying for reflections: %w", err)
+	}
 	defer rows.Close()
 
 	var rr []reflection.Reflection
@@ -76,27 +135,31 @@ func (imr *InterruptionsMeetingsReport) ServeHTTP(w http.ResponseWriter, r *http
 		var r reflection.Reflection
 		err := rows.StructScan(&r)
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			log.Error().Err(err).Msg("error scanning row")
-			return
+			return fmt.Errorf("error scanning row: %w", err)
 		}
 
 		// display dates in user timezone
-		r.Date = r.Date.Add(-1 * time.Duration(rp.TZ) * time.Hour)
+		r.Date = r.Date.Add(-1 * time.Duration(tz) * time.Hour)
 
 		rr = append(rr, r)
 	}
 	if err = rows.Err(); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		log.Error().Err(err).Msg("error getting reflections data")
-		return
+		return fmt.Errorf("error getting reflections data: %w", err)
 	}
 
-	w.Header().Set("Content-Type", "image/png")
-	err = imr.renderReflectionsEchart(rr, start, start.Add(time.Hour*14*24), w)
-	if err != nil {
-		log.Error().Err(err).Str("tid", rp.TeamID).Str("uid", rp.UserID).Msg("error rendering report")
-	}
+	return imr.renderReflectionsEchart(ctx, rr, start, end, themes[o.Theme], w)
+}
+
+// Ping checks the render service the report is drawn by is reachable.
+func (imr *InterruptionsMeetingsReport) Ping(ctx context.Context) error {
+	return imr.renderer.Ping(ctx)
+}
+
+// reportRange returns the start and end of a report covering days, ending
+// with the week t is in.
+func reportRange(t time.Time, tzOffset, days int) (start, end time.Time) {
+	end = mondayOfWeekBeforeInUTC(t, tzOffset).Add(time.Hour * 14 * 24)
+	return end.Add(-1 * time.Hour * 24 * time.Duration(days)), end
 }
 
 func mondayOfWeekBeforeInUTC(t time.Time, tzOffset int) time.Time {
@@ -8,6 +8,8 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/jharlap/good-day-app/tracing"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"golang.org/x/oauth2"
 	"google.golang.org/api/idtoken"
 )
@@ -55,14 +57,23 @@ func (s *RenderService) NewRequest(method string) (*http.Request, error) {
 	return req, nil
 }
 
-var renderClient = &http.Client{Timeout: 30 * time.Second}
+// renderClient propagates the trace context, so the render service's spans
+// join ours.
+var renderClient = &http.Client{
+	Timeout:   30 * time.Second,
+	Transport: otelhttp.NewTransport(http.DefaultTransport),
+}
 
 // Render converts the data to a chart
-func (s *RenderService) Render(in []byte) ([]byte, error) {
+func (s *RenderService) Render(ctx context.Context, in []byte) (_ []byte, err error) {
+	ctx, span := tracing.StartClient(ctx, "RenderService.Render")
+	defer func() { tracing.End(span, err) }()
+
 	req, err := s.NewRequest(http.MethodPost)
 	if err != nil {
 		return nil, fmt.Errorf("RenderService.NewRequest: %w", err)
 	}
+	req = req.WithContext(ctx)
 	req.Body = ioutil.NopCloser(bytes.NewReader(in))
 	defer req.Body.Close()
 
@@ -82,3 +93,23 @@ func (s *RenderService) Render(in []byte) ([]byte, error) {
 
 	return out, nil
 }
+
+// Ping checks the render service is reachable. It doesn't authenticate, so
+// any response other than a server error counts.
+func (s *RenderService) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.URL, nil)
+	if err != nil {
+		return fmt.Errorf("http.NewRequest: %w", err)
+	}
+
+	resp, err := renderClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("http.Client.Do: %w", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("http.Client.Do: %s (%d): render service unavailable", http.StatusText(resp.StatusCode), resp.StatusCode)
+	}
+	return nil
+}
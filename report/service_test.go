@@ -0,0 +1,42 @@
+package report
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderServicePing(t *testing.T) {
+	tcs := []struct {
+		name   string
+		status int
+		ok     bool
+	}{
+		{"ok", http.StatusOK, true},
+		{"unauthenticated", http.StatusUnauthorized, true},
+		{"method not allowed", http.StatusMethodNotAllowed, true},
+		{"unavailable", http.StatusServiceUnavailable, false},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.status)
+			}))
+			defer srv.Close()
+
+			err := (&RenderService{URL: srv.URL}).Ping(context.Background())
+			if tc.ok {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+			}
+		})
+	}
+
+	err := (&RenderService{URL: "http://127.0.0.1:1"}).Ping(context.Background())
+	require.Error(t, err, "an unreachable service should fail")
+}
@@ -19,6 +19,94 @@ CREATE TABLE `reflections` (
     PRIMARY KEY (`team_id`, `user_id`, `date`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
 
+CREATE TABLE `user_settings` (
+    `team_id` varchar(255) NOT NULL,
+    `user_id` varchar(255) NOT NULL,
+    `home_section` varchar(32) NOT NULL DEFAULT 'overview',
+    `language` varchar(16) NULL,
+    `link_generation` int unsigned NOT NULL DEFAULT 0,
+    `updated_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP() ON UPDATE CURRENT_TIMESTAMP(),
+    PRIMARY KEY (`team_id`, `user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+CREATE TABLE `deletion_audit` (
+    `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+    `team_id` varchar(255) NOT NULL,
+    `user_id` varchar(255) NOT NULL,
+    `scope` varchar(32) NOT NULL,
+    `range_start` datetime NULL,
+    `range_end` datetime NULL,
+    `deleted_count` int NOT NULL,
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP(),
+    PRIMARY KEY (`id`),
+    KEY `team_user` (`team_id`, `user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+CREATE TABLE `shares` (
+    `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+    `team_id` varchar(255) NOT NULL,
+    `user_id` varchar(255) NOT NULL,
+    `name` varchar(100) NOT NULL,
+    `chart` varchar(32) NOT NULL,
+    `max_views` int unsigned NULL,
+    `views` int unsigned NOT NULL DEFAULT 0,
+    `expires_at` datetime NOT NULL,
+    `revoked_at` datetime NULL,
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP(),
+    PRIMARY KEY (`id`),
+    KEY `team_user` (`team_id`, `user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+CREATE TABLE `api_tokens` (
+    `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+    `team_id` varchar(255) NOT NULL,
+    `user_id` varchar(255) NOT NULL,
+    `name` varchar(100) NOT NULL,
+    `token_hash` binary(32) NOT NULL,
+    `scopes` varchar(255) NOT NULL,
+    `last_used_at` datetime NULL,
+    `revoked_at` datetime NULL,
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP(),
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `token_hash` (`token_hash`),
+    KEY `team_user` (`team_id`, `user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+CREATE TABLE `webhooks` (
+    `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+    `team_id` varchar(255) NOT NULL,
+    `user_id` varchar(255) NOT NULL,
+    `scope` varchar(16) NOT NULL,
+    `url` varchar(2048) NOT NULL,
+    `secret` varchar(64) NOT NULL,
+    `revoked_at` datetime NULL,
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP(),
+    PRIMARY KEY (`id`),
+    KEY `team_user` (`team_id`, `user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+CREATE TABLE `webhook_deliveries` (
+    `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+    `webhook_id` bigint unsigned NOT NULL,
+    -- team_id and user_id are whose reflections the payload is about, so
+    -- deleting them can delete their deliveries to others' webhooks too
+    `team_id` varchar(255) NOT NULL,
+    `user_id` varchar(255) NOT NULL,
+    `event` varchar(64) NOT NULL,
+    `payload` mediumtext NOT NULL,
+    `status` varchar(16) NOT NULL DEFAULT 'pending',
+    `attempts` int NOT NULL DEFAULT 0,
+    `next_attempt_at` datetime NOT NULL,
+    `last_status_code` int NULL,
+    `last_error` varchar(255) NULL,
+    `delivered_at` datetime NULL,
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP(),
+    PRIMARY KEY (`id`),
+    KEY `status_next_attempt` (`status`, `next_attempt_at`),
+    KEY `webhook` (`webhook_id`, `id`),
+    KEY `subject` (`team_id`, `user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
 -- create calendar table
 CREATE TABLE `calendar` (
     `dt` DATE NOT NULL PRIMARY KEY,
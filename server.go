@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	readHeaderTimeout = 5 * time.Second
+	readTimeout       = 15 * time.Second
+	// writeTimeout leaves room for a chart render, which can take up to the
+	// render service's 30 second timeout.
+	writeTimeout = 45 * time.Second
+	idleTimeout  = 2 * time.Minute
+
+	// maxRequestBodySize is far more than any Slack payload needs.
+	maxRequestBodySize = 1 << 20
+
+	// drainTimeout is how long shutdown waits for in-flight requests and
+	// background jobs. Cloud Run kills the container 10 seconds after
+	// SIGTERM.
+	drainTimeout = 8 * time.Second
+)
+
+// Serve serves the app's handler on ln, and delivers webhooks, until ctx is
+// done, then stops accepting requests and waits up to drainTimeout for
+// in-flight requests, webhook deliveries and background jobs to finish. It
+// only returns nil after a clean shutdown.
+func (a *App) Serve(ctx context.Context, ln net.Listener) error {
+	srv := &http.Server{
+		Handler:           a.Handler(),
+		ReadHeaderTimeout: readHeaderTimeout,
+		ReadTimeout:       readTimeout,
+		WriteTimeout:      writeTimeout,
+		IdleTimeout:       idleTimeout,
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- srv.Serve(ln)
+	}()
+
+	// stop delivering webhooks however Serve returns
+	ctx, stop := context.WithCancel(ctx)
+	defer stop()
+	webhooksDone := make(chan struct{})
+	go func() {
+		defer close(webhooksDone)
+		a.webhooks.Run(ctx)
+	}()
+
+	select {
+	case err := <-errc:
+		return fmt.Errorf("error serving http: %w", err)
+	case <-ctx.Done():
+	}
+
+	log.Info().Dur("timeout", drainTimeout).Msg("shutting down, draining requests and background jobs")
+	dctx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+
+	// wait for requests first, since they can start jobs, and cancel any jobs
+	// still running even if requests took too long
+	err := srv.Shutdown(dctx)
+	select {
+	case <-webhooksDone:
+	case <-dctx.Done():
+		log.Warn().Msg("gave up waiting for webhook deliveries")
+	}
+	if jerr := a.jobs.Wait(dctx); jerr != nil {
+		return fmt.Errorf("error waiting for background jobs: %w", jerr)
+	}
+	if err != nil {
+		return fmt.Errorf("error draining http requests: %w", err)
+	}
+	return nil
+}
+
+// limitRequestBody stops any request reading more than n bytes of its body.
+func limitRequestBody(n int64, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, n)
+		h.ServeHTTP(w, r)
+	})
+}
+
+// isBodyTooLarge reports whether err is from reading past a request body
+// limit. The error has no type of its own before go1.19.
+func isBodyTooLarge(err error) bool {
+	return err != nil && err.Error() == "http: request body too large"
+}
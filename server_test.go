@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jharlap/good-day-app/metrics"
+	"github.com/jharlap/good-day-app/webhook"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServeDrainsJobsOnShutdown(t *testing.T) {
+	a := &App{jobs: newJobRunner(), webhooks: webhook.New(nil, metrics.New())}
+	var finished int32
+	a.handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		a.jobs.Go(r.Context(), "slow", func(ctx context.Context) {
+			time.Sleep(100 * time.Millisecond)
+			atomic.StoreInt32(&finished, 1)
+		})
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() { errc <- a.Serve(ctx, ln) }()
+
+	res, err := http.Get("http://" + ln.Addr().String())
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	cancel()
+	select {
+	case err := <-errc:
+		require.NoError(t, err)
+	case <-time.After(drainTimeout):
+		t.Fatal("Serve didn't return after shutdown")
+	}
+	require.Equal(t, int32(1), atomic.LoadInt32(&finished), "shutdown should wait for background jobs")
+
+	_, err = http.Get("http://" + ln.Addr().String())
+	require.Error(t, err, "the listener should be closed")
+}
+
+func TestServeReturnsListenerErrors(t *testing.T) {
+	a := &App{jobs: newJobRunner(), webhooks: webhook.New(nil, metrics.New()), handler: http.NotFoundHandler()}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	ln.Close()
+
+	require.Error(t, a.Serve(context.Background(), ln))
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// userSettings are the per-user preferences chosen in the home tab.
+type userSettings struct {
+	TeamID      string         `db:"team_id"`
+	UserID      string         `db:"user_id"`
+	HomeSection string         `db:"home_section"`
+	Language    sql.NullString `db:"language"`
+
+	// LinkGeneration is signed into the user's chart links, which are only
+	// accepted while it's current.
+	LinkGeneration uint64 `db:"link_generation"`
+}
+
+// getUserSettings returns the user's settings, or the defaults if they have
+// never changed any.
+func (a *App) getUserSettings(ctx context.Context, tid, uid string) (userSettings, error) {
+	s := userSettings{TeamID: tid, UserID: uid, HomeSection: homeSectionOverview}
+	start := time.Now()
+	err := a.db.GetContext(ctx, &s, "SELECT team_id, user_id, home_section, language, link_generation FROM user_settings WHERE team_id = ? AND user_id = ?", tid, uid)
+	a.metrics.ObserveQuery("get_user_settings", start, err)
+	if errors.Is(err, sql.ErrNoRows) {
+		return s, nil
+	} else if err != nil {
+		return s, fmt.Errorf("error getting user settings: %w", err)
+	}
+
+	return s, nil
+}
+
+func (a *App) saveUserHomeSection(ctx context.Context, tid, uid, section string) error {
+	start := time.Now()
+	_, err := a.db.ExecContext(ctx, "INSERT INTO user_settings SET team_id=?, user_id=?, home_section=? ON DUPLICATE KEY UPDATE home_section=VALUES(home_section)", tid, uid, section)
+	a.metrics.ObserveQuery("save_home_section", start, err)
+	if err != nil {
+		return fmt.Errorf("error saving home section: %w", err)
+	}
+
+	return nil
+}
+
+// linkGeneration returns the current generation of the user's chart links.
+func (a *App) linkGeneration(ctx context.Context, tid, uid string) (uint64, error) {
+	s, err := a.getUserSettings(ctx, tid, uid)
+	if err != nil {
+		return 0, err
+	}
+	return s.LinkGeneration, nil
+}
+
+// resetUserLinks moves the user's chart links to a new generation, so every
+// link they were sent before stops working. Their shares are signed with the
+// generation too, so they're revoked along with it rather than left listed
+// as active.
+func (a *App) resetUserLinks(ctx context.Context, tid, uid string) (err error) {
+	defer func(t time.Time) { a.metrics.ObserveQuery("reset_links", t, err) }(time.Now())
+
+	tx, err := a.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting reset links transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO user_settings SET team_id=?, user_id=?, link_generation=1 ON DUPLICATE KEY UPDATE link_generation=link_generation+1", tid, uid)
+	if err != nil {
+		return fmt.Errorf("error resetting links: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE shares SET revoked_at = ? WHERE team_id = ? AND user_id = ? AND revoked_at IS NULL", time.Now(), tid, uid)
+	if err != nil {
+		return fmt.Errorf("error revoking shares: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing reset links: %w", err)
+	}
+	return nil
+}
+
+// saveUserLanguage stores the user's language, or clears it to follow their
+// Slack locale if lang is empty.
+func (a *App) saveUserLanguage(ctx context.Context, tid, uid, lang string) error {
+	l := sql.NullString{String: lang, Valid: len(lang) > 0}
+	start := time.Now()
+	_, err := a.db.ExecContext(ctx, "INSERT INTO user_settings SET team_id=?, user_id=?, language=? ON DUPLICATE KEY UPDATE language=VALUES(language)", tid, uid, l)
+	a.metrics.ObserveQuery("save_language", start, err)
+	if err != nil {
+		return fmt.Errorf("error saving language: %w", err)
+	}
+
+	return nil
+}
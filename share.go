@@ -0,0 +1,390 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jharlap/good-day-app/heatmap"
+	"github.com/jharlap/good-day-app/i18n"
+	"github.com/jharlap/good-day-app/report"
+	"github.com/jharlap/good-day-app/urlsigner"
+	"github.com/rs/zerolog/log"
+	"github.com/slack-go/slack"
+)
+
+const (
+	// shareResource is the kind of resource share links are signed for.
+	shareResource = "share"
+
+	shareChartHeatmap = "heatmap"
+	shareChartReport  = "report"
+
+	maxShareNameLength = 100
+	maxShareViews      = 1000
+	// maxActiveShares keeps the list of shares on the home tab short.
+	maxActiveShares = 20
+)
+
+var (
+	shareCharts     = []string{shareChartHeatmap, shareChartReport}
+	shareExpiryDays = []int{1, 7, 30, 90}
+)
+
+// share is a named link to one of a user's charts, for showing to someone
+// outside Slack, until it expires, runs out of views or is revoked.
+type share struct {
+	ID        int64         `db:"id"`
+	TeamID    string        `db:"team_id"`
+	UserID    string        `db:"user_id"`
+	Name      string        `db:"name"`
+	Chart     string        `db:"chart"`
+	MaxViews  sql.NullInt64 `db:"max_views"`
+	Views     int64         `db:"views"`
+	ExpiresAt time.Time     `db:"expires_at"`
+	RevokedAt sql.NullTime  `db:"revoked_at"`
+	CreatedAt time.Time     `db:"created_at"`
+}
+
+const shareColumns = "id, team_id, user_id, name, chart, max_views, views, expires_at, revoked_at, created_at"
+
+func generateShareModal(lang string) slack.ModalViewRequest {
+	var charts []*slack.OptionBlockObject
+	for _, c := range shareCharts {
+		charts = append(charts, slack.NewOptionBlockObject(c, slack.NewTextBlockObject(slack.PlainTextType, i18n.T(lang, "share.chart."+c), false, false), nil))
+	}
+	var expiries []*slack.OptionBlockObject
+	for _, d := range shareExpiryDays {
+		expiries = append(expiries, slack.NewOptionBlockObject(strconv.Itoa(d), slack.NewTextBlockObject(slack.PlainTextType, i18n.T(lang, "share.expiry."+strconv.Itoa(d)), false, false), nil))
+	}
+
+	name := slack.NewPlainTextInputBlockElement(slack.NewTextBlockObject(slack.PlainTextType, i18n.T(lang, "share.name.placeholder"), false, false), "input")
+	name.MaxLength = maxShareNameLength
+	chart := slack.NewOptionsSelectBlockElement(slack.OptTypeStatic, nil, "select", charts...)
+	chart.InitialOption = charts[0]
+	expiry := slack.NewOptionsSelectBlockElement(slack.OptTypeStatic, nil, "select", expiries...)
+	expiry.InitialOption = expiries[1]
+	views := slack.NewInputBlock("share_max_views", slack.NewTextBlockObject(slack.PlainTextType, i18n.T(lang, "share.max_views"), false, false), slack.NewPlainTextInputBlockElement(slack.NewTextBlockObject(slack.PlainTextType, i18n.T(lang, "share.max_views.placeholder"), false, false), "input"))
+	views.Optional = true
+
+	var modalRequest slack.ModalViewRequest
+	modalRequest.Type = slack.ViewType("modal")
+	modalRequest.Title = slack.NewTextBlockObject(slack.PlainTextType, i18n.T(lang, "share.title"), false, false)
+	modalRequest.Close = slack.NewTextBlockObject(slack.PlainTextType, i18n.T(lang, "button.cancel"), false, false)
+	modalRequest.Submit = slack.NewTextBlockObject(slack.PlainTextType, i18n.T(lang, "share.submit"), false, false)
+	modalRequest.Blocks = slack.Blocks{
+		BlockSet: []slack.Block{
+			markdownSection(i18n.T(lang, "share.intro")),
+			slack.NewInputBlock("share_name", slack.NewTextBlockObject(slack.PlainTextType, i18n.T(lang, "share.name"), false, false), name),
+			slack.NewInputBlock("share_chart", slack.NewTextBlockObject(slack.PlainTextType, i18n.T(lang, "share.chart"), false, false), chart),
+			slack.NewInputBlock("share_expiry", slack.NewTextBlockObject(slack.PlainTextType, i18n.T(lang, "share.expiry"), false, false), expiry),
+			views,
+		},
+	}
+	modalRequest.CallbackID = shareModalCallbackID
+	return modalRequest
+}
+
+func (a *App) startShareDialog(ctx context.Context, triggerID, lang string) error {
+	v := generateShareModal(lang)
+	_, err := a.slack.OpenViewContext(ctx, triggerID, v)
+	if err != nil {
+		return fmt.Errorf("error opening share modal: %w", err)
+	}
+	return nil
+}
+
+// handleShareModalCallback validates the share modal, responding with errors
+// for Slack to show in the modal, or creates the share and DMs the user its
+// link.
+func (a *App) handleShareModalCallback(ctx context.Context, w http.ResponseWriter, ic slack.InteractionCallback) {
+	tid, uid := ic.Team.ID, ic.User.ID
+	su := a.lookupUser(ctx, tid, uid)
+
+	values := ic.View.State.Values
+	s := share{
+		TeamID: tid,
+		UserID: uid,
+		Name:   strings.TrimSpace(values["share_name"]["input"].Value),
+		Chart:  values["share_chart"]["select"].SelectedOption.Value,
+	}
+
+	errs := make(map[string]string)
+	if len(s.Name) == 0 || len(s.Name) > maxShareNameLength {
+		errs["share_name"] = i18n.T(su.Lang, "share.error.name")
+	}
+	if s.Chart != shareChartHeatmap && s.Chart != shareChartReport {
+		errs["share_chart"] = i18n.T(su.Lang, "share.error.chart")
+	}
+	days, err := strconv.Atoi(values["share_expiry"]["select"].SelectedOption.Value)
+	if err != nil || days < 1 || days > shareExpiryDays[len(shareExpiryDays)-1] {
+		errs["share_expiry"] = i18n.T(su.Lang, "share.error.expiry")
+	}
+	s.ExpiresAt = time.Now().AddDate(0, 0, days).Truncate(time.Second)
+	if v := strings.TrimSpace(values["share_max_views"]["input"].Value); len(v) > 0 {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 1 || n > maxShareViews {
+			errs["share_max_views"] = i18n.T(su.Lang, "share.error.max_views", maxShareViews)
+		}
+		s.MaxViews = sql.NullInt64{Int64: n, Valid: true}
+	}
+
+	if len(errs) == 0 {
+		active, err := a.activeShares(ctx, tid, uid)
+		if err != nil {
+			log.Error().Err(err).Str("tid", tid).Str("uid", uid).Msg("error counting active shares")
+		} else if len(active) >= maxActiveShares {
+			errs["share_name"] = i18n.T(su.Lang, "share.error.too_many", maxActiveShares)
+		}
+	}
+
+	if len(errs) > 0 {
+		b, err := json.Marshal(slack.NewErrorsViewSubmissionResponse(errs))
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(b)
+		return
+	}
+
+	s.ID, err = a.createShare(ctx, s)
+	if err != nil {
+		a.reportErrorToUser(ctx, err, tid, uid, i18n.T(su.Lang, "share.failed"))
+		return
+	}
+	log.Info().Str("tid", tid).Str("uid", uid).Int64("share", s.ID).Str("chart", s.Chart).Msg("created share")
+
+	u := a.shareURL(s, su.TZOffset/3600, su.Settings.LinkGeneration)
+	msg := i18n.T(su.Lang, "share.created", s.Name, slackDate(s.ExpiresAt, su.Location), u)
+	if s.MaxViews.Valid {
+		msg = i18n.T(su.Lang, "share.created_limited", s.Name, slackDate(s.ExpiresAt, su.Location), s.MaxViews.Int64, u)
+	}
+	// unfurling would fetch the chart, using up a view
+	_, _, err = a.slack.PostMessageContext(ctx, uid, slack.MsgOptionText(msg, false), slack.MsgOptionDisableLinkUnfurl(), slack.MsgOptionDisableMediaUnfurl())
+	if err != nil {
+		log.Error().Err(err).Str("tid", tid).Str("uid", uid).Msg("error sending share link")
+	}
+	a.refreshHomeView(ctx, tid, uid)
+}
+
+// shareURL returns the signed link to the share, which expires with it.
+func (a *App) shareURL(s share, tz int, generation uint64) string {
+	sig := a.signer.Sign(urlsigner.Params{
+		Resource:   shareResource,
+		TeamID:     s.TeamID,
+		UserID:     s.UserID,
+		TZ:         tz,
+		Options:    map[string]string{"id": strconv.FormatInt(s.ID, 10)},
+		Generation: generation,
+		Expiry:     s.ExpiresAt.Unix(),
+	})
+	return fmt.Sprintf("%s/share/%s", a.baseURL, sig)
+}
+
+func (a *App) createShare(ctx context.Context, s share) (int64, error) {
+	start := time.Now()
+	res, err := a.db.ExecContext(ctx, "INSERT INTO shares SET team_id=?, user_id=?, name=?, chart=?, max_views=?, expires_at=?", s.TeamID, s.UserID, s.Name, s.Chart, s.MaxViews, s.ExpiresAt)
+	a.metrics.ObserveQuery("create_share", start, err)
+	if err != nil {
+		return 0, fmt.Errorf("error creating share: %w", err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("error getting share id: %w", err)
+	}
+	return id, nil
+}
+
+// activeShares returns the user's shares that can still be viewed, soonest to
+// expire first.
+func (a *App) activeShares(ctx context.Context, tid, uid string) ([]share, error) {
+	var ss []share
+	start := time.Now()
+	err := a.db.SelectContext(ctx, &ss, "SELECT "+shareColumns+" FROM shares WHERE team_id = ? AND user_id = ? AND revoked_at IS NULL AND expires_at > ? AND (max_views IS NULL OR views < max_views) ORDER BY expires_at", tid, uid, time.Now())
+	a.metrics.ObserveQuery("active_shares", start, err)
+	if err != nil {
+		return nil, fmt.Errorf("error getting active shares: %w", err)
+	}
+	return ss, nil
+}
+
+func (a *App) revokeShare(ctx context.Context, tid, uid string, id int64) error {
+	start := time.Now()
+	_, err := a.db.ExecContext(ctx, "UPDATE shares SET revoked_at = ? WHERE id = ? AND team_id = ? AND user_id = ? AND revoked_at IS NULL", time.Now(), id, tid, uid)
+	a.metrics.ObserveQuery("revoke_share", start, err)
+	if err != nil {
+		return fmt.Errorf("error revoking share: %w", err)
+	}
+	return nil
+}
+
+// getShare returns the share if it can still be viewed, or false if it has
+// expired, run out of views or been revoked.
+func (a *App) getShare(ctx context.Context, tid, uid string, id int64) (share, bool, error) {
+	var s share
+	start := time.Now()
+	err := a.db.GetContext(ctx, &s, "SELECT "+shareColumns+" FROM shares WHERE id = ? AND team_id = ? AND user_id = ? AND revoked_at IS NULL AND expires_at > ? AND (max_views IS NULL OR views < max_views)", id, tid, uid, time.Now())
+	a.metrics.ObserveQuery("get_share", start, err)
+	if errors.Is(err, sql.ErrNoRows) {
+		return share{}, false, nil
+	} else if err != nil {
+		return share{}, false, fmt.Errorf("error getting share: %w", err)
+	}
+	return s, true, nil
+}
+
+// countShareView counts a view of the share, or returns false if it can't be
+// viewed anymore. The conditions are checked again so concurrent views can't go
+// over the limit.
+func (a *App) countShareView(ctx context.Context, s share) (_ bool, err error) {
+	defer func(t time.Time) { a.metrics.ObserveQuery("view_share", t, err) }(time.Now())
+
+	res, err := a.db.ExecContext(ctx, "UPDATE shares SET views = views + 1 WHERE id = ? AND team_id = ? AND user_id = ? AND revoked_at IS NULL AND expires_at > ? AND (max_views IS NULL OR views < max_views)", s.ID, s.TeamID, s.UserID, time.Now())
+	if err != nil {
+		return false, fmt.Errorf("error counting share view: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error counting share view: %w", err)
+	}
+	return n > 0, nil
+}
+
+// handleShare serves the chart a share link is for.
+func (a *App) handleShare(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	i := strings.LastIndex(r.URL.Path, "/")
+	if i < 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	p, err := a.signer.ParseContext(r.Context(), r.URL.Path[i+1:], shareResource)
+	if errors.Is(err, urlsigner.ErrInvalidSignature) {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Debug().Err(err).Str("path", r.URL.Path).Msg("invalid url signing")
+		return
+	} else if errors.Is(err, urlsigner.ErrGenerationUnavailable) {
+		w.WriteHeader(http.StatusInternalServerError)
+		log.Error().Err(err).Msg("error checking share url is current")
+		return
+	} else if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		log.Debug().Err(err).Str("path", r.URL.Path).Msg("invalid url signing")
+		return
+	}
+
+	id, err := strconv.ParseInt(p.Options["id"], 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		log.Debug().Err(err).Str("path", r.URL.Path).Msg("invalid share id")
+		return
+	}
+
+	s, ok, err := a.getShare(r.Context(), p.TeamID, p.UserID, id)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		log.Error().Err(err).Int64("share", id).Msg("error viewing share")
+		return
+	} else if !ok {
+		w.WriteHeader(http.StatusGone)
+		return
+	}
+
+	// render before counting the view, so a failure doesn't use one up
+
+	buf := new(bytes.Buffer)
+	if s.Chart == shareChartHeatmap {
+		err = a.heatmapper.Render(r.Context(), buf, s.TeamID, s.UserID, p.TZ, heatmap.DefaultOptions)
+	} else {
+		err = a.reporter.Render(r.Context(), buf, s.TeamID, s.UserID, p.TZ, report.DefaultOptions)
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		log.Error().Err(err).Int64("share", id).Str("chart", s.Chart).Msg("error rendering shared chart")
+		return
+	}
+
+	ok, err = a.countShareView(r.Context(), s)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		log.Error().Err(err).Int64("share", id).Msg("error viewing share")
+		return
+	} else if !ok {
+		w.WriteHeader(http.StatusGone)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "no-store")
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		log.Error().Err(err).Msg("error writing shared chart")
+	}
+}
+
+// renderHomeShares lists the user's active shares, each with a button to
+// revoke it.
+func (a *App) renderHomeShares(ctx context.Context, tid, uid string, su slackUser) ([]slack.Block, error) {
+	ss, err := a.activeShares(ctx, tid, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	bb := []slack.Block{markdownSection(i18n.T(su.Lang, "home.shares"))}
+	if len(ss) == 0 {
+		bb = append(bb, slack.NewContextBlock("", slack.NewTextBlockObject(slack.MarkdownType, i18n.T(su.Lang, "home.shares.empty"), false, false)))
+		return bb, nil
+	}
+
+	for _, s := range ss {
+		views := i18n.T(su.Lang, "home.shares.views", s.Views)
+		if s.MaxViews.Valid {
+			views = i18n.T(su.Lang, "home.shares.views_limited", s.Views, s.MaxViews.Int64)
+		}
+		text := fmt.Sprintf("*%s* · %s\n%s · %s", s.Name, i18n.T(su.Lang, "share.chart."+s.Chart), i18n.T(su.Lang, "home.shares.expires", slackDate(s.ExpiresAt, su.Location)), views)
+
+		id := strconv.FormatInt(s.ID, 10)
+		revokeBtn := slack.NewButtonBlockElement(homeButtonRevokeShare, id, slack.NewTextBlockObject(slack.PlainTextType, i18n.T(su.Lang, "home.shares.revoke"), false, false)).WithStyle(slack.StyleDanger)
+		revokeBtn.Confirm = slack.NewConfirmationBlockObject(
+			slack.NewTextBlockObject(slack.PlainTextType, i18n.T(su.Lang, "home.shares.revoke_confirm.title"), false, false),
+			slack.NewTextBlockObject(slack.MarkdownType, i18n.T(su.Lang, "home.shares.revoke_confirm.text", s.Name), false, false),
+			slack.NewTextBlockObject(slack.PlainTextType, i18n.T(su.Lang, "home.shares.revoke"), false, false),
+			slack.NewTextBlockObject(slack.PlainTextType, i18n.T(su.Lang, "button.cancel"), false, false),
+		)
+		bb = append(bb, slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, text, false, false), nil, slack.NewAccessory(revokeBtn)))
+	}
+	return bb, nil
+}
+
+// handleRevokeShare revokes the user's share with the id in value, from the
+// home tab.
+func (a *App) handleRevokeShare(ctx context.Context, tid, uid, value string) {
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		log.Error().Err(err).Str("value", value).Msg("error parsing share id to revoke")
+		return
+	}
+
+	if err := a.revokeShare(ctx, tid, uid, id); err != nil {
+		a.reportErrorToUser(ctx, err, tid, uid, i18n.T(a.lookupUser(ctx, tid, uid).Lang, "home.shares.revoke_failed"))
+		return
+	}
+	log.Info().Str("tid", tid).Str("uid", uid).Int64("share", id).Msg("revoked share")
+
+	a.refreshHomeView(ctx, tid, uid)
+}
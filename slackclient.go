@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/jharlap/good-day-app/metrics"
+	"github.com/jharlap/good-day-app/slackupload"
+	"github.com/jharlap/good-day-app/tracing"
+	"github.com/slack-go/slack"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// slackClient is the part of the Slack Web API the app uses. *slack.Client
+// implements it, and tests point one at a fake Slack server.
+type slackClient interface {
+	AuthTestContext(ctx context.Context) (*slack.AuthTestResponse, error)
+	GetUserInfoContext(ctx context.Context, user string) (*slack.User, error)
+
+	OpenViewContext(ctx context.Context, triggerID string, view slack.ModalViewRequest) (*slack.ViewResponse, error)
+	PublishViewContext(ctx context.Context, userID string, view slack.HomeTabViewRequest, hash string) (*slack.ViewResponse, error)
+
+	PostMessageContext(ctx context.Context, channelID string, options ...slack.MsgOption) (string, string, error)
+	DeleteMessageContext(ctx context.Context, channel, messageTimestamp string) (string, string, error)
+	OpenConversationContext(ctx context.Context, params *slack.OpenConversationParameters) (*slack.Channel, bool, bool, error)
+
+	GetFileInfoContext(ctx context.Context, fileID string, count, page int) (*slack.File, []slack.Comment, *slack.Paging, error)
+	GetFile(downloadURL string, writer io.Writer) error
+}
+
+// fileUploader shares files in Slack. *slackupload.Uploader implements it.
+type fileUploader interface {
+	Upload(ctx context.Context, params slackupload.Params) (slackupload.File, error)
+}
+
+var (
+	_ slackClient  = (*slack.Client)(nil)
+	_ fileUploader = (*slackupload.Uploader)(nil)
+)
+
+// instrumentedSlack records metrics and traces for every call it makes to
+// Slack.
+type instrumentedSlack struct {
+	c slackClient
+	m *metrics.Metrics
+}
+
+func (s instrumentedSlack) AuthTestContext(ctx context.Context) (*slack.AuthTestResponse, error) {
+	ctx, done := startSlackCall(ctx, s.m, "auth.test")
+	res, err := s.c.AuthTestContext(ctx)
+	done(err)
+	return res, err
+}
+
+func (s instrumentedSlack) GetUserInfoContext(ctx context.Context, user string) (*slack.User, error) {
+	ctx, done := startSlackCall(ctx, s.m, "users.info")
+	u, err := s.c.GetUserInfoContext(ctx, user)
+	done(err)
+	return u, err
+}
+
+func (s instrumentedSlack) OpenViewContext(ctx context.Context, triggerID string, view slack.ModalViewRequest) (*slack.ViewResponse, error) {
+	ctx, done := startSlackCall(ctx, s.m, "views.open")
+	res, err := s.c.OpenViewContext(ctx, triggerID, view)
+	done(err)
+	return res, err
+}
+
+func (s instrumentedSlack) PublishViewContext(ctx context.Context, userID string, view slack.HomeTabViewRequest, hash string) (*slack.ViewResponse, error) {
+	ctx, done := startSlackCall(ctx, s.m, "views.publish")
+	res, err := s.c.PublishViewContext(ctx, userID, view, hash)
+	done(err)
+	return res, err
+}
+
+func (s instrumentedSlack) PostMessageContext(ctx context.Context, channelID string, options ...slack.MsgOption) (string, string, error) {
+	ctx, done := startSlackCall(ctx, s.m, "chat.postMessage")
+	ch, ts, err := s.c.PostMessageContext(ctx, channelID, options...)
+	done(err)
+	return ch, ts, err
+}
+
+func (s instrumentedSlack) DeleteMessageContext(ctx context.Context, channel, messageTimestamp string) (string, string, error) {
+	ctx, done := startSlackCall(ctx, s.m, "chat.delete")
+	ch, ts, err := s.c.DeleteMessageContext(ctx, channel, messageTimestamp)
+	done(err)
+	return ch, ts, err
+}
+
+func (s instrumentedSlack) OpenConversationContext(ctx context.Context, params *slack.OpenConversationParameters) (*slack.Channel, bool, bool, error) {
+	ctx, done := startSlackCall(ctx, s.m, "conversations.open")
+	ch, noOp, alreadyOpen, err := s.c.OpenConversationContext(ctx, params)
+	done(err)
+	return ch, noOp, alreadyOpen, err
+}
+
+func (s instrumentedSlack) GetFileInfoContext(ctx context.Context, fileID string, count, page int) (*slack.File, []slack.Comment, *slack.Paging, error) {
+	ctx, done := startSlackCall(ctx, s.m, "files.info")
+	f, cc, p, err := s.c.GetFileInfoContext(ctx, fileID, count, page)
+	done(err)
+	return f, cc, p, err
+}
+
+// GetFile is only timed, not traced, since it takes no context to put a span
+// in.
+func (s instrumentedSlack) GetFile(downloadURL string, writer io.Writer) error {
+	start := time.Now()
+	err := s.c.GetFile(downloadURL, writer)
+	s.m.ObserveSlackCall("files.download", start, err)
+	return err
+}
+
+// startSlackCall starts timing and tracing a call to a Slack API method. done
+// ends it.
+func startSlackCall(ctx context.Context, m *metrics.Metrics, method string) (_ context.Context, done func(error)) {
+	start := time.Now()
+	ctx, span := tracing.StartClient(ctx, "slack "+method, attribute.String("slack.method", method))
+	return ctx, func(err error) {
+		m.ObserveSlackCall(method, start, err)
+		tracing.End(span, err)
+	}
+}
+
+// instrumentedUploader records metrics and traces for every file shared in
+// Slack.
+type instrumentedUploader struct {
+	u fileUploader
+	m *metrics.Metrics
+}
+
+func (u instrumentedUploader) Upload(ctx context.Context, params slackupload.Params) (slackupload.File, error) {
+	ctx, done := startSlackCall(ctx, u.m, "files.upload")
+	f, err := u.u.Upload(ctx, params)
+	done(err)
+	return f, err
+}
@@ -0,0 +1,234 @@
+// Package slackupload uploads files to Slack with the external upload flow:
+// files.getUploadURLExternal, a POST of the file's bytes to the returned URL,
+// then files.completeUploadExternal to share it.
+package slackupload
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const defaultAPIURL = "https://slack.com/api/"
+
+// Uploader uploads files with a bot token.
+type Uploader struct {
+	token  string
+	apiURL string
+	client *http.Client
+}
+
+// Option configures an Uploader.
+type Option func(*Uploader)
+
+// OptionAPIURL sets the Slack API URL, which is only useful for testing.
+func OptionAPIURL(u string) Option {
+	return func(up *Uploader) {
+		up.apiURL = u
+	}
+}
+
+// OptionHTTPClient sets the HTTP client used for all requests.
+func OptionHTTPClient(c *http.Client) Option {
+	return func(up *Uploader) {
+		up.client = c
+	}
+}
+
+func New(token string, opts ...Option) *Uploader {
+	u := &Uploader{
+		token:  token,
+		apiURL: defaultAPIURL,
+		client: http.DefaultClient,
+	}
+	for _, opt := range opts {
+		opt(u)
+	}
+	return u
+}
+
+// Params describe a file to upload and where to share it.
+type Params struct {
+	Filename string
+	Title    string
+	// Reader is read once, to the end.
+	Reader io.Reader
+	// Length is the file size in bytes. If it is 0 the file is first spooled
+	// to a temporary file to find its size, since Slack needs it up front.
+	Length int64
+
+	// ChannelID is where the file is shared. Direct messages need the DM's
+	// channel ID, not the user ID.
+	ChannelID      string
+	InitialComment string
+	ThreadTS       string
+}
+
+// File is an uploaded file.
+type File struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+}
+
+// Error is an error response from a Slack API method.
+type Error struct {
+	Method string
+	Code   string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("slack %s error: %s", e.Method, e.Code)
+}
+
+type response struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error"`
+}
+
+// Upload uploads the file and shares it in params.ChannelID.
+func (u *Uploader) Upload(ctx context.Context, params Params) (File, error) {
+	if params.Length <= 0 {
+		f, n, err := spool(params.Reader)
+		if err != nil {
+			return File{}, err
+		}
+		defer os.Remove(f.Name())
+		defer f.Close()
+
+		params.Reader = f
+		params.Length = n
+	}
+	if params.Length == 0 {
+		// Slack refuses empty files
+		return File{}, fmt.Errorf("error uploading %s: file is empty", params.Filename)
+	}
+
+	var ur struct {
+		response
+		UploadURL string `json:"upload_url"`
+		FileID    string `json:"file_id"`
+	}
+	err := u.call(ctx, "files.getUploadURLExternal", url.Values{
+		"filename": {params.Filename},
+		"length":   {strconv.FormatInt(params.Length, 10)},
+	}, &ur)
+	if err != nil {
+		return File{}, err
+	}
+
+	if err := u.send(ctx, ur.UploadURL, params.Reader, params.Length); err != nil {
+		return File{}, err
+	}
+
+	title := params.Title
+	if len(title) == 0 {
+		title = params.Filename
+	}
+	files, err := json.Marshal([]File{{ID: ur.FileID, Title: title}})
+	if err != nil {
+		return File{}, fmt.Errorf("error encoding uploaded files: %w", err)
+	}
+	v := url.Values{"files": {string(files)}}
+	if len(params.ChannelID) > 0 {
+		v.Set("channel_id", params.ChannelID)
+	}
+	if len(params.InitialComment) > 0 {
+		v.Set("initial_comment", params.InitialComment)
+	}
+	if len(params.ThreadTS) > 0 {
+		v.Set("thread_ts", params.ThreadTS)
+	}
+
+	var cr struct {
+		response
+		Files []File `json:"files"`
+	}
+	if err := u.call(ctx, "files.completeUploadExternal", v, &cr); err != nil {
+		return File{}, err
+	}
+	if len(cr.Files) == 0 {
+		return File{ID: ur.FileID, Title: title}, nil
+	}
+	return cr.Files[0], nil
+}
+
+// spool copies r to a temporary file, returning it rewound with its size.
+func spool(r io.Reader) (*os.File, int64, error) {
+	f, err := ioutil.TempFile("", "slackupload-")
+	if err != nil {
+		return nil, 0, fmt.Errorf("error creating spool file: %w", err)
+	}
+
+	n, err := io.Copy(f, r)
+	if err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, 0, fmt.Errorf("error spooling upload: %w", err)
+	}
+	return f, n, nil
+}
+
+// send streams the file's bytes to the upload URL.
+func (u *Uploader) send(ctx context.Context, uploadURL string, r io.Reader, length int64) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, uploadURL, ioutil.NopCloser(r))
+	if err != nil {
+		return fmt.Errorf("error creating upload request: %w", err)
+	}
+	req.ContentLength = length
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Authorization", "Bearer "+u.token)
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error uploading file: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("error uploading file: %s", resp.Status)
+	}
+	return nil
+}
+
+// call calls a Slack API method, decoding its response into out, which must
+// embed response.
+func (u *Uploader) call(ctx context.Context, method string, v url.Values, out interface{ result() response }) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.apiURL+method, strings.NewReader(v.Encode()))
+	if err != nil {
+		return fmt.Errorf("error creating %s request: %w", method, err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Authorization", "Bearer "+u.token)
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error calling %s: %w", method, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("error calling %s: %s", method, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("error decoding %s response: %w", method, err)
+	}
+	if r := out.result(); !r.OK {
+		return &Error{Method: method, Code: r.Error}
+	}
+	return nil
+}
+
+func (r response) result() response {
+	return r
+}
@@ -0,0 +1,151 @@
+package slackupload
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSlack implements the external upload flow like Slack does.
+type fakeSlack struct {
+	t   *testing.T
+	srv *httptest.Server
+
+	mu        sync.Mutex
+	lengths   map[string]int64
+	uploaded  map[string][]byte
+	completed url.Values
+	failWith  string
+}
+
+func newFakeSlack(t *testing.T) *fakeSlack {
+	f := &fakeSlack{t: t, lengths: make(map[string]int64), uploaded: make(map[string][]byte)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/files.getUploadURLExternal", f.getUploadURL)
+	mux.HandleFunc("/api/files.completeUploadExternal", f.complete)
+	mux.HandleFunc("/upload/", f.upload)
+	f.srv = httptest.NewServer(mux)
+	t.Cleanup(f.srv.Close)
+	return f
+}
+
+func (f *fakeSlack) reply(w http.ResponseWriter, v map[string]interface{}) {
+	if len(f.failWith) > 0 {
+		v = map[string]interface{}{"ok": false, "error": f.failWith}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func (f *fakeSlack) getUploadURL(w http.ResponseWriter, r *http.Request) {
+	require.Equal(f.t, "Bearer xoxb-test", r.Header.Get("Authorization"))
+	require.NoError(f.t, r.ParseForm())
+	n, err := strconv.ParseInt(r.PostFormValue("length"), 10, 64)
+	require.NoError(f.t, err, "length should be an integer")
+
+	f.mu.Lock()
+	id := "F" + strconv.Itoa(len(f.lengths)+1)
+	f.lengths[id] = n
+	f.mu.Unlock()
+
+	f.reply(w, map[string]interface{}{"ok": true, "file_id": id, "upload_url": f.srv.URL + "/upload/" + id})
+}
+
+func (f *fakeSlack) upload(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/upload/")
+	b, err := ioutil.ReadAll(r.Body)
+	require.NoError(f.t, err)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if n, ok := f.lengths[id]; !ok || n != int64(len(b)) {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	f.uploaded[id] = b
+}
+
+func (f *fakeSlack) complete(w http.ResponseWriter, r *http.Request) {
+	require.NoError(f.t, r.ParseForm())
+	var files []File
+	require.NoError(f.t, json.Unmarshal([]byte(r.PostFormValue("files")), &files))
+
+	f.mu.Lock()
+	f.completed = r.PostForm
+	for _, file := range files {
+		if _, ok := f.uploaded[file.ID]; !ok {
+			f.mu.Unlock()
+			f.reply(w, map[string]interface{}{"ok": false, "error": "file_not_found"})
+			return
+		}
+	}
+	f.mu.Unlock()
+
+	f.reply(w, map[string]interface{}{"ok": true, "files": files})
+}
+
+func TestUpload(t *testing.T) {
+	content := strings.Repeat("date,work_day_quality\n2021-07-01,3-good\n", 1000)
+
+	tcs := []struct {
+		name   string
+		length int64
+	}{
+		{"known length", int64(len(content))},
+		{"unknown length", 0},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			fs := newFakeSlack(t)
+			u := New("xoxb-test", OptionAPIURL(fs.srv.URL+"/api/"))
+
+			f, err := u.Upload(context.Background(), Params{
+				Filename:       "reflections.csv",
+				Reader:         strings.NewReader(content),
+				Length:         tc.length,
+				ChannelID:      "D123",
+				InitialComment: "here you go",
+			})
+			require.NoError(t, err)
+			require.Equal(t, "F1", f.ID)
+			require.Equal(t, "reflections.csv", f.Title, "title should default to the filename")
+			require.Equal(t, content, string(fs.uploaded["F1"]))
+			require.Equal(t, "D123", fs.completed.Get("channel_id"))
+			require.Equal(t, "here you go", fs.completed.Get("initial_comment"))
+		})
+	}
+}
+
+func TestUploadErrors(t *testing.T) {
+	fs := newFakeSlack(t)
+	fs.failWith = "invalid_auth"
+	u := New("xoxb-test", OptionAPIURL(fs.srv.URL+"/api/"))
+
+	_, err := u.Upload(context.Background(), Params{Filename: "a.csv", Reader: strings.NewReader("a")})
+	var se *Error
+	require.ErrorAs(t, err, &se)
+	require.Equal(t, "files.getUploadURLExternal", se.Method)
+	require.Equal(t, "invalid_auth", se.Code)
+
+	_, err = u.Upload(context.Background(), Params{Filename: "a.csv", Reader: strings.NewReader("")})
+	require.Error(t, err, "empty files should be refused")
+}
+
+func TestUploadLengthMismatch(t *testing.T) {
+	fs := newFakeSlack(t)
+	u := New("xoxb-test", OptionAPIURL(fs.srv.URL+"/api/"))
+
+	_, err := u.Upload(context.Background(), Params{Filename: "a.csv", Reader: strings.NewReader("abc"), Length: 10})
+	require.Error(t, err, "a failed upload should not be completed")
+	require.Nil(t, fs.completed)
+}
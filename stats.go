@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jharlap/good-day-app/i18n"
+	"github.com/jharlap/good-day-app/insights"
+	"github.com/jharlap/good-day-app/reflection"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// defaultStatsDays is how far back /reflect stats looks unless told.
+	defaultStatsDays = 90
+	// notableCorrelation is how strongly an answer has to go with better or
+	// worse days to be pointed out.
+	notableCorrelation = 0.3
+)
+
+// parseStatsCommand parses `/reflect stats [days]` into how many days back
+// to summarize, up to streakHistoryDays.
+func parseStatsCommand(text string) (days int, ok bool) {
+	ff := strings.Fields(text)
+	switch len(ff) {
+	case 1:
+		return defaultStatsDays, true
+	case 2:
+		n, err := strconv.Atoi(ff[1])
+		if err != nil || n < 1 || n > streakHistoryDays {
+			return 0, false
+		}
+		return n, true
+	default:
+		return 0, false
+	}
+}
+
+// handleStatsCommand summarizes the user's reflections for `/reflect stats`,
+// returning the reply to show only to the user.
+func (a *App) handleStatsCommand(ctx context.Context, tid, uid, text string, su slackUser) string {
+	days, ok := parseStatsCommand(text)
+	if !ok {
+		return i18n.T(su.Lang, "stats.usage", streakHistoryDays)
+	}
+
+	rr, err := a.userReflectionsSince(ctx, tid, uid, time.Now().AddDate(0, 0, -streakHistoryDays))
+	if err != nil {
+		log.Error().Err(err).Str("tid", tid).Str("uid", uid).Msg("error loading reflections for stats")
+		return i18n.T(su.Lang, "stats.failed")
+	}
+	return strings.Join(insightsText(summarizeDays(rr, days, time.Now(), su.Location), days, su.Lang), "\n\n")
+}
+
+// summarizeDays summarizes the reflections in rr from the last days before
+// now, with streaks counted over all of rr.
+func summarizeDays(rr []reflection.Reflection, days int, now time.Time, loc *time.Location) insights.Summary {
+	var recent []reflection.Reflection
+	dates := make([]time.Time, len(rr))
+	windowStart := now.AddDate(0, 0, -days)
+	for i, r := range rr {
+		dates[i] = r.Date
+		if !r.Date.Before(windowStart) {
+			recent = append(recent, r)
+		}
+	}
+
+	s := insights.Summarize(recent, now, loc)
+	s.CurrentStreak = insights.CurrentStreak(dates, now, loc)
+	s.LongestStreak = insights.LongestStreak(dates, loc)
+	return s
+}
+
+// insightsText describes s, the summary of the last days, as paragraphs of
+// markdown, each short enough for a section block.
+func insightsText(s insights.Summary, days int, lang string) []string {
+	if s.Count == 0 {
+		return []string{i18n.T(lang, "insights.empty", days)}
+	}
+
+	questions := make(map[string]reflection.Question)
+	for _, q := range reflection.LocalizedQuestions(lang) {
+		questions[q.Field] = q
+	}
+
+	buf := new(strings.Builder)
+	fmt.Fprintf(buf, "%s\n", i18n.T(lang, "insights.count", s.Count, days))
+	if s.CurrentStreak > 1 {
+		fmt.Fprintf(buf, "%s\n", i18n.T(lang, "confirm.streak", s.CurrentStreak))
+	}
+	if s.LongestStreak > 1 {
+		fmt.Fprintf(buf, "%s\n", i18n.T(lang, "insights.longest_streak", s.LongestStreak))
+	}
+	pp := []string{strings.TrimSpace(buf.String())}
+
+	buf.Reset()
+	fmt.Fprintf(buf, "%s\n", i18n.T(lang, "insights.typical"))
+	for _, qs := range s.Questions {
+		if qs.Answered == 0 {
+			continue
+		}
+		q := questions[qs.Field]
+		fmt.Fprintf(buf, "• %s: *%s*", q.Text, q.Options.ValueFor(qs.MostCommon))
+		if qs.Ordered {
+			fmt.Fprintf(buf, " %s", i18n.T(lang, "insights.average", qs.Average, len(q.Options.Options)-1))
+		}
+		fmt.Fprintln(buf)
+	}
+	pp = append(pp, strings.TrimSpace(buf.String()))
+
+	quality := questions[insights.QualityField]
+	qualityAnswered := 0
+	for _, qs := range s.Questions {
+		if qs.Field == insights.QualityField {
+			qualityAnswered = qs.Answered
+		}
+	}
+	if qualityAnswered > 0 {
+		buf.Reset()
+		fmt.Fprintf(buf, "%s\n", i18n.T(lang, "insights.weekdays"))
+		for _, w := range s.Weekdays {
+			if w.QualityAnswered == 0 {
+				continue
+			}
+			fmt.Fprintf(buf, "• %s\n", i18n.T(lang, "insights.weekday", i18n.T(lang, "weekday."+strings.ToLower(w.Weekday.String())), w.AverageQuality, len(quality.Options.Options)-1, w.QualityAnswered))
+		}
+		pp = append(pp, strings.TrimSpace(buf.String()))
+	}
+
+	buf.Reset()
+	fmt.Fprintf(buf, "%s\n", i18n.T(lang, "insights.correlations"))
+	notable := 0
+	for _, c := range s.Correlations {
+		if math.Abs(c.Rho) < notableCorrelation {
+			continue
+		}
+		key := "insights.correlation.more"
+		if c.Rho < 0 {
+			key = "insights.correlation.less"
+		}
+		fmt.Fprintf(buf, "• %s: %s\n", questions[c.Field].Text, i18n.T(lang, key, c.Rho))
+		notable++
+	}
+	switch {
+	case notable > 0:
+	case qualityAnswered < insights.MinCorrelationSamples:
+		fmt.Fprintf(buf, "%s\n", i18n.T(lang, "insights.correlations.too_few", insights.MinCorrelationSamples))
+	default:
+		fmt.Fprintf(buf, "%s\n", i18n.T(lang, "insights.correlations.none"))
+	}
+	return append(pp, strings.TrimSpace(buf.String()))
+}
@@ -0,0 +1,154 @@
+// Package store saves, updates and deletes reflections, so every way of
+// changing them, from Slack or the API, writes the same rows and audit log.
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jharlap/good-day-app/metrics"
+	"github.com/jharlap/good-day-app/reflection"
+	"github.com/jmoiron/sqlx"
+)
+
+// Columns are the columns of the reflections table scanned into a
+// reflection.Reflection, so that new columns don't break scanning.
+const Columns = "team_id, user_id, date, work_day_quality, work_other_people_amount, help_other_people_amount, interrupted_amount, progress_goals_amount, quality_work_amount, lot_of_work_amount, work_day_feeling, stressful_amount, breaks_amount, meeting_number, most_productive_time, least_productive_time, created_at"
+
+// Deletion scopes, as recorded in the deletion audit log.
+const (
+	DeletionScopeDay   = "day"
+	DeletionScopeRange = "range"
+	DeletionScopeAll   = "all"
+	// DeletionScopeReflection is a single reflection, deleted from the home
+	// tab history or the API.
+	DeletionScopeReflection = "reflection"
+)
+
+// erDupEntry is MySQL's error number for a duplicate key.
+const erDupEntry = 1062
+
+// ErrExists is returned when saving a reflection at the same date as one the
+// user already has.
+var ErrExists = errors.New("reflection already exists")
+
+type Store struct {
+	db      *sqlx.DB
+	metrics *metrics.Metrics
+}
+
+func New(db *sqlx.DB, m *metrics.Metrics) *Store {
+	return &Store{db: db, metrics: m}
+}
+
+// Save inserts a new reflection. Its date is truncated to the second, like
+// the date column, so it can be referenced by exactly what was stored.
+func (s *Store) Save(ctx context.Context, r reflection.Reflection) error {
+	r.Date = r.Date.Truncate(time.Second)
+	start := time.Now()
+	_, err := s.db.ExecContext(ctx, "INSERT INTO reflections SET team_id=?, user_id=?, date=?, work_day_quality=?, work_other_people_amount=?, help_other_people_amount=?, interrupted_amount=?, progress_goals_amount=?, quality_work_amount=?, lot_of_work_amount=?, work_day_feeling=?, stressful_amount=?, breaks_amount=?, meeting_number=?, most_productive_time=?, least_productive_time=?", r.TeamID, r.UserID, r.Date, r.WorkDayQuality, r.WorkOtherPeopleAmount, r.HelpOtherPeopleAmount, r.InterruptedAmount, r.ProgressGoalsAmount, r.QualityWorkAmount, r.LotOfWorkAmount, r.WorkDayFeeling, r.StressfulAmount, r.BreaksAmount, r.MeetingNumber, r.MostProductiveTime, r.LeastProductiveTime)
+	s.metrics.ObserveQuery("save_reflection", start, err)
+	var me *mysql.MySQLError
+	if errors.As(err, &me) && me.Number == erDupEntry {
+		return fmt.Errorf("error saving reflection: %w", ErrExists)
+	} else if err != nil {
+		return fmt.Errorf("error saving reflection: %w", err)
+	}
+	return nil
+}
+
+// Update replaces the answers of the reflection with r's team, user and date.
+func (s *Store) Update(ctx context.Context, r reflection.Reflection) error {
+	start := time.Now()
+	_, err := s.db.ExecContext(ctx, "UPDATE reflections SET work_day_quality=?, work_other_people_amount=?, help_other_people_amount=?, interrupted_amount=?, progress_goals_amount=?, quality_work_amount=?, lot_of_work_amount=?, work_day_feeling=?, stressful_amount=?, breaks_amount=?, meeting_number=?, most_productive_time=?, least_productive_time=? WHERE team_id=? AND user_id=? AND date=?", r.WorkDayQuality, r.WorkOtherPeopleAmount, r.HelpOtherPeopleAmount, r.InterruptedAmount, r.ProgressGoalsAmount, r.QualityWorkAmount, r.LotOfWorkAmount, r.WorkDayFeeling, r.StressfulAmount, r.BreaksAmount, r.MeetingNumber, r.MostProductiveTime, r.LeastProductiveTime, r.TeamID, r.UserID, r.Date)
+	s.metrics.ObserveQuery("update_reflection", start, err)
+	if err != nil {
+		return fmt.Errorf("error updating reflection: %w", err)
+	}
+	return nil
+}
+
+// Get returns the user's reflection at date. The error wraps sql.ErrNoRows
+// if there isn't one.
+func (s *Store) Get(ctx context.Context, tid, uid string, date time.Time) (reflection.Reflection, error) {
+	var r reflection.Reflection
+	start := time.Now()
+	err := s.db.GetContext(ctx, &r, "SELECT "+Columns+" FROM reflections WHERE team_id = ? AND user_id = ? AND date = ?", tid, uid, date)
+	s.metrics.ObserveQuery("get_reflection", start, err)
+	if err != nil {
+		return r, fmt.Errorf("error getting reflection: %w", err)
+	}
+	return r, nil
+}
+
+// ReflectionRange is the range of dates holding only the reflection at date,
+// since dates have no fractional seconds.
+func ReflectionRange(date time.Time) (start, end time.Time) {
+	return date, date.Add(time.Second)
+}
+
+// DeleteReflection deletes the user's reflection at date and records it in
+// the audit log. It returns the number of reflections deleted, 0 if there
+// was none.
+func (s *Store) DeleteReflection(ctx context.Context, tid, uid string, date time.Time) (int64, error) {
+	start, end := ReflectionRange(date)
+	return s.Delete(ctx, tid, uid, DeletionScopeReflection, start, end, nil)
+}
+
+// Delete deletes the user's reflections dated in [start, end), or all of
+// them for DeletionScopeAll, and records the deletion in the audit log. If
+// also isn't nil, it's run in the same transaction, to delete whatever else
+// goes with the reflections. It returns the number of reflections deleted.
+//
+// A single reflection that was already gone isn't audited, since nothing was
+// deleted.
+func (s *Store) Delete(ctx context.Context, tid, uid, scope string, start, end time.Time, also func(context.Context, *sqlx.Tx) error) (_ int64, err error) {
+	defer func(t time.Time) { s.metrics.ObserveQuery("delete_reflections", t, err) }(time.Now())
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("error starting deletion transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var res sql.Result
+	var rangeStart, rangeEnd sql.NullTime
+	if scope == DeletionScopeAll {
+		res, err = tx.ExecContext(ctx, "DELETE FROM reflections WHERE team_id = ? AND user_id = ?", tid, uid)
+	} else {
+		rangeStart = sql.NullTime{Time: start, Valid: true}
+		rangeEnd = sql.NullTime{Time: end, Valid: true}
+		res, err = tx.ExecContext(ctx, "DELETE FROM reflections WHERE team_id = ? AND user_id = ? AND date >= ? AND date < ?", tid, uid, start, end)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error deleting reflections: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("error counting deleted reflections: %w", err)
+	}
+	if n == 0 && scope == DeletionScopeReflection {
+		return 0, nil
+	}
+
+	if also != nil {
+		if err := also(ctx, tx); err != nil {
+			return 0, err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO deletion_audit SET team_id=?, user_id=?, scope=?, range_start=?, range_end=?, deleted_count=?", tid, uid, scope, rangeStart, rangeEnd, n)
+	if err != nil {
+		return 0, fmt.Errorf("error recording deletion audit entry: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("error committing deletion: %w", err)
+	}
+	return n, nil
+}
@@ -0,0 +1,119 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/jharlap/good-day-app/metrics"
+	"github.com/jharlap/good-day-app/reflection"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestStore(t *testing.T) (*Store, sqlmock.Sqlmock) {
+	mdb, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { mdb.Close() })
+	return New(sqlx.NewDb(mdb, "mysql"), metrics.New()), mock
+}
+
+func anyArgs(n int) []driver.Value {
+	vv := make([]driver.Value, n)
+	for i := range vv {
+		vv[i] = sqlmock.AnyArg()
+	}
+	return vv
+}
+
+func TestSave(t *testing.T) {
+	s, mock := newTestStore(t)
+	r := reflection.Reflection{TeamID: "T1", UserID: "U1", Date: time.Date(2021, 7, 2, 21, 0, 0, 500, time.UTC)}
+
+	mock.ExpectExec("INSERT INTO reflections").WithArgs(append([]driver.Value{"T1", "U1", r.Date.Truncate(time.Second)}, anyArgs(len(reflection.Questions))...)...).WillReturnResult(sqlmock.NewResult(1, 1))
+	require.NoError(t, s.Save(context.Background(), r))
+
+	mock.ExpectExec("INSERT INTO reflections").WillReturnError(&mysql.MySQLError{Number: erDupEntry})
+	err := s.Save(context.Background(), r)
+	require.True(t, errors.Is(err, ErrExists), "got %v", err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetMissing(t *testing.T) {
+	s, mock := newTestStore(t)
+	mock.ExpectQuery("SELECT " + Columns + " FROM reflections WHERE team_id = \\? AND user_id = \\? AND date = \\?").WillReturnRows(sqlmock.NewRows([]string{"team_id"}))
+	_, err := s.Get(context.Background(), "T1", "U1", time.Now())
+	require.True(t, errors.Is(err, sql.ErrNoRows), "got %v", err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDelete(t *testing.T) {
+	date := time.Unix(1625259600, 0).UTC()
+	from, to := date.AddDate(0, 0, -7), date
+
+	tcs := map[string]struct {
+		scope      string
+		start, end time.Time
+		deleted    int64
+		audited    bool
+		also       bool
+	}{
+		"reflection":      {scope: DeletionScopeReflection, start: date, end: date.Add(time.Second), deleted: 1, audited: true},
+		"missing":         {scope: DeletionScopeReflection, start: date, end: date.Add(time.Second)},
+		"empty range":     {scope: DeletionScopeRange, start: from, end: to, audited: true},
+		"all":             {scope: DeletionScopeAll, deleted: 12, audited: true, also: true},
+		"all but nothing": {scope: DeletionScopeAll, audited: true, also: true},
+	}
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			s, mock := newTestStore(t)
+
+			mock.ExpectBegin()
+			var rangeStart, rangeEnd driver.Value
+			if tc.scope == DeletionScopeAll {
+				mock.ExpectExec("DELETE FROM reflections WHERE team_id = \\? AND user_id = \\?$").WithArgs("T1", "U1").WillReturnResult(sqlmock.NewResult(0, tc.deleted))
+			} else {
+				rangeStart, rangeEnd = tc.start, tc.end
+				mock.ExpectExec("DELETE FROM reflections WHERE team_id = \\? AND user_id = \\? AND date >= \\? AND date < \\?").WithArgs("T1", "U1", tc.start, tc.end).WillReturnResult(sqlmock.NewResult(0, tc.deleted))
+			}
+			if tc.also {
+				mock.ExpectExec("DELETE FROM shares").WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+			if tc.audited {
+				mock.ExpectExec("INSERT INTO deletion_audit").WithArgs("T1", "U1", tc.scope, rangeStart, rangeEnd, tc.deleted).WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectCommit()
+			} else {
+				mock.ExpectRollback()
+			}
+
+			var also func(context.Context, *sqlx.Tx) error
+			if tc.also {
+				also = func(ctx context.Context, tx *sqlx.Tx) error {
+					_, err := tx.ExecContext(ctx, "DELETE FROM shares WHERE team_id = ? AND user_id = ?", "T1", "U1")
+					return err
+				}
+			}
+			n, err := s.Delete(context.Background(), "T1", "U1", tc.scope, tc.start, tc.end, also)
+			require.NoError(t, err)
+			require.Equal(t, tc.deleted, n)
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestDeleteRollsBackWhenAlsoFails(t *testing.T) {
+	s, mock := newTestStore(t)
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM reflections").WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectRollback()
+
+	failed := errors.New("boom")
+	_, err := s.Delete(context.Background(), "T1", "U1", DeletionScopeAll, time.Time{}, time.Time{}, func(context.Context, *sqlx.Tx) error { return failed })
+	require.True(t, errors.Is(err, failed), "got %v", err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jharlap/good-day-app/api"
+	"github.com/jharlap/good-day-app/i18n"
+	"github.com/rs/zerolog/log"
+	"github.com/slack-go/slack"
+)
+
+// Access levels for /reflect token, by the scopes their tokens are granted.
+var tokenAccess = map[string][]string{
+	"read":  {api.ScopeRead},
+	"write": {api.ScopeRead, api.ScopeWrite},
+}
+
+// parseTokenCommand parses `/reflect token [read|write] [name]` into the
+// scopes and name of the token to create. Tokens are read only unless write
+// access is asked for.
+func parseTokenCommand(text, lang string) (scopes []string, name string) {
+	ff := strings.Fields(text)
+	if len(ff) > 0 {
+		ff = ff[1:]
+	}
+	scopes = tokenAccess["read"]
+	if len(ff) > 0 {
+		if s, ok := tokenAccess[strings.ToLower(ff[0])]; ok {
+			scopes = s
+			ff = ff[1:]
+		}
+	}
+	name = strings.Join(ff, " ")
+	if len(name) == 0 {
+		name = i18n.T(lang, "token.default_name")
+	}
+	return scopes, name
+}
+
+// handleTokenCommand creates an access token for `/reflect token`, returning
+// the reply to show only to the user, since it's the only time the token's
+// secret is shown.
+func (a *App) handleTokenCommand(ctx context.Context, tid, uid, text, lang string) string {
+	scopes, name := parseTokenCommand(text, lang)
+	if len([]rune(name)) > api.MaxTokenNameLength {
+		return i18n.T(lang, "token.name_too_long", api.MaxTokenNameLength)
+	}
+	secret, err := a.api.CreateToken(ctx, tid, uid, name, scopes)
+	if errors.Is(err, api.ErrTooManyTokens) {
+		return i18n.T(lang, "token.too_many", api.MaxActiveTokens)
+	} else if err != nil {
+		log.Error().Err(err).Str("tid", tid).Str("uid", uid).Msg("error creating api token")
+		return i18n.T(lang, "token.failed")
+	}
+	log.Info().Str("tid", tid).Str("uid", uid).Strs("scopes", scopes).Msg("created api token")
+
+	a.refreshHomeView(ctx, tid, uid)
+	return i18n.T(lang, "token.created", name, tokenAccessName(lang, strings.Join(scopes, ",")), secret, a.baseURL+api.Prefix, a.baseURL+api.Prefix+"openapi.json")
+}
+
+// tokenAccessName is the access level of a token's scopes, in lang.
+func tokenAccessName(lang, scopes string) string {
+	if strings.Contains(scopes, api.ScopeWrite) {
+		return i18n.T(lang, "token.access.write")
+	}
+	return i18n.T(lang, "token.access.read")
+}
+
+// renderHomeTokens lists the user's access tokens, each with a button to
+// revoke it.
+func (a *App) renderHomeTokens(ctx context.Context, tid, uid string, su slackUser) ([]slack.Block, error) {
+	tt, err := a.api.ActiveTokens(ctx, tid, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	bb := []slack.Block{markdownSection(i18n.T(su.Lang, "home.tokens"))}
+	if len(tt) == 0 {
+		bb = append(bb, slack.NewContextBlock("", slack.NewTextBlockObject(slack.MarkdownType, i18n.T(su.Lang, "home.tokens.empty"), false, false)))
+		return bb, nil
+	}
+
+	for _, t := range tt {
+		used := i18n.T(su.Lang, "home.tokens.never_used")
+		if t.LastUsedAt.Valid {
+			used = i18n.T(su.Lang, "home.tokens.last_used", slackDate(t.LastUsedAt.Time, su.Location))
+		}
+		text := fmt.Sprintf("*%s* · %s\n%s · %s", t.Name, tokenAccessName(su.Lang, t.Scopes), i18n.T(su.Lang, "home.tokens.created", slackDate(t.CreatedAt, su.Location)), used)
+
+		id := strconv.FormatInt(t.ID, 10)
+		revokeBtn := slack.NewButtonBlockElement(homeButtonRevokeToken, id, slack.NewTextBlockObject(slack.PlainTextType, i18n.T(su.Lang, "home.tokens.revoke"), false, false)).WithStyle(slack.StyleDanger)
+		revokeBtn.Confirm = slack.NewConfirmationBlockObject(
+			slack.NewTextBlockObject(slack.PlainTextType, i18n.T(su.Lang, "home.tokens.revoke_confirm.title"), false, false),
+			slack.NewTextBlockObject(slack.MarkdownType, i18n.T(su.Lang, "home.tokens.revoke_confirm.text", t.Name), false, false),
+			slack.NewTextBlockObject(slack.PlainTextType, i18n.T(su.Lang, "home.tokens.revoke"), false, false),
+			slack.NewTextBlockObject(slack.PlainTextType, i18n.T(su.Lang, "button.cancel"), false, false),
+		)
+		bb = append(bb, slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, text, false, false), nil, slack.NewAccessory(revokeBtn)))
+	}
+	return bb, nil
+}
+
+// handleRevokeToken revokes the user's access token with the id in value,
+// from the home tab.
+func (a *App) handleRevokeToken(ctx context.Context, tid, uid, value string) {
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		log.Error().Err(err).Str("value", value).Msg("error parsing api token id to revoke")
+		return
+	}
+
+	if err := a.api.RevokeToken(ctx, tid, uid, id); err != nil {
+		a.reportErrorToUser(ctx, err, tid, uid, i18n.T(a.lookupUser(ctx, tid, uid).Lang, "home.tokens.revoke_failed"))
+		return
+	}
+	log.Info().Str("tid", tid).Str("uid", uid).Int64("token", id).Msg("revoked api token")
+
+	a.refreshHomeView(ctx, tid, uid)
+}
@@ -0,0 +1,122 @@
+// Package tracing sets up OpenTelemetry tracing, and starts the spans the app
+// creates itself around database queries and calls to other services.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Exporters spans can be sent with.
+const (
+	// ExporterNone drops every span, which is the default.
+	ExporterNone = "none"
+	// ExporterOTLP sends spans to an OpenTelemetry collector over OTLP/HTTP.
+	ExporterOTLP = "otlp"
+)
+
+// Exporters are the valid exporter names.
+var Exporters = []string{ExporterNone, ExporterOTLP}
+
+const (
+	instrumentationName = "github.com/jharlap/good-day-app"
+	serviceName         = "good-day-app"
+)
+
+// Setup installs the global tracer provider and propagator. Spans are sent
+// with exporter; for ExporterOTLP, to endpoint, a URL like
+// http://collector:4318, or to the OTEL_EXPORTER_OTLP_* defaults if it's
+// empty. The returned func flushes any spans not yet sent and stops tracing.
+func Setup(ctx context.Context, exporter, endpoint, version string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	switch exporter {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q", exporter)
+	}
+
+	var opts []otlptracehttp.Option
+	if len(endpoint) > 0 {
+		u, err := url.Parse(endpoint)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing otlp endpoint: %w", err)
+		}
+		opts = append(opts, otlptracehttp.WithEndpoint(u.Host))
+		if len(u.Path) > 0 && u.Path != "/" {
+			opts = append(opts, otlptracehttp.WithURLPath(u.Path))
+		}
+		if u.Scheme == "http" {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+	}
+
+	exp, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("error creating otlp exporter: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceNameKey.String(serviceName),
+		semconv.ServiceVersionKey.String(version),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("error creating tracing resource: %w", err)
+	}
+
+	tp := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exp), sdktrace.WithResource(res))
+	otel.SetTracerProvider(tp)
+	return tp.Shutdown, nil
+}
+
+// Start starts a span named name as a child of any span in ctx.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// StartQuery starts a span for the database query called name.
+func StartQuery(ctx context.Context, name, statement string) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, "db "+name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemMySQL,
+			semconv.DBStatementKey.String(statement),
+		),
+	)
+}
+
+// StartClient starts a span for a call to another service, such as Slack.
+func StartClient(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+// End records err on span, if it isn't nil, and ends the span.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Detach returns parent with ctx's span, for work that carries on after ctx's
+// request has been answered, and so shouldn't share its cancellation.
+func Detach(ctx, parent context.Context) context.Context {
+	return trace.ContextWithSpanContext(parent, trace.SpanContextFromContext(ctx))
+}
@@ -0,0 +1,81 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func withRecorder() *tracetest.SpanRecorder {
+	sr := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)))
+	return sr
+}
+
+func TestSetup(t *testing.T) {
+	tcs := []struct {
+		exporter string
+		endpoint string
+		wantErr  bool
+	}{
+		{"", "", false},
+		{ExporterNone, "", false},
+		{ExporterOTLP, "http://127.0.0.1:4318", false},
+		{"jaeger", "", true},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.exporter, func(t *testing.T) {
+			shutdown, err := Setup(context.Background(), tc.exporter, tc.endpoint, "test")
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.NoError(t, shutdown(context.Background()))
+		})
+	}
+}
+
+func TestEnd(t *testing.T) {
+	sr := withRecorder()
+
+	_, span := Start(context.Background(), "ok")
+	End(span, nil)
+	_, span = StartQuery(context.Background(), "get_reflection", "SELECT 1")
+	End(span, errors.New("connection refused"))
+
+	ss := sr.Ended()
+	require.Len(t, ss, 2)
+	require.Equal(t, codes.Unset, ss[0].Status().Code)
+	require.Equal(t, "db get_reflection", ss[1].Name())
+	require.Equal(t, trace.SpanKindClient, ss[1].SpanKind())
+	require.Equal(t, codes.Error, ss[1].Status().Code)
+	require.Equal(t, "connection refused", ss[1].Status().Description)
+}
+
+func TestDetach(t *testing.T) {
+	sr := withRecorder()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ctx, parent := Start(ctx, "request")
+	detached := Detach(ctx, context.Background())
+	cancel()
+	parent.End()
+
+	require.NoError(t, detached.Err(), "a detached context should outlive the request")
+	_, child := Start(detached, "background")
+	child.End()
+
+	ss := sr.Ended()
+	require.Len(t, ss, 2)
+	require.Equal(t, ss[0].SpanContext().TraceID(), ss[1].SpanContext().TraceID())
+	require.Equal(t, ss[0].SpanContext().SpanID(), ss[1].Parent().SpanID())
+}
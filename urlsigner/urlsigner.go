@@ -1,78 +1,455 @@
+// Package urlsigner signs the parameters of URLs the app hands out, like
+// links to a user's charts, so they can be served without a Slack session.
+//
+// A token is "<key id>.<payload>.<mac>", with the payload and its
+// HMAC-SHA256 base64url encoded. The payload is a canonical binary encoding of
+// the params, so each set of params has exactly one signed form. Tokens are
+// signed with the first key and verified with any key, so a new key can be
+// added first and the old one kept until the links it signed expire.
+//
+// Every token is for one kind of resource, like a heatmap, and is only
+// accepted for that resource. It can carry options for the resource, like
+// what to chart, which are signed with the rest.
+//
+// Tokens carry the generation of the user's links they were signed in. With
+// CheckGenerations, ParseContext only accepts tokens from the user's current
+// generation, so moving a user to a new generation revokes all their links.
+//
+// Tokens from before key IDs, hex encoded JSON signed with HMAC-SHA1, say
+// nothing of what they're for. They are still accepted until they expire, for
+// the resources allowed with AllowLegacy.
 package urlsigner
 
 import (
+	"bytes"
+	"context"
 	"crypto/hmac"
 	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"regexp"
+	"sort"
+	"strings"
 	"time"
 )
 
 type Params struct {
-	TeamID string `json:"t"`
-	UserID string `json:"u"`
-	TZ     int    `json:"z"`
-	Expiry int64  `json:"ts"`
-	HMAC   []byte `json:"h"`
+	// Resource is the kind of resource the params grant access to.
+	Resource string
+	TeamID   string
+	UserID   string
+	TZ       int
+	// Options are how to present the resource, signed so they can't be
+	// changed.
+	Options map[string]string
+	// Generation is the generation of the user's links the params were
+	// signed in.
+	Generation uint64
+	// Expiry is the unix time the signature expires at.
+	Expiry int64
 
-	ExpiryDuration time.Duration `json:"-"`
+	// ExpiryDuration sets Expiry from when the params are signed, if Expiry
+	// isn't set.
+	ExpiryDuration time.Duration
 }
 
 var (
 	ErrInvalidSignature = errors.New("invalid signature")
 	ErrExpiredSignature = errors.New("expired signature")
+	ErrUnknownKey       = fmt.Errorf("%w: unknown key", ErrInvalidSignature)
+	ErrWrongResource    = fmt.Errorf("%w: wrong resource", ErrInvalidSignature)
+	ErrRevoked          = fmt.Errorf("%w: revoked", ErrInvalidSignature)
+
+	// ErrGenerationUnavailable is returned when the user's current generation
+	// can't be looked up, so whether a token is revoked isn't known.
+	ErrGenerationUnavailable = errors.New("link generation unavailable")
 )
 
+// GenerationFunc returns the current generation of a user's links.
+type GenerationFunc func(ctx context.Context, teamID, userID string) (uint64, error)
+
+// Key is a secret key for signing URLs, with the ID tokens refer to it by.
+type Key struct {
+	ID     string
+	Secret []byte
+}
+
+var keyIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+
+// ValidKeyID reports whether id can be used as a key ID: 1 to 32 letters,
+// digits, dashes or underscores.
+func ValidKeyID(id string) bool {
+	return keyIDPattern.MatchString(id)
+}
+
 type Engine struct {
-	key []byte
+	keys    []Key
+	keyByID map[string]Key
+
+	legacyResources map[string]bool
+	generations     GenerationFunc
 }
 
-func New(key []byte) *Engine {
-	return &Engine{key: key}
+// New creates an engine that signs with the first key and verifies with any
+// of them.
+func New(keys ...Key) (*Engine, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("at least one key is required")
+	}
+
+	e := &Engine{keys: keys, keyByID: make(map[string]Key, len(keys)), legacyResources: make(map[string]bool)}
+	for _, k := range keys {
+		if !ValidKeyID(k.ID) {
+			return nil, fmt.Errorf("invalid key id %q", k.ID)
+		}
+		if len(k.Secret) == 0 {
+			return nil, fmt.Errorf("key %q has no secret", k.ID)
+		}
+		if _, ok := e.keyByID[k.ID]; ok {
+			return nil, fmt.Errorf("duplicate key id %q", k.ID)
+		}
+		e.keyByID[k.ID] = k
+	}
+	return e, nil
+}
+
+// AllowLegacy accepts tokens from before key IDs for the resources, which
+// should be those they were handed out for.
+func (e *Engine) AllowLegacy(resources ...string) {
+	for _, r := range resources {
+		e.legacyResources[r] = true
+	}
+}
+
+// CheckGenerations makes ParseContext reject tokens not from the user's
+// current generation, as returned by fn.
+func (e *Engine) CheckGenerations(fn GenerationFunc) {
+	e.generations = fn
 }
 
 func (e *Engine) Sign(p Params) string {
 	if p.Expiry == 0 && p.ExpiryDuration > 0 {
 		p.Expiry = time.Now().Add(p.ExpiryDuration).Unix()
 	}
-	p.HMAC = e.hmac(p)
 
-	b, err := json.Marshal(p)
-	if err != nil {
-		return ""
+	k := e.keys[0]
+	payload := encodeParams(p)
+	return k.ID + "." + b64.EncodeToString(payload) + "." + b64.EncodeToString(mac(k, payload))
+}
+
+// ParseContext is Parse, also rejecting revoked tokens if CheckGenerations
+// was called.
+func (e *Engine) ParseContext(ctx context.Context, sig, resource string) (Params, error) {
+	p, err := e.Parse(sig, resource)
+	if err != nil || e.generations == nil {
+		return p, err
 	}
 
-	return hex.EncodeToString(b)
+	gen, err := e.generations(ctx, p.TeamID, p.UserID)
+	if err != nil {
+		return Params{}, fmt.Errorf("%w: %v", ErrGenerationUnavailable, err)
+	}
+	if p.Generation != gen {
+		return Params{}, ErrRevoked
+	}
+	return p, nil
 }
 
-func (e *Engine) Parse(sig string) (Params, error) {
-	b, err := hex.DecodeString(sig)
-	if err != nil {
-		return Params{}, fmt.Errorf("error decoding string: %w", err)
+// Parse verifies sig is a token for resource, and returns its params. It
+// doesn't check whether the token is revoked.
+func (e *Engine) Parse(sig, resource string) (Params, error) {
+	if !strings.Contains(sig, ".") {
+		if !e.legacyResources[resource] {
+			return Params{}, ErrWrongResource
+		}
+		return e.parseLegacy(sig, resource)
 	}
 
-	var p Params
-	err = json.Unmarshal(b, &p)
+	parts := strings.Split(sig, ".")
+	if len(parts) != 3 {
+		return Params{}, fmt.Errorf("%w: malformed token", ErrInvalidSignature)
+	}
+	k, ok := e.keyByID[parts[0]]
+	if !ok {
+		return Params{}, ErrUnknownKey
+	}
+	payload, err := b64.DecodeString(parts[1])
 	if err != nil {
-		return Params{}, fmt.Errorf("error unmarshaling json: %w", err)
+		return Params{}, fmt.Errorf("error decoding payload: %w", err)
+	}
+	m, err := b64.DecodeString(parts[2])
+	if err != nil {
+		return Params{}, fmt.Errorf("error decoding mac: %w", err)
 	}
 
-	hm := e.hmac(p)
-	if !hmac.Equal(hm, p.HMAC) {
+	// check the mac before looking at the payload, so nothing unsigned is
+	// ever decoded
+	if !hmac.Equal(m, mac(k, payload)) {
 		return Params{}, ErrInvalidSignature
 	}
 
+	p, err := decodeParams(payload)
+	if err != nil {
+		return Params{}, err
+	}
+	if p.Resource != resource {
+		return Params{}, ErrWrongResource
+	}
 	if p.Expiry < time.Now().Unix() {
 		return Params{}, ErrExpiredSignature
 	}
+	return p, nil
+}
+
+var b64 = base64.RawURLEncoding
+
+// tokenVersion starts every payload, and is signed with it, so a payload
+// can't be mistaken for another version's.
+const tokenVersion = 2
+
+func mac(k Key, payload []byte) []byte {
+	m := hmac.New(sha256.New, k.Secret)
+	m.Write([]byte(k.ID))
+	m.Write([]byte{0})
+	m.Write(payload)
+	return m.Sum(nil)
+}
+
+// Payload fields, tagged so that adding one can't change what an existing
+// token means. Fields are encoded in tag order, each once, as the tag, the
+// uvarint length of the value, then the value. Optional fields are left out
+// when empty.
+const (
+	fieldExpiry     byte = 'e'
+	fieldGeneration byte = 'g' // optional
+	fieldOptions    byte = 'o' // optional
+	fieldResource   byte = 'r' // optional
+	fieldTeamID     byte = 't'
+	fieldUserID     byte = 'u'
+	fieldTZ         byte = 'z'
+)
+
+// requiredFields are in every payload.
+var requiredFields = []byte{fieldExpiry, fieldTeamID, fieldUserID, fieldTZ}
+
+func encodeParams(p Params) []byte {
+	var buf bytes.Buffer
+	buf.WriteByte(tokenVersion)
+	writeField(&buf, fieldExpiry, varint(p.Expiry))
+	if p.Generation > 0 {
+		writeField(&buf, fieldGeneration, uvarint(p.Generation))
+	}
+	if len(p.Options) > 0 {
+		writeField(&buf, fieldOptions, encodeOptions(p.Options))
+	}
+	if len(p.Resource) > 0 {
+		writeField(&buf, fieldResource, []byte(p.Resource))
+	}
+	writeField(&buf, fieldTeamID, []byte(p.TeamID))
+	writeField(&buf, fieldUserID, []byte(p.UserID))
+	writeField(&buf, fieldTZ, varint(int64(p.TZ)))
+	return buf.Bytes()
+}
+
+// encodeOptions encodes options in key order, each as the length prefixed
+// key then the length prefixed value.
+func encodeOptions(opts map[string]string) []byte {
+	keys := make([]string, 0, len(opts))
+	for k := range opts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	for _, k := range keys {
+		writeBytes(&buf, []byte(k))
+		writeBytes(&buf, []byte(opts[k]))
+	}
+	return buf.Bytes()
+}
+
+func writeField(buf *bytes.Buffer, tag byte, v []byte) {
+	buf.WriteByte(tag)
+	writeBytes(buf, v)
+}
+
+func writeBytes(buf *bytes.Buffer, v []byte) {
+	var n [binary.MaxVarintLen64]byte
+	buf.Write(n[:binary.PutUvarint(n[:], uint64(len(v)))])
+	buf.Write(v)
+}
+
+// readBytes reads a length prefixed value from b, returning the rest of b.
+func readBytes(b []byte) (v, rest []byte, err error) {
+	n, sz := binary.Uvarint(b)
+	if sz <= 0 || n > uint64(len(b)-sz) {
+		return nil, nil, errMalformedPayload
+	}
+	return b[sz : sz+int(n)], b[sz+int(n):], nil
+}
+
+func varint(v int64) []byte {
+	var b [binary.MaxVarintLen64]byte
+	return b[:binary.PutVarint(b[:], v)]
+}
 
+func uvarint(v uint64) []byte {
+	var b [binary.MaxVarintLen64]byte
+	return b[:binary.PutUvarint(b[:], v)]
+}
+
+var errMalformedPayload = fmt.Errorf("%w: malformed payload", ErrInvalidSignature)
+
+// decodeParams decodes a payload from encodeParams, rejecting anything it
+// wouldn't have written.
+func decodeParams(b []byte) (Params, error) {
+	if len(b) == 0 || b[0] != tokenVersion {
+		return Params{}, errMalformedPayload
+	}
+	b = b[1:]
+
+	fields := make(map[byte][]byte)
+	var last byte
+	for len(b) > 0 {
+		tag := b[0]
+		if tag <= last {
+			return Params{}, errMalformedPayload
+		}
+		last = tag
+
+		v, rest, err := readBytes(b[1:])
+		if err != nil {
+			return Params{}, err
+		}
+		fields[tag] = v
+		b = rest
+	}
+
+	var p Params
+	for tag, v := range fields {
+		var err error
+		switch tag {
+		case fieldExpiry:
+			p.Expiry, err = readVarint(v)
+		case fieldGeneration:
+			p.Generation, err = readUvarint(v)
+			if err == nil && p.Generation == 0 {
+				err = errMalformedPayload
+			}
+		case fieldOptions:
+			p.Options, err = decodeOptions(v)
+		case fieldResource:
+			if len(v) == 0 {
+				err = errMalformedPayload
+			}
+			p.Resource = string(v)
+		case fieldTeamID:
+			p.TeamID = string(v)
+		case fieldUserID:
+			p.UserID = string(v)
+		case fieldTZ:
+			var tz int64
+			tz, err = readVarint(v)
+			p.TZ = int(tz)
+		default:
+			err = errMalformedPayload
+		}
+		if err != nil {
+			return Params{}, err
+		}
+	}
+	for _, tag := range requiredFields {
+		if _, ok := fields[tag]; !ok {
+			return Params{}, errMalformedPayload
+		}
+	}
 	return p, nil
 }
 
-func (e *Engine) hmac(p Params) []byte {
-	mac := hmac.New(sha1.New, e.key)
-	mac.Write([]byte(fmt.Sprintf("%s:%s:%d:%d", p.TeamID, p.UserID, p.TZ, p.Expiry)))
-	return mac.Sum(nil)
+// decodeOptions decodes options from encodeOptions, rejecting anything it
+// wouldn't have written.
+func decodeOptions(b []byte) (map[string]string, error) {
+	if len(b) == 0 {
+		return nil, errMalformedPayload
+	}
+
+	opts := make(map[string]string)
+	var last string
+	for len(b) > 0 {
+		k, rest, err := readBytes(b)
+		if err != nil {
+			return nil, err
+		}
+		v, rest, err := readBytes(rest)
+		if err != nil {
+			return nil, err
+		}
+		if len(opts) > 0 && string(k) <= last {
+			return nil, errMalformedPayload
+		}
+		last = string(k)
+		opts[last] = string(v)
+		b = rest
+	}
+	return opts, nil
+}
+
+func readVarint(b []byte) (int64, error) {
+	v, n := binary.Varint(b)
+	if n != len(b) {
+		return 0, errMalformedPayload
+	}
+	return v, nil
+}
+
+func readUvarint(b []byte) (uint64, error) {
+	v, n := binary.Uvarint(b)
+	if n != len(b) {
+		return 0, errMalformedPayload
+	}
+	return v, nil
+}
+
+// legacyParams is the hex encoded JSON form of params from before key IDs.
+type legacyParams struct {
+	TeamID string `json:"t"`
+	UserID string `json:"u"`
+	TZ     int    `json:"z"`
+	Expiry int64  `json:"ts"`
+	HMAC   []byte `json:"h"`
+}
+
+// parseLegacy parses a token from before key IDs. They don't say which key
+// signed them, so every key is tried.
+func (e *Engine) parseLegacy(sig, resource string) (Params, error) {
+	b, err := hex.DecodeString(sig)
+	if err != nil {
+		return Params{}, fmt.Errorf("error decoding string: %w", err)
+	}
+
+	var lp legacyParams
+	err = json.Unmarshal(b, &lp)
+	if err != nil {
+		return Params{}, fmt.Errorf("error unmarshaling json: %w", err)
+	}
+
+	msg := []byte(fmt.Sprintf("%s:%s:%d:%d", lp.TeamID, lp.UserID, lp.TZ, lp.Expiry))
+	for _, k := range e.keys {
+		m := hmac.New(sha1.New, k.Secret)
+		m.Write(msg)
+		if !hmac.Equal(m.Sum(nil), lp.HMAC) {
+			continue
+		}
+
+		if lp.Expiry < time.Now().Unix() {
+			return Params{}, ErrExpiredSignature
+		}
+		return Params{Resource: resource, TeamID: lp.TeamID, UserID: lp.UserID, TZ: lp.TZ, Expiry: lp.Expiry}, nil
+	}
+	return Params{}, ErrInvalidSignature
 }
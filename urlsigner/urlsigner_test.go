@@ -1,8 +1,15 @@
 package urlsigner
 
 import (
+	"context"
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"net/url"
+	"strings"
 	"testing"
 	"time"
 
@@ -14,47 +21,262 @@ func TestSignAndParse(t *testing.T) {
 		p   Params
 		err error
 	}{
-		"ok":      {p: Params{TeamID: "t1235", UserID: "u492skdjf", TZ: -2, ExpiryDuration: time.Hour}, err: nil},
-		"expired": {p: Params{TeamID: "t1235", UserID: "u492skdjf", TZ: -2, ExpiryDuration: -1 * time.Second}, err: ErrExpiredSignature},
+		"ok":      {p: Params{Resource: "chart", TeamID: "t1235", UserID: "u492skdjf", TZ: -2, ExpiryDuration: time.Hour}, err: nil},
+		"options": {p: Params{Resource: "chart", TeamID: "t1235", UserID: "u492skdjf", Options: map[string]string{"metric": "work_day_quality", "range": "30", "empty": ""}, ExpiryDuration: time.Hour}, err: nil},
+		"expired": {p: Params{Resource: "chart", TeamID: "t1235", UserID: "u492skdjf", TZ: -2, ExpiryDuration: -1 * time.Second}, err: ErrExpiredSignature},
+		"past":    {p: Params{Resource: "chart", TeamID: "t1235", UserID: "u492skdjf", TZ: 5, Expiry: time.Now().Add(-time.Minute).Unix()}, err: ErrExpiredSignature},
+		"empty":   {p: Params{Expiry: time.Now().Add(time.Hour).Unix()}, err: nil},
 	}
 
 	for name, tc := range tcs {
 		t.Run(name, func(t *testing.T) {
-			e := New(randKey())
+			e := newEngine(t, randKey("k1"))
 
 			sig := e.Sign(tc.p)
 			require.NotEmpty(t, sig, "Signature should not be empty")
+			require.Equal(t, url.PathEscape(sig), sig, "Signature should be safe in a URL path")
+
+			p, err := e.Parse(sig, tc.p.Resource)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.p.Resource, p.Resource)
+			require.Equal(t, tc.p.TeamID, p.TeamID)
+			require.Equal(t, tc.p.UserID, p.UserID)
+			require.Equal(t, tc.p.TZ, p.TZ)
+			require.Equal(t, len(tc.p.Options), len(p.Options))
+			for k, v := range tc.p.Options {
+				require.Equal(t, v, p.Options[k], "option %s", k)
+			}
+		})
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	k1, k2 := randKey("2021-07"), randKey("2021-10")
+	p := Params{Resource: "chart", TeamID: "T1", UserID: "U1", ExpiryDuration: time.Hour}
+
+	old := newEngine(t, k1).Sign(p)
+	rotating := newEngine(t, k2, k1)
+	_, err := rotating.Parse(old, "chart")
+	require.NoError(t, err, "links signed with the previous key should still work")
+
+	sig := rotating.Sign(p)
+	require.True(t, strings.HasPrefix(sig, "2021-10."), "new links should be signed with the first key")
+
+	_, err = newEngine(t, k2).Parse(old, "chart")
+	require.ErrorIs(t, err, ErrUnknownKey, "links signed with a retired key should stop working")
+	require.ErrorIs(t, err, ErrInvalidSignature)
+}
+
+func TestParseLegacy(t *testing.T) {
+	k := randKey("legacy")
+	e := newEngine(t, randKey("new"), k)
+	sig := legacySign(k.Secret, "T1", "U1", -4, time.Now().Add(time.Hour).Unix())
+
+	_, err := e.Parse(sig, "chart")
+	require.ErrorIs(t, err, ErrWrongResource, "legacy tokens should be rejected unless allowed")
+
+	e.AllowLegacy("chart")
+	p, err := e.Parse(sig, "chart")
+	require.NoError(t, err)
+	require.Equal(t, "chart", p.Resource)
+	require.Equal(t, "T1", p.TeamID)
+	require.Equal(t, "U1", p.UserID)
+	require.Equal(t, -4, p.TZ)
+	require.Empty(t, p.Options)
+
+	_, err = e.Parse(sig, "other")
+	require.ErrorIs(t, err, ErrWrongResource)
+
+	_, err = e.Parse(legacySign(k.Secret, "T1", "U1", -4, time.Now().Add(-time.Hour).Unix()), "chart")
+	require.ErrorIs(t, err, ErrExpiredSignature)
+
+	_, err = e.Parse(legacySign(randKey("other").Secret, "T1", "U1", -4, time.Now().Add(time.Hour).Unix()), "chart")
+	require.ErrorIs(t, err, ErrInvalidSignature)
+}
+
+func TestParseWrongResource(t *testing.T) {
+	e := newEngine(t, randKey("k1"))
+	exp := time.Now().Add(time.Hour).Unix()
+
+	tcs := map[string]struct {
+		signed, parsed string
+	}{
+		"other resource": {signed: "heatmap", parsed: "report"},
+		"no resource":    {signed: "", parsed: "report"},
+		"any resource":   {signed: "heatmap", parsed: ""},
+		"prefix":         {signed: "heat", parsed: "heatmap"},
+	}
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			sig := e.Sign(Params{Resource: tc.signed, TeamID: "T1", UserID: "U1", Expiry: exp})
+			_, err := e.Parse(sig, tc.parsed)
+			require.ErrorIs(t, err, ErrWrongResource)
+			require.ErrorIs(t, err, ErrInvalidSignature)
+		})
+	}
+}
+
+func TestParseContextChecksGenerations(t *testing.T) {
+	e := newEngine(t, randKey("k1"))
+	exp := time.Now().Add(time.Hour).Unix()
+	current := map[string]uint64{"U0": 0, "U1": 1, "U2": 2}
+	e.CheckGenerations(func(ctx context.Context, teamID, userID string) (uint64, error) {
+		gen, ok := current[userID]
+		if !ok {
+			return 0, fmt.Errorf("no such user %s", userID)
+		}
+		return gen, nil
+	})
+
+	tcs := map[string]struct {
+		p   Params
+		err error
+	}{
+		"never reset":   {p: Params{Resource: "chart", TeamID: "T1", UserID: "U0", Expiry: exp}, err: nil},
+		"current":       {p: Params{Resource: "chart", TeamID: "T1", UserID: "U2", Generation: 2, Expiry: exp}, err: nil},
+		"reset since":   {p: Params{Resource: "chart", TeamID: "T1", UserID: "U2", Generation: 1, Expiry: exp}, err: ErrRevoked},
+		"before resets": {p: Params{Resource: "chart", TeamID: "T1", UserID: "U1", Expiry: exp}, err: ErrRevoked},
+		"from future":   {p: Params{Resource: "chart", TeamID: "T1", UserID: "U1", Generation: 2, Expiry: exp}, err: ErrRevoked},
+		"lookup fails":  {p: Params{Resource: "chart", TeamID: "T1", UserID: "U9", Expiry: exp}, err: ErrGenerationUnavailable},
+	}
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			sig := e.Sign(tc.p)
+			p, err := e.ParseContext(context.Background(), sig, "chart")
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.p.Generation, p.Generation)
+
+			_, err = e.Parse(sig, "chart")
+			require.NoError(t, err)
 		})
 	}
+
+	e.AllowLegacy("chart")
+	_, err := e.ParseContext(context.Background(), legacySign(e.keys[0].Secret, "T1", "U1", 0, exp), "chart")
+	require.ErrorIs(t, err, ErrRevoked, "legacy links should be revoked by a reset")
 }
 
 func TestParseEdges(t *testing.T) {
-	e := New(randKey())
-	valid := e.Sign(Params{Expiry: time.Now().Add(time.Hour).Unix()})
+	k := randKey("k1")
+	e := newEngine(t, k)
+	valid := e.Sign(Params{Resource: "chart", TeamID: "T1", UserID: "U1", Expiry: time.Now().Add(time.Hour).Unix()})
+	parts := strings.Split(valid, ".")
+
+	other := newEngine(t, Key{ID: "k1", Secret: randKey("k1").Secret}).Sign(Params{Resource: "chart", Expiry: time.Now().Add(time.Hour).Unix()})
+	tampered := encodeParams(Params{Resource: "chart", TeamID: "T1", UserID: "U2", Expiry: time.Now().Add(time.Hour).Unix()})
 
 	tcs := map[string]string{
 		"empty":            "",
 		"empty json":       "7b7d",
-		"wrong secret key": New(randKey()).Sign(Params{Expiry: time.Now().Add(time.Hour).Unix()}),
+		"wrong secret key": other,
 		"garbage prefix":   fmt.Sprintf("00%s", valid),
 		"garbage suffix":   fmt.Sprintf("%s00", valid),
 		"garbage":          "asdlkfjsldkjflskdjfl",
+		"extra part":       valid + ".x",
+		"missing mac":      parts[0] + "." + parts[1],
+		"other key id":     "k2." + parts[1] + "." + parts[2],
+		"tampered payload": parts[0] + "." + b64.EncodeToString(tampered) + "." + parts[2],
 	}
 
 	for name, in := range tcs {
 		t.Run(name, func(t *testing.T) {
 
-			_, err := e.Parse(in)
+			_, err := e.Parse(in, "chart")
 			require.Error(t, err, "")
 		})
 	}
 }
 
-func randKey() []byte {
+func TestDecodeParamsIsCanonical(t *testing.T) {
+	p := Params{Resource: "chart", TeamID: "T1", UserID: "U1", TZ: -5, Options: map[string]string{"b": "2", "a": "1"}, Generation: 3, Expiry: 1627000000}
+	b := encodeParams(p)
+	got, err := decodeParams(b)
+	require.NoError(t, err)
+	require.Equal(t, p, got)
+
+	field := func(tag byte, v string) []byte { return append([]byte{tag, byte(len(v))}, v...) }
+	opt := func(k, v string) string { return string(rune(len(k))) + k + string(rune(len(v))) + v }
+	exp := append([]byte{fieldExpiry, 1}, varint(1)...)
+	rest := concat(field(fieldTeamID, "T1"), field(fieldUserID, "U1"), append([]byte{fieldTZ, 1}, varint(0)...))
+	tcs := map[string][]byte{
+		"empty options":     concat([]byte{tokenVersion}, exp, field(fieldOptions, ""), rest),
+		"unsorted options":  concat([]byte{tokenVersion}, exp, field(fieldOptions, opt("b", "2")+opt("a", "1")), rest),
+		"duplicate option":  concat([]byte{tokenVersion}, exp, field(fieldOptions, opt("a", "1")+opt("a", "2")), rest),
+		"truncated options": concat([]byte{tokenVersion}, exp, field(fieldOptions, opt("a", "1")[:2]), rest),
+		"empty resource":    concat([]byte{tokenVersion}, exp, field(fieldResource, ""), rest),
+		"zero generation":   concat([]byte{tokenVersion}, exp, append([]byte{fieldGeneration, 1}, uvarint(0)...), rest),
+		"wrong version":     append([]byte{1}, b[1:]...),
+		"trailing byte":     append(append([]byte{}, b...), 0),
+		"out of order":      concat([]byte{tokenVersion}, field(fieldTeamID, "T1"), exp, field(fieldUserID, "U1"), append([]byte{fieldTZ, 1}, varint(0)...)),
+		"duplicate":         concat([]byte{tokenVersion}, exp, field(fieldTeamID, "T1"), field(fieldTeamID, "T2"), field(fieldUserID, "U1"), append([]byte{fieldTZ, 1}, varint(0)...)),
+		"missing tz":        concat([]byte{tokenVersion}, exp, field(fieldTeamID, "T1"), field(fieldUserID, "U1")),
+		"unknown":           concat([]byte{tokenVersion}, exp, field(fieldTeamID, "T1"), field(fieldUserID, "U1"), field('x', "?"), append([]byte{fieldTZ, 1}, varint(0)...)),
+		"long length":       concat([]byte{tokenVersion}, []byte{fieldExpiry, 200}),
+	}
+	for name, in := range tcs {
+		t.Run(name, func(t *testing.T) {
+			_, err := decodeParams(in)
+			require.ErrorIs(t, err, ErrInvalidSignature)
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	_, err := New()
+	require.Error(t, err)
+	_, err = New(Key{ID: "a.b", Secret: []byte("s")})
+	require.Error(t, err, "key ids can't contain the token separator")
+	_, err = New(Key{ID: "a", Secret: []byte("s")}, Key{ID: "a", Secret: []byte("t")})
+	require.Error(t, err)
+	_, err = New(Key{ID: "a"})
+	require.Error(t, err)
+}
+
+func TestTokensAreShorterThanLegacy(t *testing.T) {
+	k := randKey("2021-10")
+	exp := time.Now().Add(time.Hour).Unix()
+	sig := newEngine(t, k).Sign(Params{TeamID: "T0123456789", UserID: "U0123456789", TZ: -4, Expiry: exp})
+	require.Less(t, len(sig), len(legacySign(k.Secret, "T0123456789", "U0123456789", -4, exp)))
+}
+
+func newEngine(t *testing.T, keys ...Key) *Engine {
+	e, err := New(keys...)
+	require.NoError(t, err)
+	return e
+}
+
+// legacySign signs params the way tokens were before key IDs.
+func legacySign(key []byte, tid, uid string, tz int, expiry int64) string {
+	m := hmac.New(sha1.New, key)
+	m.Write([]byte(fmt.Sprintf("%s:%s:%d:%d", tid, uid, tz, expiry)))
+	b, err := json.Marshal(legacyParams{TeamID: tid, UserID: uid, TZ: tz, Expiry: expiry, HMAC: m.Sum(nil)})
+	if err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+func concat(bb ...[]byte) []byte {
+	var out []byte
+	for _, b := range bb {
+		out = append(out, b...)
+	}
+	return out
+}
+
+func randKey(id string) Key {
 	b := make([]byte, 256)
 	_, err := rand.Read(b)
 	if err != nil {
 		panic(fmt.Sprintf("error reading random bytes: %v", err))
 	}
-	return b
+	return Key{ID: id, Secret: b}
 }
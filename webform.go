@@ -0,0 +1,251 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jharlap/good-day-app/i18n"
+	"github.com/jharlap/good-day-app/metrics"
+	"github.com/jharlap/good-day-app/reflection"
+	"github.com/jharlap/good-day-app/urlsigner"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	webFormResource = "reflect"
+	// webFormExpiry is how long a link to the web form works. The home tab
+	// makes a new link whenever it's rendered.
+	webFormExpiry = 12 * time.Hour
+	// csrfCookie holds the token the form must be posted with, so that other
+	// sites can't post answers with the user's link.
+	csrfCookie = "good_day_csrf"
+	// maxWebFormBytes is far more than a form of answers needs.
+	maxWebFormBytes = 16 << 10
+)
+
+//go:embed assets/reflect.html
+var webFormHTML string
+
+var webFormTemplate = template.Must(template.New("reflect").Parse(webFormHTML))
+
+// webForm is what the web form template is executed with.
+type webForm struct {
+	Lang      string
+	Title     string
+	Header    string
+	Submit    string
+	CSRF      string
+	Error     string
+	Saved     string
+	Questions []webFormQuestion
+}
+
+type webFormQuestion struct {
+	Field       string
+	Text        string
+	Placeholder string
+	Options     []reflection.Option
+	Answer      string
+	Error       string
+}
+
+// webFormURL returns a signed link to the web form for the user to reflect
+// from a browser.
+func (a *App) webFormURL(tid, uid string, su slackUser) string {
+	sig := a.signer.Sign(urlsigner.Params{
+		Resource:       webFormResource,
+		TeamID:         tid,
+		UserID:         uid,
+		TZ:             su.TZOffset / 3600,
+		Generation:     su.Settings.LinkGeneration,
+		ExpiryDuration: webFormExpiry,
+	})
+	return fmt.Sprintf("%s/reflect/%s", a.baseURL, sig)
+}
+
+// handleWebForm shows the web form for a signed link, and saves the
+// reflection posted from it.
+func (a *App) handleWebForm(w http.ResponseWriter, r *http.Request) {
+	i := strings.LastIndex(r.URL.Path, "/")
+	if i < 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	p, err := a.signer.ParseContext(r.Context(), r.URL.Path[i+1:], webFormResource)
+	if errors.Is(err, urlsigner.ErrInvalidSignature) {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Debug().Err(err).Str("path", r.URL.Path).Msg("invalid url signing")
+		return
+	} else if errors.Is(err, urlsigner.ErrGenerationUnavailable) {
+		w.WriteHeader(http.StatusInternalServerError)
+		log.Error().Err(err).Msg("error checking web form url is current")
+		return
+	} else if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		log.Debug().Err(err).Str("path", r.URL.Path).Msg("invalid url signing")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		su := a.lookupUser(r.Context(), p.TeamID, p.UserID)
+		a.writeWebForm(w, r, http.StatusOK, newWebForm(su.Lang, nil))
+	case http.MethodPost:
+		a.submitWebForm(w, r, p)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (a *App) submitWebForm(w http.ResponseWriter, r *http.Request, p urlsigner.Params) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxWebFormBytes)
+	if err := r.ParseForm(); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		log.Debug().Err(err).Msg("error parsing web form")
+		return
+	}
+
+	su := a.lookupUser(r.Context(), p.TeamID, p.UserID)
+	rf, f, ok := webFormReflection(r.PostForm, su.Lang)
+	if c, err := r.Cookie(csrfCookie); err != nil || !validCSRF(c.Value, r.PostFormValue("csrf")) {
+		// most likely the cookie expired, so keep the answers and let the
+		// user post them again with a new token
+		log.Debug().Err(err).Str("uid", p.UserID).Msg("web form posted without a valid csrf token")
+		f.Error = i18n.T(su.Lang, "webform.stale")
+		a.writeWebForm(w, r, http.StatusForbidden, f)
+		return
+	}
+	f.CSRF = r.PostFormValue("csrf")
+	if !ok {
+		a.writeWebForm(w, r, http.StatusBadRequest, f)
+		return
+	}
+
+	rf.TeamID = p.TeamID
+	rf.UserID = p.UserID
+	rf.Date = time.Now()
+	err := a.submitReflection(r.Context(), rf, su, metrics.SourceWeb)
+	if err != nil {
+		log.Error().Err(err).Str("tid", p.TeamID).Str("uid", p.UserID).Msg("error saving web form reflection")
+		f.Error = i18n.T(su.Lang, "webform.save_failed")
+		a.writeWebForm(w, r, http.StatusInternalServerError, f)
+		return
+	}
+
+	// the token is spent, so reposting the page can't save the answers twice
+	http.SetCookie(w, &http.Cookie{Name: csrfCookie, Path: r.URL.Path, MaxAge: -1, HttpOnly: true, Secure: a.secureCookies(), SameSite: http.SameSiteStrictMode})
+	f.Questions = nil
+	f.Saved = i18n.T(su.Lang, "webform.saved")
+	a.writeWebForm(w, r, http.StatusOK, f)
+}
+
+// newWebForm returns the web form in lang, with answers preselected.
+func newWebForm(lang string, answers url.Values) webForm {
+	f := webForm{
+		Lang:   lang,
+		Title:  i18n.T(lang, "modal.title"),
+		Header: i18n.T(lang, "modal.header"),
+		Submit: i18n.T(lang, "modal.submit"),
+	}
+	for _, q := range reflection.LocalizedQuestions(lang) {
+		f.Questions = append(f.Questions, webFormQuestion{
+			Field:       q.Field,
+			Text:        q.Text,
+			Placeholder: q.Options.Placeholder,
+			Options:     q.Options.Options,
+			Answer:      answers.Get(q.Field),
+		})
+	}
+	return f
+}
+
+// webFormReflection returns the reflection answered in a posted form, and
+// the form to show again with its answers. Like the Slack modal, every
+// question must be answered with one of its options; if any isn't, ok is
+// false and the form has the errors.
+func webFormReflection(form url.Values, lang string) (r reflection.Reflection, f webForm, ok bool) {
+	f = newWebForm(lang, form)
+	ok = true
+	for i, q := range reflection.LocalizedQuestions(lang) {
+		code := form.Get(q.Field)
+		if len(code) == 0 {
+			f.Questions[i].Error = i18n.T(lang, "webform.required")
+			ok = false
+			continue
+		}
+		if len(q.Options.ValueFor(code)) == 0 {
+			f.Questions[i].Answer = ""
+			f.Questions[i].Error = i18n.T(lang, "webform.invalid")
+			ok = false
+			continue
+		}
+		r.SetValueForQuestion(q.Field, reflection.NumberPrefixedEnum(code))
+	}
+	return r, f, ok
+}
+
+// writeWebForm writes the form with status, with a new CSRF token unless
+// the form already has one.
+func (a *App) writeWebForm(w http.ResponseWriter, r *http.Request, status int, f webForm) {
+	if len(f.CSRF) == 0 && len(f.Saved) == 0 {
+		token, err := newCSRFToken()
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			log.Error().Err(err).Msg("error creating csrf token")
+			return
+		}
+		f.CSRF = token
+		http.SetCookie(w, &http.Cookie{
+			Name:     csrfCookie,
+			Value:    token,
+			Path:     r.URL.Path,
+			MaxAge:   int(webFormExpiry / time.Second),
+			HttpOnly: true,
+			Secure:   a.secureCookies(),
+			SameSite: http.SameSiteStrictMode,
+		})
+	}
+
+	h := w.Header()
+	h.Set("Content-Type", "text/html; charset=utf-8")
+	h.Set("Cache-Control", "no-store")
+	// the URL is the credential, so it mustn't leak in a referrer
+	h.Set("Referrer-Policy", "no-referrer")
+	h.Set("X-Content-Type-Options", "nosniff")
+	h.Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; form-action 'self'; base-uri 'none'; frame-ancestors 'none'")
+	w.WriteHeader(status)
+	if err := webFormTemplate.Execute(w, f); err != nil {
+		log.Error().Err(err).Msg("error writing web form")
+	}
+}
+
+// secureCookies is true if the app is served over HTTPS, so cookies can be
+// kept off plain HTTP.
+func (a *App) secureCookies() bool {
+	return strings.HasPrefix(a.baseURL, "https://")
+}
+
+func newCSRFToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("error reading random bytes: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// validCSRF is true if the token posted with a form matches the one in its
+// cookie.
+func validCSRF(cookie, posted string) bool {
+	return len(cookie) > 0 && subtle.ConstantTimeCompare([]byte(cookie), []byte(posted)) == 1
+}
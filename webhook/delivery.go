@@ -0,0 +1,282 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	"github.com/jharlap/good-day-app/metrics"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// MaxAttempts is how many times a delivery is tried before it's given
+	// up on. With the backoff, the last attempt is about a day and a half
+	// after the first.
+	MaxAttempts = 15
+
+	// EventHeader and DeliveryHeader are the headers deliveries are sent
+	// with, for the event's type and the delivery's id.
+	EventHeader    = "X-GoodDay-Event"
+	DeliveryHeader = "X-GoodDay-Delivery"
+
+	deliveryTimeout = 10 * time.Second
+	// leaseTimeout is how long a claimed delivery is left before it's tried
+	// again, in case whoever claimed it never finished.
+	leaseTimeout = deliveryTimeout + time.Minute
+
+	firstBackoff = 30 * time.Second
+	maxBackoff   = 6 * time.Hour
+
+	pollInterval  = 10 * time.Second
+	pruneInterval = time.Hour
+	// retention is how long finished deliveries are kept in the log.
+	retention = 30 * 24 * time.Hour
+
+	// batchSize is the most deliveries one DeliverDue tries.
+	batchSize    = 20
+	maxErrLength = 255
+)
+
+// backoff is how long to wait after the nth failed attempt before trying
+// again: 30s, doubling each time, up to 6h.
+func backoff(n int) time.Duration {
+	b := firstBackoff
+	for i := 1; i < n && b < maxBackoff; i++ {
+		b *= 2
+	}
+	if b > maxBackoff {
+		return maxBackoff
+	}
+	return b
+}
+
+// due is a delivery ready to be tried, with what's needed to send it.
+type due struct {
+	ID            int64     `db:"id"`
+	Event         string    `db:"event"`
+	Payload       []byte    `db:"payload"`
+	Attempts      int       `db:"attempts"`
+	NextAttemptAt time.Time `db:"next_attempt_at"`
+	URL           string    `db:"url"`
+	Secret        string    `db:"secret"`
+}
+
+// DeliverDue tries every delivery that's due, up to a batch. It stops
+// starting deliveries when ctx is done, but finishes the one in flight so
+// its attempt is recorded.
+func (d *Dispatcher) DeliverDue(ctx context.Context) error {
+	var dd []due
+	start := time.Now()
+	err := d.db.SelectContext(ctx, &dd, "SELECT d.id, d.event, d.payload, d.attempts, d.next_attempt_at, w.url, w.secret FROM webhook_deliveries d JOIN webhooks w ON w.id = d.webhook_id WHERE d.status = ? AND d.next_attempt_at <= ? AND w.revoked_at IS NULL ORDER BY d.next_attempt_at LIMIT ?", StatusPending, time.Now(), batchSize)
+	d.metrics.ObserveQuery("due_webhook_deliveries", start, err)
+	if err != nil {
+		return fmt.Errorf("error getting due webhook deliveries: %w", err)
+	}
+
+	for _, dl := range dd {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := d.deliver(dl); err != nil {
+			log.Error().Err(err).Int64("delivery", dl.ID).Msg("error delivering webhook")
+		}
+	}
+	return nil
+}
+
+// deliver claims dl, so no one else tries it at the same time, then sends it
+// and records how it went.
+func (d *Dispatcher) deliver(dl due) error {
+	ctx, cancel := context.WithTimeout(context.Background(), leaseTimeout)
+	defer cancel()
+
+	start := time.Now()
+	res, err := d.db.ExecContext(ctx, "UPDATE webhook_deliveries SET next_attempt_at = ? WHERE id = ? AND status = ? AND next_attempt_at = ?", start.Add(leaseTimeout), dl.ID, StatusPending, dl.NextAttemptAt)
+	d.metrics.ObserveQuery("claim_webhook_delivery", start, err)
+	if err != nil {
+		return fmt.Errorf("error claiming webhook delivery: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("error claiming webhook delivery: %w", err)
+	} else if n == 0 {
+		// someone else got to it first
+		return nil
+	}
+
+	code, err := d.send(ctx, dl)
+	attempts := dl.Attempts + 1
+	start = time.Now()
+	switch {
+	case err == nil:
+		_, err = d.db.ExecContext(ctx, "UPDATE webhook_deliveries SET status = ?, attempts = ?, last_status_code = ?, last_error = NULL, delivered_at = ? WHERE id = ?", StatusDelivered, attempts, code, start, dl.ID)
+		d.metrics.WebhookDelivery(metrics.DeliveryDelivered)
+	case attempts >= MaxAttempts:
+		_, err = d.db.ExecContext(ctx, "UPDATE webhook_deliveries SET status = ?, attempts = ?, last_status_code = ?, last_error = ? WHERE id = ?", StatusFailed, attempts, statusCode(code), truncate(err.Error(), maxErrLength), dl.ID)
+		d.metrics.WebhookDelivery(metrics.DeliveryFailed)
+	default:
+		_, err = d.db.ExecContext(ctx, "UPDATE webhook_deliveries SET attempts = ?, last_status_code = ?, last_error = ?, next_attempt_at = ? WHERE id = ?", attempts, statusCode(code), truncate(err.Error(), maxErrLength), start.Add(backoff(attempts)), dl.ID)
+		d.metrics.WebhookDelivery(metrics.DeliveryRetrying)
+	}
+	d.metrics.ObserveQuery("record_webhook_delivery", start, err)
+	if err != nil {
+		return fmt.Errorf("error recording webhook delivery: %w", err)
+	}
+	return nil
+}
+
+// send posts dl's payload to its webhook, returning the response's status
+// code, if there was a response, and an error unless it was a 2xx.
+func (d *Dispatcher) send(ctx context.Context, dl due) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, dl.URL, bytes.NewReader(dl.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "GoodDay-Webhooks/1.0")
+	req.Header.Set(EventHeader, dl.Event)
+	req.Header.Set(DeliveryHeader, fmt.Sprint(dl.ID))
+	req.Header.Set(SignatureHeader, Sign(dl.Secret, time.Now(), dl.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// drain some of the body so the connection can be reused
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// Run delivers due deliveries every pollInterval, and prunes the delivery
+// log every pruneInterval, until ctx is done.
+func (d *Dispatcher) Run(ctx context.Context) {
+	poll := time.NewTicker(pollInterval)
+	defer poll.Stop()
+	prune := time.NewTicker(pruneInterval)
+	defer prune.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-poll.C:
+			if err := d.DeliverDue(ctx); err != nil && !errors.Is(err, context.Canceled) {
+				log.Error().Err(err).Msg("error delivering webhooks")
+			}
+		case <-prune.C:
+			if err := d.prune(ctx); err != nil && !errors.Is(err, context.Canceled) {
+				log.Error().Err(err).Msg("error pruning webhook deliveries")
+			}
+		}
+	}
+}
+
+// prune deletes deliveries older than retention that are finished, or that
+// never will be since their webhook was removed.
+func (d *Dispatcher) prune(ctx context.Context) error {
+	start := time.Now()
+	_, err := d.db.ExecContext(ctx, "DELETE FROM webhook_deliveries WHERE created_at < ? AND (status <> ? OR webhook_id IN (SELECT id FROM webhooks WHERE revoked_at IS NOT NULL))", start.Add(-retention), StatusPending)
+	d.metrics.ObserveQuery("prune_webhook_deliveries", start, err)
+	if err != nil {
+		return fmt.Errorf("error pruning webhook deliveries: %w", err)
+	}
+	return nil
+}
+
+// statusCode is code for the database, or NULL if there was no response.
+func statusCode(code int) interface{} {
+	if code == 0 {
+		return nil
+	}
+	return code
+}
+
+// truncate shortens s to at most n runes.
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n])
+}
+
+// blockedNets are the addresses webhooks can't be delivered to, so a webhook
+// can't be used to reach anything that's only reachable from inside the
+// app's network.
+var blockedNets = parseCIDRs(
+	"0.0.0.0/8",
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"127.0.0.0/8",
+	"169.254.0.0/16",
+	"172.16.0.0/12",
+	"192.168.0.0/16",
+	"224.0.0.0/4",
+	"::/128",
+	"::1/128",
+	"fc00::/7",
+	"fe80::/10",
+	"ff00::/8",
+)
+
+func parseCIDRs(cidrs ...string) []*net.IPNet {
+	nn := make([]*net.IPNet, len(cidrs))
+	for i, c := range cidrs {
+		_, n, err := net.ParseCIDR(c)
+		if err != nil {
+			panic(err)
+		}
+		nn[i] = n
+	}
+	return nn
+}
+
+var errBlockedAddress = errors.New("webhook address is not public")
+
+// checkPublic refuses to connect to blocked addresses. It's checked as the
+// connection is made, after the host is resolved, so DNS can't be used to
+// get around it.
+func checkPublic(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return fmt.Errorf("%w: %s", errBlockedAddress, host)
+	}
+	for _, n := range blockedNets {
+		if n.Contains(ip) {
+			return fmt.Errorf("%w: %s", errBlockedAddress, host)
+		}
+	}
+	return nil
+}
+
+// publicTransport is a transport that only connects to public addresses,
+// and never through a proxy.
+func publicTransport() *http.Transport {
+	dialer := &net.Dialer{
+		Timeout: deliveryTimeout,
+		Control: checkPublic,
+	}
+	return &http.Transport{
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   deliveryTimeout,
+		ResponseHeaderTimeout: deliveryTimeout,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       90 * time.Second,
+	}
+}
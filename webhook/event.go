@@ -0,0 +1,97 @@
+package webhook
+
+import (
+	"time"
+
+	"github.com/jharlap/good-day-app/export"
+	"github.com/jharlap/good-day-app/i18n"
+	"github.com/jharlap/good-day-app/reflection"
+)
+
+// Types of events.
+const (
+	EventReflectionCreated   = "reflection.created"
+	EventReflectionUpdated   = "reflection.updated"
+	EventReflectionsDeleted  = "reflections.deleted"
+	EventReflectionsImported = "reflections.imported"
+)
+
+// Event is what's posted to webhooks. Every webhook an event is delivered
+// to gets the same ID, so receivers can tell retries apart from new events.
+type Event struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	TeamID    string    `json:"team_id"`
+	UserID    string    `json:"user_id"`
+
+	// Reflection is the reflection created or updated.
+	Reflection *export.Reflection `json:"reflection,omitempty"`
+	// Deleted is what was deleted.
+	Deleted *Deletion `json:"deleted,omitempty"`
+	// Imported is what was imported.
+	Imported *Import `json:"imported,omitempty"`
+}
+
+// Deletion is the range reflections were deleted from, with no bounds when
+// all of the user's reflections were deleted.
+type Deletion struct {
+	From  *time.Time `json:"from,omitempty"`
+	To    *time.Time `json:"to,omitempty"`
+	Count int64      `json:"count"`
+}
+
+// Import is when the first and last imported reflections were made. An
+// import isn't sent reflection by reflection, since it can be years of them.
+type Import struct {
+	From  time.Time `json:"from"`
+	To    time.Time `json:"to"`
+	Count int64     `json:"count"`
+}
+
+// ReflectionSaved is the event for a reflection being created, or updated if
+// it was edited.
+func ReflectionSaved(r reflection.Reflection, isEdit bool) Event {
+	typ := EventReflectionCreated
+	if isEdit {
+		typ = EventReflectionUpdated
+	}
+	rf := export.NewReflection(r, reflection.LocalizedQuestions(i18n.Default))
+	return Event{
+		Type:       typ,
+		TeamID:     r.TeamID,
+		UserID:     r.UserID,
+		Reflection: &rf,
+	}
+}
+
+// ReflectionsDeleted is the event for n of the user's reflections dated in
+// [from, to) being deleted. Zero times leave the range unbounded.
+func ReflectionsDeleted(teamID, userID string, from, to time.Time, n int64) Event {
+	d := &Deletion{Count: n}
+	if !from.IsZero() {
+		f := from.UTC()
+		d.From = &f
+	}
+	if !to.IsZero() {
+		t := to.UTC()
+		d.To = &t
+	}
+	return Event{
+		Type:    EventReflectionsDeleted,
+		TeamID:  teamID,
+		UserID:  userID,
+		Deleted: d,
+	}
+}
+
+// ReflectionsImported is the event for n reflections made from first to last
+// being imported for the user.
+func ReflectionsImported(teamID, userID string, first, last time.Time, n int64) Event {
+	return Event{
+		Type:     EventReflectionsImported,
+		TeamID:   teamID,
+		UserID:   userID,
+		Imported: &Import{From: first.UTC(), To: last.UTC(), Count: n},
+	}
+}
@@ -0,0 +1,231 @@
+// Package webhook posts events about reflections to the URLs people
+// subscribe, signed with each subscription's secret. Deliveries are queued
+// in the database and retried with backoff until they succeed or run out of
+// attempts, and the queue doubles as the delivery log.
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/jharlap/good-day-app/metrics"
+	"github.com/jmoiron/sqlx"
+)
+
+// Scopes of webhooks.
+const (
+	// ScopeUser webhooks get events about their owner's reflections.
+	ScopeUser = "user"
+	// ScopeTeam webhooks get events about everyone's reflections in their
+	// owner's team.
+	ScopeTeam = "team"
+)
+
+const (
+	// MaxWebhooks is how many webhooks a user can have at once.
+	MaxWebhooks = 5
+	// MaxURLLength is the longest a webhook's URL can be.
+	MaxURLLength = 2048
+
+	// secretPrefix marks the app's webhook secrets, so they can be
+	// recognised if they leak.
+	secretPrefix = "whsec_"
+)
+
+// Statuses of deliveries.
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusFailed    = "failed"
+)
+
+var (
+	// ErrTooManyWebhooks is returned when subscribing a webhook for a user
+	// who already has MaxWebhooks.
+	ErrTooManyWebhooks = errors.New("too many webhooks")
+	// ErrInvalidURL is returned when subscribing a URL that isn't an
+	// absolute https URL.
+	ErrInvalidURL = errors.New("invalid webhook url")
+)
+
+// Webhook is a subscription to events.
+type Webhook struct {
+	ID        int64     `db:"id"`
+	TeamID    string    `db:"team_id"`
+	UserID    string    `db:"user_id"`
+	Scope     string    `db:"scope"`
+	URL       string    `db:"url"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+const webhookColumns = "id, team_id, user_id, scope, url, created_at"
+
+// Delivery is an event queued for, or delivered to, a webhook.
+type Delivery struct {
+	ID             int64          `db:"id"`
+	WebhookID      int64          `db:"webhook_id"`
+	Event          string         `db:"event"`
+	Status         string         `db:"status"`
+	Attempts       int            `db:"attempts"`
+	NextAttemptAt  time.Time      `db:"next_attempt_at"`
+	LastStatusCode sql.NullInt64  `db:"last_status_code"`
+	LastError      sql.NullString `db:"last_error"`
+	CreatedAt      time.Time      `db:"created_at"`
+}
+
+const deliveryColumns = "d.id, d.webhook_id, d.event, d.status, d.attempts, d.next_attempt_at, d.last_status_code, d.last_error, d.created_at"
+
+// Dispatcher subscribes webhooks and delivers events to them.
+type Dispatcher struct {
+	db      *sqlx.DB
+	metrics *metrics.Metrics
+	client  *http.Client
+}
+
+// New returns a Dispatcher for the webhooks in db. Webhooks can only be
+// delivered to public addresses.
+func New(db *sqlx.DB, m *metrics.Metrics) *Dispatcher {
+	return &Dispatcher{
+		db:      db,
+		metrics: m,
+		client: &http.Client{
+			Timeout:   deliveryTimeout,
+			Transport: publicTransport(),
+			// a redirect could point anywhere, so it's reported as a failure
+			// for the owner to fix the URL instead
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// ValidateURL checks u can be subscribed: an absolute https URL, without
+// credentials, since they'd be shown in the delivery log.
+func ValidateURL(u string) error {
+	if len(u) > MaxURLLength {
+		return fmt.Errorf("%w: longer than %d characters", ErrInvalidURL, MaxURLLength)
+	}
+	p, err := url.Parse(u)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidURL, err)
+	}
+	if p.Scheme != "https" || len(p.Hostname()) == 0 {
+		return fmt.Errorf("%w: must be an https url", ErrInvalidURL)
+	}
+	if p.User != nil {
+		return fmt.Errorf("%w: can't have credentials", ErrInvalidURL)
+	}
+	return nil
+}
+
+// Subscribe subscribes a webhook at u for the user, returning it and the
+// secret its deliveries are signed with.
+func (d *Dispatcher) Subscribe(ctx context.Context, teamID, userID, scope, u string) (_ Webhook, _ string, err error) {
+	defer func(t time.Time) { d.metrics.ObserveQuery("subscribe_webhook", t, err) }(time.Now())
+
+	if err := ValidateURL(u); err != nil {
+		return Webhook{}, "", err
+	}
+	if scope != ScopeUser && scope != ScopeTeam {
+		return Webhook{}, "", fmt.Errorf("unknown webhook scope %q", scope)
+	}
+
+	var n int
+	err = d.db.GetContext(ctx, &n, "SELECT COUNT(*) FROM webhooks WHERE team_id = ? AND user_id = ? AND revoked_at IS NULL", teamID, userID)
+	if err != nil {
+		return Webhook{}, "", fmt.Errorf("error counting webhooks: %w", err)
+	}
+	if n >= MaxWebhooks {
+		return Webhook{}, "", ErrTooManyWebhooks
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return Webhook{}, "", fmt.Errorf("error reading random bytes: %w", err)
+	}
+	secret := secretPrefix + base64.RawURLEncoding.EncodeToString(b)
+
+	res, err := d.db.ExecContext(ctx, "INSERT INTO webhooks SET team_id=?, user_id=?, scope=?, url=?, secret=?", teamID, userID, scope, u, secret)
+	if err != nil {
+		return Webhook{}, "", fmt.Errorf("error creating webhook: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return Webhook{}, "", fmt.Errorf("error getting webhook id: %w", err)
+	}
+	return Webhook{ID: id, TeamID: teamID, UserID: userID, Scope: scope, URL: u, CreatedAt: time.Now()}, secret, nil
+}
+
+// Webhooks returns the user's active webhooks, oldest first.
+func (d *Dispatcher) Webhooks(ctx context.Context, teamID, userID string) ([]Webhook, error) {
+	var ww []Webhook
+	start := time.Now()
+	err := d.db.SelectContext(ctx, &ww, "SELECT "+webhookColumns+" FROM webhooks WHERE team_id = ? AND user_id = ? AND revoked_at IS NULL ORDER BY created_at", teamID, userID)
+	d.metrics.ObserveQuery("webhooks", start, err)
+	if err != nil {
+		return nil, fmt.Errorf("error getting webhooks: %w", err)
+	}
+	return ww, nil
+}
+
+// Unsubscribe revokes the user's webhook with id, so nothing more is
+// delivered to it, including deliveries still being retried.
+func (d *Dispatcher) Unsubscribe(ctx context.Context, teamID, userID string, id int64) error {
+	start := time.Now()
+	_, err := d.db.ExecContext(ctx, "UPDATE webhooks SET revoked_at = ? WHERE id = ? AND team_id = ? AND user_id = ? AND revoked_at IS NULL", time.Now(), id, teamID, userID)
+	d.metrics.ObserveQuery("unsubscribe_webhook", start, err)
+	if err != nil {
+		return fmt.Errorf("error revoking webhook: %w", err)
+	}
+	return nil
+}
+
+// Deliveries returns the latest n deliveries to the user's webhook with id,
+// newest first.
+func (d *Dispatcher) Deliveries(ctx context.Context, teamID, userID string, id int64, n int) ([]Delivery, error) {
+	var dd []Delivery
+	start := time.Now()
+	err := d.db.SelectContext(ctx, &dd, "SELECT "+deliveryColumns+" FROM webhook_deliveries d JOIN webhooks w ON w.id = d.webhook_id WHERE d.webhook_id = ? AND w.team_id = ? AND w.user_id = ? ORDER BY d.id DESC LIMIT ?", id, teamID, userID, n)
+	d.metrics.ObserveQuery("webhook_deliveries", start, err)
+	if err != nil {
+		return nil, fmt.Errorf("error getting webhook deliveries: %w", err)
+	}
+	return dd, nil
+}
+
+// Enqueue queues e for delivery to every webhook subscribed to it: the
+// user's own, and their team's. It returns how many deliveries were queued.
+func (d *Dispatcher) Enqueue(ctx context.Context, e Event) (_ int64, err error) {
+	defer func(t time.Time) { d.metrics.ObserveQuery("enqueue_webhook_event", t, err) }(time.Now())
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return 0, fmt.Errorf("error reading random bytes: %w", err)
+	}
+	e.ID = "evt_" + hex.EncodeToString(b)
+	e.CreatedAt = time.Now().UTC().Truncate(time.Second)
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return 0, fmt.Errorf("error marshaling webhook event: %w", err)
+	}
+
+	res, err := d.db.ExecContext(ctx, "INSERT INTO webhook_deliveries (webhook_id, team_id, user_id, event, payload, next_attempt_at) SELECT id, ?, ?, ?, ?, ? FROM webhooks WHERE team_id = ? AND (user_id = ? OR scope = ?) AND revoked_at IS NULL", e.TeamID, e.UserID, e.Type, payload, e.CreatedAt, e.TeamID, e.UserID, ScopeTeam)
+	if err != nil {
+		return 0, fmt.Errorf("error queueing webhook deliveries: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("error counting queued webhook deliveries: %w", err)
+	}
+	return n, nil
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jharlap/good-day-app/i18n"
+	"github.com/jharlap/good-day-app/webhook"
+	"github.com/rs/zerolog/log"
+	"github.com/slack-go/slack"
+)
+
+// homeWebhookDeliveries is how many of each webhook's latest deliveries the
+// home tab shows.
+const homeWebhookDeliveries = 5
+
+// notifyWebhooks queues e for the webhooks subscribed to it and starts
+// delivering it. Webhooks failing never fails what they're told about, so
+// errors are only logged.
+//
+// Reflections added by /reflect import are notified as one summary event,
+// since an import can be years of reflections.
+func (a *App) notifyWebhooks(ctx context.Context, e webhook.Event) {
+	n, err := a.webhooks.Enqueue(ctx, e)
+	if err != nil {
+		log.Error().Err(err).Str("tid", e.TeamID).Str("uid", e.UserID).Str("event", e.Type).Msg("error queueing webhook deliveries")
+		return
+	}
+	if n == 0 {
+		return
+	}
+	a.jobs.Go(ctx, "webhook-delivery", func(ctx context.Context) {
+		if err := a.webhooks.DeliverDue(ctx); err != nil {
+			log.Error().Err(err).Msg("error delivering webhooks")
+		}
+	})
+}
+
+// parseWebhookCommand parses `/reflect webhook add <url> [team]` into the
+// URL and scope of the webhook to subscribe.
+func parseWebhookCommand(text string) (u, scope string, ok bool) {
+	ff := strings.Fields(text)
+	if len(ff) < 3 || len(ff) > 4 || strings.ToLower(ff[1]) != "add" {
+		return "", "", false
+	}
+	scope = webhook.ScopeUser
+	if len(ff) == 4 {
+		if strings.ToLower(ff[3]) != webhook.ScopeTeam {
+			return "", "", false
+		}
+		scope = webhook.ScopeTeam
+	}
+	return unwrapSlackLink(ff[2]), scope, true
+}
+
+// unwrapSlackLink returns the URL of a link Slack formatted as <url> or
+// <url|label>.
+func unwrapSlackLink(s string) string {
+	if !strings.HasPrefix(s, "<") || !strings.HasSuffix(s, ">") {
+		return s
+	}
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "<"), ">")
+	if i := strings.Index(s, "|"); i >= 0 {
+		s = s[:i]
+	}
+	return s
+}
+
+// handleWebhookCommand subscribes a webhook for `/reflect webhook add`,
+// returning the reply to show only to the user, since it's the only time the
+// webhook's secret is shown. Only workspace admins can subscribe webhooks
+// for the whole team.
+func (a *App) handleWebhookCommand(ctx context.Context, tid, uid, text, lang string) string {
+	u, scope, ok := parseWebhookCommand(text)
+	if !ok {
+		return i18n.T(lang, "webhook.usage")
+	}
+	if scope == webhook.ScopeTeam {
+		su, err := a.fetchUser(ctx, tid, uid)
+		if err != nil {
+			log.Error().Err(err).Str("tid", tid).Str("uid", uid).Msg("error checking user is an admin for a team webhook")
+			return i18n.T(lang, "webhook.failed")
+		}
+		if !su.IsAdmin {
+			return i18n.T(lang, "webhook.team_admin_only")
+		}
+	}
+
+	wh, secret, err := a.webhooks.Subscribe(ctx, tid, uid, scope, u)
+	if errors.Is(err, webhook.ErrInvalidURL) {
+		return i18n.T(lang, "webhook.invalid_url")
+	} else if errors.Is(err, webhook.ErrTooManyWebhooks) {
+		return i18n.T(lang, "webhook.too_many", webhook.MaxWebhooks)
+	} else if err != nil {
+		log.Error().Err(err).Str("tid", tid).Str("uid", uid).Msg("error subscribing webhook")
+		return i18n.T(lang, "webhook.failed")
+	}
+	log.Info().Str("tid", tid).Str("uid", uid).Str("scope", scope).Int64("webhook", wh.ID).Msg("subscribed webhook")
+
+	a.refreshHomeView(ctx, tid, uid)
+	return i18n.T(lang, "webhook.created", wh.URL, i18n.T(lang, "webhook.scope."+scope), secret, webhook.SignatureHeader)
+}
+
+// renderHomeWebhooks lists the user's webhooks with their latest deliveries,
+// each with a button to remove it.
+func (a *App) renderHomeWebhooks(ctx context.Context, tid, uid string, su slackUser) ([]slack.Block, error) {
+	ww, err := a.webhooks.Webhooks(ctx, tid, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	bb := []slack.Block{markdownSection(i18n.T(su.Lang, "home.webhooks"))}
+	if len(ww) == 0 {
+		bb = append(bb, slack.NewContextBlock("", slack.NewTextBlockObject(slack.MarkdownType, i18n.T(su.Lang, "home.webhooks.empty"), false, false)))
+		return bb, nil
+	}
+
+	for _, wh := range ww {
+		dd, err := a.webhooks.Deliveries(ctx, tid, uid, wh.ID, homeWebhookDeliveries)
+		if err != nil {
+			return nil, err
+		}
+
+		text := fmt.Sprintf("*%s* · %s\n%s", wh.URL, i18n.T(su.Lang, "webhook.scope."+wh.Scope), i18n.T(su.Lang, "home.webhooks.created", slackDate(wh.CreatedAt, su.Location)))
+		removeBtn := slack.NewButtonBlockElement(homeButtonRemoveWebhook, strconv.FormatInt(wh.ID, 10), slack.NewTextBlockObject(slack.PlainTextType, i18n.T(su.Lang, "home.webhooks.remove"), false, false)).WithStyle(slack.StyleDanger)
+		removeBtn.Confirm = slack.NewConfirmationBlockObject(
+			slack.NewTextBlockObject(slack.PlainTextType, i18n.T(su.Lang, "home.webhooks.remove_confirm.title"), false, false),
+			slack.NewTextBlockObject(slack.MarkdownType, i18n.T(su.Lang, "home.webhooks.remove_confirm.text", wh.URL), false, false),
+			slack.NewTextBlockObject(slack.PlainTextType, i18n.T(su.Lang, "home.webhooks.remove"), false, false),
+			slack.NewTextBlockObject(slack.PlainTextType, i18n.T(su.Lang, "button.cancel"), false, false),
+		)
+		bb = append(bb,
+			slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, text, false, false), nil, slack.NewAccessory(removeBtn)),
+			slack.NewContextBlock("", slack.NewTextBlockObject(slack.MarkdownType, webhookDeliveryLog(dd, su), false, false)),
+		)
+	}
+	return bb, nil
+}
+
+// webhookDeliveryLog describes deliveries, one per line.
+func webhookDeliveryLog(dd []webhook.Delivery, su slackUser) string {
+	if len(dd) == 0 {
+		return i18n.T(su.Lang, "home.webhooks.no_deliveries")
+	}
+
+	lines := make([]string, len(dd))
+	for i, d := range dd {
+		at := slackDateTime(d.CreatedAt, su.Location)
+		switch {
+		case d.Status == webhook.StatusDelivered:
+			lines[i] = i18n.T(su.Lang, "home.webhooks.delivered", d.Event, at)
+		case d.Status == webhook.StatusFailed:
+			lines[i] = i18n.T(su.Lang, "home.webhooks.failed", d.Event, at, d.Attempts, d.LastError.String)
+		case d.Attempts > 0:
+			lines[i] = i18n.T(su.Lang, "home.webhooks.retrying", d.Event, at, d.Attempts, d.LastError.String, slackDateTime(d.NextAttemptAt, su.Location))
+		default:
+			lines[i] = i18n.T(su.Lang, "home.webhooks.queued", d.Event, at)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// handleRemoveWebhook removes the user's webhook with the id in value, from
+// the home tab.
+func (a *App) handleRemoveWebhook(ctx context.Context, tid, uid, value string) {
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		log.Error().Err(err).Str("value", value).Msg("error parsing webhook id to remove")
+		return
+	}
+
+	if err := a.webhooks.Unsubscribe(ctx, tid, uid, id); err != nil {
+		a.reportErrorToUser(ctx, err, tid, uid, i18n.T(a.lookupUser(ctx, tid, uid).Lang, "home.webhooks.remove_failed"))
+		return
+	}
+	log.Info().Str("tid", tid).Str("uid", uid).Int64("webhook", id).Msg("removed webhook")
+
+	a.refreshHomeView(ctx, tid, uid)
+}